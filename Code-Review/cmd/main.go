@@ -2,30 +2,50 @@ package main
 
 import (
 	"app/internal/application"
+	"app/internal/config"
 	"fmt"
+	"os"
 )
 
 func main() {
 	// env
-	// ...
+	// - defaults < config file < environment variables < flags
+	c, printConfig, err := config.Load(os.Args[1:], os.LookupEnv)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if printConfig {
+		c.Print(os.Stdout)
+		if err := c.Validate(); err != nil {
+			fmt.Println("#", err)
+		}
+		return
+	}
+	err = c.Validate()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// log
+	// - the handlers get the logger from the context of their request, with its request id
+	logger := c.Logger(os.Stderr)
 
 	// app
 	// - config
-	cfg := &application.ConfigApplicationDefault{
-		ServerAddress: ":8080",
-		LoaderFilePath: "docs/db/vehicles_100.json",
-	}
+	cfg := c.Application(logger)
 	app := application.NewApplicationDefault(cfg)
 	// - setup
-	err := app.SetUp()
+	err = app.SetUp()
 	if err != nil {
-		fmt.Println(err)
+		logger.Error("setup failed", "error", err)
 		return
 	}
 	// - run
 	err = app.Run()
 	if err != nil {
-		fmt.Println(err)
+		logger.Error("run failed", "error", err)
 		return
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"app/internal/repository"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+// migrate creates the schema of the vehicles in a SQL database and imports a vehicles file into it
+// - vehicles with the same id are replaced, so a file can be imported again
+// - usage: go run ./cmd/migrate -file docs/db/vehicles_100.json -dsn vehicles.db
+func main() {
+	// flags
+	file := flag.String("file", "docs/db/vehicles_100.json", "vehicles file to import (json, csv or ndjson)")
+	format := flag.String("format", "", "format of the vehicles file (default: taken from the file extension)")
+	strict := flag.Bool("strict", false, "fail if any record is invalid (default: invalid records are skipped)")
+	driver := flag.String("driver", "sqlite", "database/sql driver")
+	dsn := flag.String("dsn", "vehicles.db", "data source name of the database")
+	flag.Parse()
+
+	err := run(*file, *format, *strict, *driver, *dsn)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// run is a function that migrates the database and imports the vehicles file
+func run(file string, format string, strict bool, driver string, dsn string) (err error) {
+	// load vehicles
+	ldFile, err := loader.NewLoaderVehicleFile(file, format)
+	if err != nil {
+		return
+	}
+	ld := loader.NewLoaderVehicleValidated(ldFile, internal.NewVehicleValidatorDefault(), strict)
+	v, err := ld.LoadRecords()
+	if err != nil {
+		return
+	}
+	report := ld.Report()
+	for _, p := range report.Problems {
+		if p.Severity == internal.ValidationError {
+			fmt.Println("skipped", p)
+		}
+	}
+
+	// database
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	err = repository.MigrateVehicleSQL(db)
+	if err != nil {
+		return
+	}
+
+	// import
+	err = repository.NewRepositoryVehicleSQL(db).Import(context.Background(), v)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("imported %d vehicles from %s (%s)\n", len(v), file, report.Summary())
+	return
+}
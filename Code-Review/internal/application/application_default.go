@@ -1,24 +1,120 @@
 package application
 
 import (
+	"app/internal"
 	"app/internal/handler"
 	"app/internal/loader"
 	"app/internal/repository"
 	"app/internal/service"
+	"app/platform/web/auth"
+	"app/platform/web/cache"
+	"app/platform/web/logging"
+	"app/platform/web/metrics"
+	"app/platform/web/ratelimit"
+	"app/platform/web/requestid"
+	"app/platform/web/timeout"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	_ "modernc.org/sqlite"
 )
 
+const (
+	// StorageBackendFile is the storage backend that serves the vehicles of LoaderFilePath from memory
+	StorageBackendFile = "file"
+	// StorageBackendSQL is the storage backend that serves the vehicles of a SQL database
+	StorageBackendSQL = "sql"
+)
+
+const (
+	// RouteGroupRead is the route group of the finders and of the search of vehicles
+	RouteGroupRead = "read"
+	// RouteGroupAggregate is the route group of the averages and of the stats of vehicles, that scan every match
+	RouteGroupAggregate = "aggregate"
+	// RouteGroupWrite is the route group of the writes of vehicles
+	RouteGroupWrite = "write"
+	// RouteGroupAdmin is the route group of the admin endpoints
+	RouteGroupAdmin = "admin"
+)
+
+// cacheMaxEntries is the maximum number of responses of the aggregate endpoints kept in memory
+const cacheMaxEntries = 1024
+
+// RouteGroups is the list of the route groups that can be rate limited
+var RouteGroups = []string{RouteGroupRead, RouteGroupAggregate, RouteGroupWrite, RouteGroupAdmin}
+
 // ConfigApplicationDefault is a struct that represents the configuration for ApplicationDefault
 type ConfigApplicationDefault struct {
 	// Router is the router / multiplexer that will be used by the application
 	Router *chi.Mux
+	// Logger is the logger of the requests and of the application (default: slog.Default())
+	Logger *slog.Logger
 	// ServerAddress is the address where the server will be listening
 	ServerAddress string
+	// ReadHeaderTimeout is the maximum duration to read the headers of a request
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout is the maximum duration to read a request, including the body
+	ReadTimeout time.Duration
+	// WriteTimeout is the maximum duration to write a response, from the end of the headers of the request
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum duration to wait for the next request on a keep-alive connection
+	IdleTimeout time.Duration
+	// ShutdownTimeout is the maximum duration to drain the in-flight requests on SIGINT / SIGTERM
+	ShutdownTimeout time.Duration
+	// RequestTimeout is the deadline of the context of a request, unless its route has one in RouteTimeouts (negative: none)
+	RequestTimeout time.Duration
+	// RouteTimeouts is the deadline of the context of the requests of a route, by method and pattern (e.g. "GET /vehicles/stats")
+	RouteTimeouts map[string]time.Duration
+	// StorageBackend is where the vehicles are stored: file or sql (default: file)
+	StorageBackend string
+	// DatabaseDriver is the database/sql driver of the sql backend (default: sqlite, the only driver linked in)
+	DatabaseDriver string
+	// DatabaseDSN is the data source name of the sql backend (the schema is created if it does not exist)
+	DatabaseDSN string
 	// LoaderFilePath is the path to the file that contains the vehicles
 	LoaderFilePath string
+	// LoaderFormat is the format of LoaderFilePath: json, csv or ndjson (default: taken from the file extension)
+	LoaderFormat string
+	// LoaderStrict is true if a record that fails the validation fails SetUp (default: the record is skipped and logged)
+	LoaderStrict bool
+	// WALFilePath is the path to the write-ahead log of the vehicles (default: LoaderFilePath + ".wal")
+	WALFilePath string
+	// CompactionInterval is the interval between compactions of the write-ahead log into LoaderFilePath
+	CompactionInterval time.Duration
+	// ReloadInterval is the interval between checks of LoaderFilePath, reloaded when it changes (negative: never)
+	ReloadInterval time.Duration
+	// AdminToken is the bearer token of the admin endpoints, an API key with the admin role
+	AdminToken string
+	// APIKeys is the role of each static API key: reader, editor or admin
+	APIKeys map[string]string
+	// JWTKey is the HMAC-SHA256 key of the JWT bearer tokens (empty: tokens are not accepted)
+	JWTKey string
+	// JWTIssuer is the required iss claim of the JWT bearer tokens (empty: not checked)
+	JWTIssuer string
+	// JWTAudience is the audience the aud claim of the JWT bearer tokens must contain (empty: not checked)
+	JWTAudience string
+	// AuthDisabled opens the vehicles endpoints and the metrics without credentials configured, for development
+	// - without credentials and AuthDisabled the vehicles are read only and the metrics closed, the admin endpoints are always closed
+	AuthDisabled bool
+	// RateLimits is the limit of the requests of each client on a route group, by name in RouteGroups (e.g. "10/s:20", see ratelimit.ParseLimit)
+	// - a client is the principal of its credentials, or its IP without them
+	RateLimits map[string]string
+	// MaxInFlight is the maximum number of requests served at once (0: no limit)
+	MaxInFlight int
+	// CacheMaxAge is how long clients can reuse a response of the vehicle reads without revalidating it (0: every time)
+	CacheMaxAge time.Duration
 }
 
 // NewApplicationDefault is a function that returns a new instance of ApplicationDefault
@@ -26,24 +122,129 @@ func NewApplicationDefault(cfg *ConfigApplicationDefault) *ApplicationDefault {
 	// default values
 	defaultConfig := &ConfigApplicationDefault{
 		Router: chi.NewRouter(),
+		Logger: slog.Default(),
 		ServerAddress: ":8080",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout: 10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout: 2 * time.Minute,
+		ShutdownTimeout: 15 * time.Second,
+		RequestTimeout: 10 * time.Second,
+		StorageBackend: StorageBackendFile,
+		DatabaseDriver: "sqlite",
+		CompactionInterval: time.Minute,
+		ReloadInterval: 5 * time.Second,
 	}
 	if cfg != nil {
 		if cfg.Router != nil {
 			defaultConfig.Router = cfg.Router
 		}
+		if cfg.Logger != nil {
+			defaultConfig.Logger = cfg.Logger
+		}
 		if cfg.ServerAddress != "" {
 			defaultConfig.ServerAddress = cfg.ServerAddress
 		}
+		if cfg.ReadHeaderTimeout != 0 {
+			defaultConfig.ReadHeaderTimeout = cfg.ReadHeaderTimeout
+		}
+		if cfg.ReadTimeout != 0 {
+			defaultConfig.ReadTimeout = cfg.ReadTimeout
+		}
+		if cfg.WriteTimeout != 0 {
+			defaultConfig.WriteTimeout = cfg.WriteTimeout
+		}
+		if cfg.IdleTimeout != 0 {
+			defaultConfig.IdleTimeout = cfg.IdleTimeout
+		}
+		if cfg.ShutdownTimeout != 0 {
+			defaultConfig.ShutdownTimeout = cfg.ShutdownTimeout
+		}
+		if cfg.RequestTimeout != 0 {
+			defaultConfig.RequestTimeout = cfg.RequestTimeout
+		}
+		if cfg.RouteTimeouts != nil {
+			defaultConfig.RouteTimeouts = cfg.RouteTimeouts
+		}
+		if cfg.StorageBackend != "" {
+			defaultConfig.StorageBackend = cfg.StorageBackend
+		}
+		if cfg.DatabaseDriver != "" {
+			defaultConfig.DatabaseDriver = cfg.DatabaseDriver
+		}
+		if cfg.DatabaseDSN != "" {
+			defaultConfig.DatabaseDSN = cfg.DatabaseDSN
+		}
 		if cfg.LoaderFilePath != "" {
 			defaultConfig.LoaderFilePath = cfg.LoaderFilePath
 		}
+		if cfg.LoaderFormat != "" {
+			defaultConfig.LoaderFormat = cfg.LoaderFormat
+		}
+		defaultConfig.LoaderStrict = cfg.LoaderStrict
+		if cfg.WALFilePath != "" {
+			defaultConfig.WALFilePath = cfg.WALFilePath
+		}
+		if cfg.CompactionInterval != 0 {
+			defaultConfig.CompactionInterval = cfg.CompactionInterval
+		}
+		if cfg.ReloadInterval != 0 {
+			defaultConfig.ReloadInterval = cfg.ReloadInterval
+		}
+		if cfg.AdminToken != "" {
+			defaultConfig.AdminToken = cfg.AdminToken
+		}
+		defaultConfig.APIKeys = cfg.APIKeys
+		defaultConfig.JWTKey = cfg.JWTKey
+		defaultConfig.JWTIssuer = cfg.JWTIssuer
+		defaultConfig.JWTAudience = cfg.JWTAudience
+		defaultConfig.AuthDisabled = cfg.AuthDisabled
+		defaultConfig.RateLimits = cfg.RateLimits
+		defaultConfig.MaxInFlight = cfg.MaxInFlight
+		defaultConfig.CacheMaxAge = cfg.CacheMaxAge
+	}
+	if defaultConfig.WALFilePath == "" {
+		defaultConfig.WALFilePath = defaultConfig.LoaderFilePath + ".wal"
 	}
 
+	// metrics: the ones of the dataset are set on load / reload (file backend)
+	reg := metrics.NewRegistry()
+
 	return &ApplicationDefault{
+		metrics: reg,
+		datasetSize: reg.Gauge("vehicles_dataset_size", "Number of vehicles of the last load of the vehicles file."),
+		datasetLoaded: reg.Gauge("vehicles_dataset_last_load_timestamp_seconds", "Unix time of the last load of the vehicles file."),
 		router: defaultConfig.Router,
-		serverAddress: defaultConfig.ServerAddress,
+		logger: defaultConfig.Logger,
+		server: &http.Server{
+			Addr: defaultConfig.ServerAddress,
+			Handler: defaultConfig.Router,
+			ReadHeaderTimeout: defaultConfig.ReadHeaderTimeout,
+			ReadTimeout: defaultConfig.ReadTimeout,
+			WriteTimeout: defaultConfig.WriteTimeout,
+			IdleTimeout: defaultConfig.IdleTimeout,
+		},
+		shutdownTimeout: defaultConfig.ShutdownTimeout,
+		requestTimeout: defaultConfig.RequestTimeout,
+		routeTimeouts: defaultConfig.RouteTimeouts,
+		storageBackend: defaultConfig.StorageBackend,
+		databaseDriver: defaultConfig.DatabaseDriver,
+		databaseDSN: defaultConfig.DatabaseDSN,
 		loaderFilePath: defaultConfig.LoaderFilePath,
+		loaderFormat: defaultConfig.LoaderFormat,
+		loaderStrict: defaultConfig.LoaderStrict,
+		walFilePath: defaultConfig.WALFilePath,
+		compactionInterval: defaultConfig.CompactionInterval,
+		reloadInterval: defaultConfig.ReloadInterval,
+		adminToken: defaultConfig.AdminToken,
+		apiKeys: defaultConfig.APIKeys,
+		jwtKey: defaultConfig.JWTKey,
+		jwtIssuer: defaultConfig.JWTIssuer,
+		jwtAudience: defaultConfig.JWTAudience,
+		authDisabled: defaultConfig.AuthDisabled,
+		rateLimits: defaultConfig.RateLimits,
+		maxInFlight: defaultConfig.MaxInFlight,
+		cacheMaxAge: defaultConfig.CacheMaxAge,
 	}
 }
 
@@ -51,52 +252,407 @@ func NewApplicationDefault(cfg *ConfigApplicationDefault) *ApplicationDefault {
 type ApplicationDefault struct {
 	// router is the router / multiplexer that will be used by the application
 	router *chi.Mux
-	// serverAddress is the address where the server will be listening
-	serverAddress string
+	// logger is the logger of the requests and of the application
+	logger *slog.Logger
+	// metrics is the registry of the metrics of the requests and of the dataset, served on /metrics
+	metrics *metrics.Registry
+	// datasetSize is the gauge of the number of vehicles loaded
+	datasetSize *metrics.Gauge
+	// datasetLoaded is the gauge of the time of the last load
+	datasetLoaded *metrics.Gauge
+	// server is the http server of the router
+	server *http.Server
+	// shutdownTimeout is the maximum duration to drain the in-flight requests on SIGINT / SIGTERM
+	shutdownTimeout time.Duration
+	// requestTimeout is the deadline of the context of a request without a route timeout
+	requestTimeout time.Duration
+	// routeTimeouts is the deadline of the context of the requests of a route, by method and pattern
+	routeTimeouts map[string]time.Duration
+	// storageBackend is where the vehicles are stored
+	storageBackend string
+	// databaseDriver is the database/sql driver of the sql backend
+	databaseDriver string
+	// databaseDSN is the data source name of the sql backend
+	databaseDSN string
 	// loaderFilePath is the path to the file that contains the vehicles
 	loaderFilePath string
+	// loaderFormat is the format of the file that contains the vehicles
+	loaderFormat string
+	// loaderStrict is true if a record that fails the validation fails SetUp
+	loaderStrict bool
+	// walFilePath is the path to the write-ahead log of the vehicles
+	walFilePath string
+	// compactionInterval is the interval between compactions of the write-ahead log
+	compactionInterval time.Duration
+	// reloadInterval is the interval between checks of the loader file
+	reloadInterval time.Duration
+	// adminToken is the bearer token of the admin endpoints, an API key with the admin role
+	adminToken string
+	// apiKeys is the role of each static API key
+	apiKeys map[string]string
+	// jwtKey is the HMAC-SHA256 key of the JWT bearer tokens
+	jwtKey string
+	// jwtIssuer is the required iss claim of the JWT bearer tokens
+	jwtIssuer string
+	// jwtAudience is the audience the aud claim of the JWT bearer tokens must contain
+	jwtAudience string
+	// authDisabled is true if the vehicles endpoints and the metrics are open without credentials configured
+	authDisabled bool
+	// rateLimits is the limit of the requests of each client on a route group
+	rateLimits map[string]string
+	// maxInFlight is the maximum number of requests served at once
+	maxInFlight int
+	// cacheMaxAge is how long clients can reuse a response of the vehicle reads without revalidating it
+	cacheMaxAge time.Duration
+	// versions is the source of the versions of the vehicles served
+	versions internal.RepositoryVersionedVehicle
+	// rpVersioned is the repository that counts the versions of the vehicles, the one served (file backend)
+	rpVersioned *repository.RepositoryVehicleVersioned
+	// ld is the loader of the vehicles, validated
+	ld *loader.LoaderVehicleValidated
+	// watcher is the watcher of the loader file
+	watcher *loader.FileWatcher
+	// rpFile is the repository that persists the vehicles (file backend)
+	rpFile *repository.RepositoryVehicleFile
+	// db is the database of the vehicles (sql backend)
+	db *sql.DB
+	// reloadMu is the mutex that serializes reloads
+	reloadMu sync.Mutex
+	// stopWatch is the channel that stops the watch of the loader file
+	stopWatch chan struct{}
+	// doneWatch is the channel closed when the watch of the loader file has stopped
+	doneWatch chan struct{}
+	// shutdownOnce is the once that releases the resources on the first Shutdown
+	shutdownOnce sync.Once
+	// shutdownErr is the error of the first Shutdown
+	shutdownErr error
 }
 
 // SetUp is a method that sets up the application
 func (a *ApplicationDefault) SetUp() (err error) {
 	// dependencies
-	// - loader: loader for vehicles
-	ld := loader.NewLoaderVehicleJSON(a.loaderFilePath)
-	// - db: map of vehicles
-	db, err := ld.Load()
+	// - repository: repository for vehicles, by storage backend
+	var rp internal.RepositoryVehicle
+	switch a.storageBackend {
+	case StorageBackendFile:
+		rp, err = a.setUpFile()
+	case StorageBackendSQL:
+		rp, err = a.setUpSQL()
+	default:
+		err = fmt.Errorf("application: unknown storage backend %q", a.storageBackend)
+	}
 	if err != nil {
 		return
 	}
-	// - repository: repository for vehicles
-	rp := repository.NewRepositoryReadVehicleMap(db)
 	// - service: service for vehicles
 	sv := service.NewServiceVehicleDefault(rp)
 	// - handler: handler for vehicles
 	hd := handler.NewHandlerVehicle(sv)
+	// - authenticators: API keys and JWT bearer tokens
+	authenticators, err := a.authenticators()
+	if err != nil {
+		return
+	}
+	// - roles: without credentials configured the vehicles are read only and the metrics closed, unless auth is disabled
+	// (the admin endpoints are always closed)
+	open := func(auth.Role) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	requireRead, require := auth.Require, auth.Require
+	switch {
+	case len(authenticators) > 0:
+	case a.authDisabled:
+		a.logger.Warn("auth: disabled, the vehicles endpoints and the metrics are open")
+		requireRead, require = open, open
+	default:
+		a.logger.Warn("auth: no api keys or jwt key configured, the vehicles are read only and the metrics closed")
+		requireRead = open
+	}
+
+	// - cache: validators of the vehicle reads by version of the vehicles, responses of the aggregates kept in memory
+	validator := cache.NewValidator(a.versions.Version, a.cacheMaxAge, "Accept", handler.HeaderAcceptUnits)
+	aggregates := cache.NewCache(validator, cacheMaxEntries)
+	// - limits: rate of each client by route group
+	limit, err := a.rateLimiters()
+	if err != nil {
+		return
+	}
 
 	// routes
 	// - middlewares
-	a.router.Use(middleware.Logger)
+	a.router.Use(requestid.Middleware)
+	a.router.Use(logging.Middleware(a.logger))
+	a.router.Use(metrics.Middleware(a.metrics, a.router))
+	if a.maxInFlight > 0 {
+		a.router.Use(ratelimit.MaxInFlight(a.maxInFlight))
+	}
 	a.router.Use(middleware.Recoverer)
+	a.router.Use(timeout.Middleware(timeout.ByRoute(a.router, a.requestTimeout, a.routeTimeouts)))
+	a.router.Use(auth.Middleware(authenticators...))
 	// - endpoints
+	// Metrics in the Prometheus text exposition format (any role)
+	a.router.With(require(auth.RoleReader)).Method(http.MethodGet, "/metrics", a.metrics.Handler())
 	a.router.Route("/vehicles", func(r chi.Router) {
-		// Get vehicles by color and year
-		r.Get("/color/{color}/year/{year}", hd.FindByColorAndYear())
-		// Get vehicles by brand between years
-		r.Get("/brand/{brand}/between/{start_year}/{end_year}", hd.FindByBrandAndYearRange())
-		// Get average max speed by brand
-		r.Get("/average_speed/brand/{brand}", hd.AverageMaxSpeedByBrand())
-		// Get average capacity by brand
-		r.Get("/average_capacity/brand/{brand}", hd.AverageCapacityByBrand())
-		// Get vehicles by weight range (query)
-		r.Get("/weight", hd.SearchByWeightRange())
+		// - reader role
+		r.Group(func(r chi.Router) {
+			r.Use(limit(RouteGroupRead), requireRead(auth.RoleReader), validator.Middleware)
+			// Get vehicles by color and year
+			r.Get("/color/{color}/year/{year}", hd.FindByColorAndYear())
+			// Get vehicles by brand between years
+			r.Get("/brand/{brand}/between/{start_year}/{end_year}", hd.FindByBrandAndYearRange())
+			// Get vehicles by weight range (query)
+			r.Get("/weight", hd.SearchByWeightRange())
+			// Search vehicles by any combination of attributes (query)
+			r.Get("/", hd.Search())
+			// Compare vehicles attribute by attribute (query)
+			r.Get("/compare", hd.Compare())
+		})
+		// - reader role, aggregates
+		r.Group(func(r chi.Router) {
+			r.Use(limit(RouteGroupAggregate), requireRead(auth.RoleReader), validator.Middleware, aggregates.Middleware)
+			// Get average max speed by brand (same as /stats?brand={brand}&metric=max_speed&agg=avg)
+			r.Get("/average_speed/brand/{brand}", hd.AverageMaxSpeedByBrand())
+			// Get average capacity by brand (same as /stats?brand={brand}&metric=capacity&agg=avg)
+			r.Get("/average_capacity/brand/{brand}", hd.AverageCapacityByBrand())
+			// Get aggregates of a metric grouped by attributes, for the vehicles that match the search (query)
+			r.Get("/stats", hd.Stats())
+			// Get the vehicles most like a vehicle (query)
+			r.Get("/{id}/similar", hd.Similar())
+		})
+		// - editor role
+		r.Group(func(r chi.Router) {
+			r.Use(limit(RouteGroupWrite), require(auth.RoleEditor))
+			// Create a vehicle
+			r.Post("/", hd.Create())
+			// Replace a vehicle
+			r.Put("/{id}", hd.Update())
+			// Update some attributes of a vehicle
+			r.Patch("/{id}", hd.Patch())
+			// Delete a vehicle
+			r.Delete("/{id}", hd.Delete())
+		})
 	})
+	if a.rpFile != nil {
+		// - handler: handler for administration (the loader file can be reloaded)
+		hdAdmin := handler.NewHandlerAdmin(a)
+		a.router.Route("/admin", func(r chi.Router) {
+			// - admin role
+			r.Use(limit(RouteGroupAdmin), auth.Require(auth.RoleAdmin))
+			// Reload the vehicles from the loader file
+			r.Post("/reload", hdAdmin.Reload())
+		})
+	}
 
 	return
 }
 
-// Run is a method that runs the application
+// authenticators is a method that returns the authenticators of the configured credentials
+// - API keys (the admin token is one with the admin role), then JWT bearer tokens
+func (a *ApplicationDefault) authenticators() (authenticators []auth.Authenticator, err error) {
+	keys := make(map[string]auth.Role, len(a.apiKeys)+1)
+	for key, name := range a.apiKeys {
+		keys[key], err = auth.ParseRole(name)
+		if err != nil {
+			err = fmt.Errorf("application: api keys: %w", err)
+			return
+		}
+	}
+	if a.adminToken != "" {
+		keys[a.adminToken] = auth.RoleAdmin
+	}
+	if len(keys) > 0 {
+		authenticators = append(authenticators, auth.NewAPIKeys(keys))
+	}
+	if a.jwtKey != "" {
+		authenticators = append(authenticators, auth.NewJWT([]byte(a.jwtKey), a.jwtIssuer, a.jwtAudience))
+	}
+	return
+}
+
+// rateLimiters is a method that returns the middleware that limits the rate of the requests of each client on a route group
+// - a group without a limit is not limited
+// - clients are keyed by the subject of their principal, or by IP without credentials
+func (a *ApplicationDefault) rateLimiters() (limit func(group string) func(http.Handler) http.Handler, err error) {
+	limiters := make(map[string]*ratelimit.Limiter, len(a.rateLimits))
+	for group, spec := range a.rateLimits {
+		if !slices.Contains(RouteGroups, group) {
+			err = fmt.Errorf("application: rate limits: unknown route group %q", group)
+			return
+		}
+		l, errLimit := ratelimit.ParseLimit(spec)
+		if errLimit != nil {
+			err = fmt.Errorf("application: rate limits: %s: %w", group, errLimit)
+			return
+		}
+		limiters[group] = ratelimit.NewLimiter(l)
+	}
+
+	key := func(r *http.Request) string {
+		if p, ok := auth.FromContext(r.Context()); ok {
+			return "principal:" + p.Subject
+		}
+		return "ip:" + ratelimit.ClientIP(r)
+	}
+	limit = func(group string) func(http.Handler) http.Handler {
+		l, ok := limiters[group]
+		if !ok {
+			return func(next http.Handler) http.Handler { return next }
+		}
+		return ratelimit.Middleware(l, key)
+	}
+	return
+}
+
+// Run is a method that runs the application until SIGINT / SIGTERM or Shutdown
+// - on a signal, the in-flight requests are drained within the shutdown timeout and the resources are released
 func (a *ApplicationDefault) Run() (err error) {
-	err = http.ListenAndServe(a.serverAddress, a.router)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	chErr := make(chan error, 1)
+	go func() {
+		chErr <- a.server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-chErr:
+		// - Shutdown was called by someone else, that is not an error
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		return
+	case <-ctx.Done():
+		stop()
+	}
+
+	a.logger.Info("server: shutting down", "timeout", a.shutdownTimeout)
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+	err = a.Shutdown(ctxShutdown)
+	if errServe := <-chErr; !errors.Is(errServe, http.ErrServerClosed) {
+		err = errors.Join(errServe, err)
+	}
 	return
-}
\ No newline at end of file
+}
+
+// Shutdown is a method that stops the application gracefully
+// - the server stops accepting requests and waits for the in-flight ones until ctx is done
+// - then the resources are released in order: the watch of the loader file, the vehicle store (compacted) and the database
+// - the resources are released even if ctx is done first, later calls return the result of the first one
+func (a *ApplicationDefault) Shutdown(ctx context.Context) (err error) {
+	a.shutdownOnce.Do(func() {
+		var errs []error
+		if err := a.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("application: server: %w", err))
+		}
+		a.stopWatchFile()
+		if a.rpFile != nil {
+			if err := a.rpFile.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("application: vehicle store: %w", err))
+			}
+		}
+		if a.db != nil {
+			if err := a.db.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("application: database: %w", err))
+			}
+		}
+		a.shutdownErr = errors.Join(errs...)
+	})
+	return a.shutdownErr
+}
+
+// setUpFile is a method that returns the repository of the file backend
+// - the vehicles of the loader file are validated and served from memory (indexed, finders do not scan the whole db)
+// - writes are persisted to the loader file through a write-ahead log that is replayed on boot
+// - the loader file is reloaded when it changes or on demand
+func (a *ApplicationDefault) setUpFile() (rp internal.RepositoryVehicle, err error) {
+	// - loader: loader for vehicles (by format or file extension)
+	ldFile, err := loader.NewLoaderVehicleFile(a.loaderFilePath, a.loaderFormat)
+	if err != nil {
+		return
+	}
+	// validated records: strict fails on any invalid record, lenient skips them
+	a.ld = loader.NewLoaderVehicleValidated(ldFile, internal.NewVehicleValidatorDefault(), a.loaderStrict)
+	// - watcher: detects new versions of the loader file, recorded before it is loaded
+	a.watcher = loader.NewFileWatcher(a.loaderFilePath)
+	err = a.watcher.Sync()
+	if err != nil {
+		return
+	}
+	// - db: map of vehicles
+	db, err := a.ld.Load()
+	if err != nil {
+		return
+	}
+	a.logValidationReport(a.ld.Report())
+	a.setDatasetMetrics(len(db))
+	// - repository: compaction does not overwrite a loader file that changed and was not reloaded yet
+	st := loader.NewStorerVehicleWatched(a.ld, a.watcher)
+	rpFile, err := repository.NewRepositoryVehicleFile(repository.NewRepositoryVehicleIndexed(db), st, a.walFilePath)
+	if err != nil {
+		return
+	}
+	rpFile.StartCompaction(a.compactionInterval)
+	a.rpFile = rpFile
+	a.logSkippedWrites()
+	// - version: bumped by every write and reload
+	a.rpVersioned = repository.NewRepositoryVehicleVersioned(rpFile)
+	a.versions = a.rpVersioned
+	// - reload
+	a.startWatch(a.reloadInterval)
+
+	rp = a.rpVersioned
+	return
+}
+
+// setUpSQL is a method that returns the repository of the sql backend
+// - the schema is created if it does not exist, vehicles are imported with cmd/migrate
+func (a *ApplicationDefault) setUpSQL() (rp internal.RepositoryVehicle, err error) {
+	db, err := sql.Open(a.databaseDriver, a.databaseDSN)
+	if err != nil {
+		return
+	}
+	err = db.Ping()
+	if err == nil {
+		err = repository.MigrateVehicleSQL(db)
+	}
+	if err != nil {
+		db.Close()
+		return
+	}
+	a.db = db
+
+	// - version: kept in the database, bumped by every write of any process
+	rpSQL := repository.NewRepositoryVehicleSQL(db)
+	a.versions = rpSQL
+
+	rp = rpSQL
+	return
+}
+
+// setDatasetMetrics is a method that records a load of the vehicles file with the given number of vehicles
+func (a *ApplicationDefault) setDatasetMetrics(size int) {
+	a.datasetSize.Set(float64(size))
+	a.datasetLoaded.Set(float64(time.Now().UnixNano()) / 1e9)
+}
+
+// logValidationReport is a method that logs the summary of a validation report and every skipped record
+func (a *ApplicationDefault) logValidationReport(report internal.ValidationReport) {
+	if len(report.Problems) == 0 {
+		return
+	}
+	a.logger.Warn("loader: validation problems", "summary", report.Summary())
+	for _, p := range report.Problems {
+		if p.Severity == internal.ValidationError {
+			a.logger.Warn("loader: record skipped", "index", p.Index, "id", p.Id, "field", p.Field, "message", p.Message)
+		}
+	}
+}
+
+// logSkippedWrites is a method that logs the writes of the write-ahead log that failed on the last replay
+// - e.g. a save of an id that the loader file got meanwhile: the vehicle of the file is kept
+func (a *ApplicationDefault) logSkippedWrites() {
+	for _, e := range a.rpFile.Skipped() {
+		a.logger.Warn("vehicle store: write skipped on replay", "line", e.Line, "op", e.Op, "id", e.Id, "error", e.Err)
+	}
+}
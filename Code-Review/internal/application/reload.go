@@ -0,0 +1,94 @@
+package application
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"fmt"
+	"time"
+)
+
+// Reload is a method that loads and validates the loader file again and replaces the vehicles being served
+// - writes not compacted yet are replayed onto the new vehicles, as on boot (the ones that fail are logged)
+// - the vehicles get a new version, even if the file has the same ones
+// - if the file can not be loaded, or every record is invalid, the current vehicles are kept and the error wraps internal.ErrReloadInvalid
+func (a *ApplicationDefault) Reload() (report internal.ValidationReport, err error) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	// - the file is recorded, loaded and swapped with the writes and the compaction of the store paused
+	var size int
+	err = a.rpFile.Reload(func() (rp internal.RepositoryVehicle, err error) {
+		// record the file before loading it: a change while it is loaded is detected by the next poll
+		err = a.watcher.Sync()
+		if err != nil {
+			err = fmt.Errorf("%w: %w", internal.ErrReloadInvalid, err)
+			return
+		}
+		db, err := a.ld.Load()
+		report = a.ld.Report()
+		if err != nil {
+			err = fmt.Errorf("%w: %w", internal.ErrReloadInvalid, err)
+			return
+		}
+		// - lenient mode skips invalid records, but a file with no valid record at all is not a new version of the data
+		if report.Records > 0 && report.Skipped == report.Records {
+			err = fmt.Errorf("%w: every record is invalid: %s", internal.ErrReloadInvalid, report.Summary())
+			return
+		}
+		rp, size = repository.NewRepositoryVehicleIndexed(db), len(db)
+		return
+	})
+	if err != nil {
+		return
+	}
+	a.logSkippedWrites()
+	a.rpVersioned.Bump()
+	a.setDatasetMetrics(size)
+	return
+}
+
+// startWatch is a method that reloads the loader file every time it changes, checking it every interval
+// - errors are logged and the current vehicles are kept until the file changes again
+func (a *ApplicationDefault) startWatch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	a.stopWatch, a.doneWatch = make(chan struct{}), make(chan struct{})
+
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				changed, err := a.watcher.Changed()
+				if err != nil {
+					a.logger.Error("reload: watch failed", "error", err)
+					continue
+				}
+				if !changed {
+					continue
+				}
+				report, err := a.Reload()
+				if err != nil {
+					a.logger.Error("reload: failed, the current vehicles are kept", "error", err)
+					continue
+				}
+				a.logger.Info("reload: vehicles reloaded", "summary", report.Summary())
+			}
+		}
+	}(a.stopWatch, a.doneWatch)
+}
+
+// stopWatchFile is a method that stops the watch of the loader file and waits for a reload in progress
+func (a *ApplicationDefault) stopWatchFile() {
+	if a.stopWatch == nil {
+		return
+	}
+	close(a.stopWatch)
+	<-a.doneWatch
+	a.stopWatch, a.doneWatch = nil, nil
+}
@@ -0,0 +1,582 @@
+package config
+
+import (
+	"app/internal/application"
+	"app/internal/loader"
+	"app/platform/web/auth"
+	"app/platform/web/logging"
+	"app/platform/web/ratelimit"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrConfigInvalid is an error that represents an invalid configuration
+	ErrConfigInvalid = errors.New("config: invalid")
+)
+
+// EnvPrefix is the prefix of the environment variables of the options (e.g. VEHICLES_SERVER_ADDRESS)
+const EnvPrefix = "VEHICLES_"
+
+// Config is a struct that represents the configuration of the vehicles server
+type Config struct {
+	// ServerAddress is the address where the server will be listening
+	ServerAddress string
+	// ReadHeaderTimeout is the maximum duration to read the headers of a request
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout is the maximum duration to read a request, including the body
+	ReadTimeout time.Duration
+	// WriteTimeout is the maximum duration to write a response
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum duration to wait for the next request on a keep-alive connection
+	IdleTimeout time.Duration
+	// ShutdownTimeout is the maximum duration to drain the in-flight requests on SIGINT / SIGTERM
+	ShutdownTimeout time.Duration
+	// RequestTimeout is the deadline of a request, unless its route has one in RouteTimeouts (negative: none)
+	RequestTimeout time.Duration
+	// RouteTimeouts is the deadline of the requests of a route, by method and pattern (e.g. "GET /vehicles/stats")
+	RouteTimeouts map[string]time.Duration
+	// LogLevel is the minimum level of the logged records: debug, info, warn or error
+	LogLevel string
+	// LogFormat is the format of the logged records: json or text
+	LogFormat string
+	// StorageBackend is where the vehicles are stored: file or sql
+	StorageBackend string
+	// DatabaseDriver is the database/sql driver of the sql backend
+	DatabaseDriver string
+	// DatabaseDSN is the data source name of the sql backend
+	DatabaseDSN string
+	// LoaderFilePath is the path to the file that contains the vehicles
+	LoaderFilePath string
+	// LoaderFormat is the format of LoaderFilePath (empty: taken from the file extension)
+	LoaderFormat string
+	// LoaderStrict is true if a record that fails the validation fails the start
+	LoaderStrict bool
+	// WALFilePath is the path to the write-ahead log of the vehicles (empty: LoaderFilePath + ".wal")
+	WALFilePath string
+	// CompactionInterval is the interval between compactions of the write-ahead log
+	CompactionInterval time.Duration
+	// ReloadInterval is the interval between checks of LoaderFilePath (negative: never)
+	ReloadInterval time.Duration
+	// AdminToken is the bearer token of the admin endpoints (an API key with the admin role)
+	AdminToken string
+	// APIKeys is the role of each static API key (reader, editor or admin)
+	APIKeys map[string]string
+	// JWTKey is the HMAC-SHA256 key of the JWT bearer tokens (empty: tokens are not accepted)
+	JWTKey string
+	// JWTIssuer is the required iss claim of the tokens (empty: not checked)
+	JWTIssuer string
+	// JWTAudience is the audience the aud claim of the tokens must contain (empty: not checked)
+	JWTAudience string
+	// AuthDisabled is true if the vehicles endpoints and the metrics are open, without credentials configured
+	AuthDisabled bool
+	// RateLimits is the limit of the requests of each client on a route group: read, aggregate, write or admin (e.g. "10/s:20")
+	RateLimits map[string]string
+	// MaxInFlight is the maximum number of requests served at once (0: no limit)
+	MaxInFlight int
+	// CacheMaxAge is how long clients can reuse a response of the vehicle reads without revalidating it (0: every time)
+	CacheMaxAge time.Duration
+}
+
+// Default is a function that returns the default configuration
+func Default() Config {
+	return Config{
+		ServerAddress:      ":8080",
+		ReadHeaderTimeout:  5 * time.Second,
+		ReadTimeout:        10 * time.Second,
+		WriteTimeout:       30 * time.Second,
+		IdleTimeout:        2 * time.Minute,
+		ShutdownTimeout:    15 * time.Second,
+		RequestTimeout:     10 * time.Second,
+		LogLevel:           "info",
+		LogFormat:          "json",
+		StorageBackend:     application.StorageBackendFile,
+		DatabaseDriver:     "sqlite",
+		LoaderFilePath:     "docs/db/vehicles_100.json",
+		CompactionInterval: time.Minute,
+		ReloadInterval:     5 * time.Second,
+	}
+}
+
+// option is a struct that represents an option of the configuration
+// - name is the key in the config file, the flag is the name with dashes and the env var is EnvPrefix + the name in upper case
+type option struct {
+	// name is the name of the option (e.g. server_address)
+	name string
+	// usage is the description of the option
+	usage string
+	// secret is true if the value must not be printed
+	secret bool
+	// get returns the value of the option
+	get func(c *Config) any
+	// set parses and sets the value of the option
+	set func(c *Config, value string) error
+}
+
+// flagName is a method that returns the name of the flag of the option
+func (o option) flagName() string {
+	return strings.ReplaceAll(o.name, "_", "-")
+}
+
+// envName is a method that returns the name of the environment variable of the option
+func (o option) envName() string {
+	return EnvPrefix + strings.ToUpper(o.name)
+}
+
+// options is the list of options, in the order they are printed
+var options = []option{
+	stringOption("server_address", "address where the server will be listening", false, func(c *Config) *string { return &c.ServerAddress }),
+	durationOption("read_header_timeout", "maximum duration to read the headers of a request", func(c *Config) *time.Duration { return &c.ReadHeaderTimeout }),
+	durationOption("read_timeout", "maximum duration to read a request, including the body", func(c *Config) *time.Duration { return &c.ReadTimeout }),
+	durationOption("write_timeout", "maximum duration to write a response", func(c *Config) *time.Duration { return &c.WriteTimeout }),
+	durationOption("idle_timeout", "maximum duration to wait for the next request on a keep-alive connection", func(c *Config) *time.Duration { return &c.IdleTimeout }),
+	durationOption("shutdown_timeout", "maximum duration to drain the in-flight requests on SIGINT / SIGTERM", func(c *Config) *time.Duration { return &c.ShutdownTimeout }),
+	durationOption("request_timeout", "deadline of a request, unless its route has one in route_timeouts (negative: none)", func(c *Config) *time.Duration { return &c.RequestTimeout }),
+	durationsOption("route_timeouts", "deadline of the requests of a route, comma separated (e.g. GET /vehicles/stats=30s,GET /vehicles/=5s)", func(c *Config) *map[string]time.Duration { return &c.RouteTimeouts }),
+	stringOption("log_level", "minimum level of the logged records: debug, info, warn or error", false, func(c *Config) *string { return &c.LogLevel }),
+	stringOption("log_format", "format of the logged records: json or text", false, func(c *Config) *string { return &c.LogFormat }),
+	stringOption("storage_backend", "where the vehicles are stored: file or sql", false, func(c *Config) *string { return &c.StorageBackend }),
+	stringOption("database_driver", "database/sql driver of the sql backend", false, func(c *Config) *string { return &c.DatabaseDriver }),
+	stringOption("database_dsn", "data source name of the sql backend", true, func(c *Config) *string { return &c.DatabaseDSN }),
+	stringOption("loader_file_path", "path to the file that contains the vehicles", false, func(c *Config) *string { return &c.LoaderFilePath }),
+	stringOption("loader_format", "format of the vehicles file: json, csv or ndjson (default: file extension)", false, func(c *Config) *string { return &c.LoaderFormat }),
+	boolOption("loader_strict", "fail the start if any record of the vehicles file is invalid", func(c *Config) *bool { return &c.LoaderStrict }),
+	stringOption("wal_file_path", "path to the write-ahead log (default: vehicles file + .wal)", false, func(c *Config) *string { return &c.WALFilePath }),
+	durationOption("compaction_interval", "interval between compactions of the write-ahead log", func(c *Config) *time.Duration { return &c.CompactionInterval }),
+	durationOption("reload_interval", "interval between checks of the vehicles file (negative: never)", func(c *Config) *time.Duration { return &c.ReloadInterval }),
+	stringOption("admin_token", "bearer token of the admin endpoints (an api key with the admin role)", true, func(c *Config) *string { return &c.AdminToken }),
+	stringsOption("api_keys", "role of each api key, comma separated (e.g. key1=reader,key2=editor)", true, func(c *Config) *map[string]string { return &c.APIKeys }),
+	stringOption("jwt_key", "HMAC-SHA256 key of the jwt bearer tokens (empty: tokens are not accepted)", true, func(c *Config) *string { return &c.JWTKey }),
+	stringOption("jwt_issuer", "required iss claim of the jwt bearer tokens", false, func(c *Config) *string { return &c.JWTIssuer }),
+	stringOption("jwt_audience", "audience the aud claim of the jwt bearer tokens must contain", false, func(c *Config) *string { return &c.JWTAudience }),
+	boolOption("auth_disabled", "open the vehicles endpoints and the metrics without credentials (default: read only vehicles)", func(c *Config) *bool { return &c.AuthDisabled }),
+	stringsOption("rate_limits", "limit of the requests of each client by route group, comma separated (e.g. read=20/s:40,aggregate=2/s:5)", false, func(c *Config) *map[string]string { return &c.RateLimits }),
+	intOption("max_in_flight", "maximum number of requests served at once (0: no limit)", func(c *Config) *int { return &c.MaxInFlight }),
+	durationOption("cache_max_age", "how long clients can reuse a response of the vehicle reads without revalidating it (0: every time)", func(c *Config) *time.Duration { return &c.CacheMaxAge }),
+}
+
+// stringOption is a function that returns an option of a string field
+func stringOption(name string, usage string, secret bool, field func(c *Config) *string) option {
+	return option{
+		name: name, usage: usage, secret: secret,
+		get: func(c *Config) any { return *field(c) },
+		set: func(c *Config, value string) error { *field(c) = value; return nil },
+	}
+}
+
+// boolOption is a function that returns an option of a bool field
+func boolOption(name string, usage string, field func(c *Config) *bool) option {
+	return option{
+		name: name, usage: usage,
+		get: func(c *Config) any { return *field(c) },
+		set: func(c *Config, value string) (err error) {
+			*field(c), err = strconv.ParseBool(value)
+			return
+		},
+	}
+}
+
+// intOption is a function that returns an option of an int field
+func intOption(name string, usage string, field func(c *Config) *int) option {
+	return option{
+		name: name, usage: usage,
+		get: func(c *Config) any { return *field(c) },
+		set: func(c *Config, value string) (err error) {
+			*field(c), err = strconv.Atoi(value)
+			return
+		},
+	}
+}
+
+// durationOption is a function that returns an option of a duration field (e.g. 30s, 1m)
+func durationOption(name string, usage string, field func(c *Config) *time.Duration) option {
+	return option{
+		name: name, usage: usage,
+		get: func(c *Config) any { return *field(c) },
+		set: func(c *Config, value string) (err error) {
+			*field(c), err = time.ParseDuration(value)
+			return
+		},
+	}
+}
+
+// durationsOption is a function that returns an option of a field of durations by key (e.g. key=30s,other key=1m)
+// - in a config file the value can also be a mapping
+func durationsOption(name string, usage string, field func(c *Config) *map[string]time.Duration) option {
+	return option{
+		name: name, usage: usage,
+		get: func(c *Config) any {
+			keys := make([]string, 0, len(*field(c)))
+			for key := range *field(c) {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			values := make([]string, len(keys))
+			for i, key := range keys {
+				values[i] = key + "=" + (*field(c))[key].String()
+			}
+			return strings.Join(values, ",")
+		},
+		set: func(c *Config, value string) (err error) {
+			m := make(map[string]time.Duration)
+			for _, item := range strings.Split(value, ",") {
+				if strings.TrimSpace(item) == "" {
+					continue
+				}
+				key, d, ok := strings.Cut(item, "=")
+				if !ok {
+					return fmt.Errorf("invalid item %q, expected key=duration", item)
+				}
+				m[strings.TrimSpace(key)], err = time.ParseDuration(strings.TrimSpace(d))
+				if err != nil {
+					return
+				}
+			}
+			*field(c) = m
+			return
+		},
+	}
+}
+
+// stringsOption is a function that returns an option of a field of strings by key (e.g. key=value,other key=value)
+// - in a config file the value can also be a mapping
+func stringsOption(name string, usage string, secret bool, field func(c *Config) *map[string]string) option {
+	return option{
+		name: name, usage: usage, secret: secret,
+		get: func(c *Config) any {
+			keys := make([]string, 0, len(*field(c)))
+			for key := range *field(c) {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			values := make([]string, len(keys))
+			for i, key := range keys {
+				values[i] = key + "=" + (*field(c))[key]
+			}
+			return strings.Join(values, ",")
+		},
+		set: func(c *Config, value string) (err error) {
+			m := make(map[string]string)
+			for _, item := range strings.Split(value, ",") {
+				if strings.TrimSpace(item) == "" {
+					continue
+				}
+				key, v, ok := strings.Cut(item, "=")
+				if !ok {
+					return errors.New("invalid item, expected key=value")
+				}
+				m[strings.TrimSpace(key)] = strings.TrimSpace(v)
+			}
+			*field(c) = m
+			return
+		},
+	}
+}
+
+// Load is a function that returns the configuration from its layers, each one overriding the previous ones
+// - defaults
+// - config file: --config flag or VEHICLES_CONFIG env var, JSON (.json) or YAML (.yaml, .yml)
+// - environment variables: VEHICLES_<NAME> (e.g. VEHICLES_SERVER_ADDRESS)
+// - command-line flags: --<name> (e.g. --server-address)
+// printConfig is true if --print-config was given. The configuration is not validated (see Validate)
+func Load(args []string, lookupEnv func(string) (string, bool)) (c Config, printConfig bool, err error) {
+	c = Default()
+
+	// flags: parsed first to find the config file, applied last
+	type flagValue struct {
+		o     option
+		value string
+	}
+	var flagValues []flagValue
+	fs := flag.NewFlagSet("vehicles", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to a JSON or YAML config file (env: "+EnvPrefix+"CONFIG)")
+	fs.BoolVar(&printConfig, "print-config", false, "print the effective config, with secrets redacted, and exit")
+	for _, o := range options {
+		o := o
+		fs.Func(o.flagName(), o.usage+" (env: "+o.envName()+")", func(value string) error {
+			flagValues = append(flagValues, flagValue{o: o, value: value})
+			return nil
+		})
+	}
+	err = fs.Parse(args)
+	if err != nil {
+		return
+	}
+
+	// config file
+	if *configPath == "" {
+		*configPath, _ = lookupEnv(EnvPrefix + "CONFIG")
+	}
+	if *configPath != "" {
+		err = c.loadFile(*configPath)
+		if err != nil {
+			return
+		}
+	}
+
+	// environment variables
+	for _, o := range options {
+		value, ok := lookupEnv(o.envName())
+		if !ok {
+			continue
+		}
+		if err = o.set(&c, value); err != nil {
+			err = fmt.Errorf("%w: env %s: %v", ErrConfigInvalid, o.envName(), err)
+			return
+		}
+	}
+
+	// flags
+	for _, fv := range flagValues {
+		if err = fv.o.set(&c, fv.value); err != nil {
+			err = fmt.Errorf("%w: flag --%s: %v", ErrConfigInvalid, fv.o.flagName(), err)
+			return
+		}
+	}
+
+	return
+}
+
+// loadFile is a method that sets the options of a JSON or YAML config file
+// - keys are the names of the options, unknown keys are an error
+func (c *Config) loadFile(path string) (err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		err = dec.Decode(&values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &values)
+	default:
+		err = fmt.Errorf("unknown extension %q, expected .json, .yaml or .yml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("%w: file %s: %v", ErrConfigInvalid, path, err)
+	}
+
+	for _, o := range options {
+		value, ok := values[o.name]
+		if !ok {
+			continue
+		}
+		delete(values, o.name)
+		// - mappings are set as key=value,key=value
+		if m, ok := value.(map[string]any); ok {
+			items := make([]string, 0, len(m))
+			for key, v := range m {
+				items = append(items, fmt.Sprintf("%s=%v", key, v))
+			}
+			value = strings.Join(items, ",")
+		}
+		if err = o.set(c, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("%w: file %s: %s: %v", ErrConfigInvalid, path, o.name, err)
+		}
+	}
+	for name := range values {
+		return fmt.Errorf("%w: file %s: unknown option %s", ErrConfigInvalid, path, name)
+	}
+	return
+}
+
+// Validate is a method that returns an error wrapping ErrConfigInvalid naming the first invalid option
+func (c Config) Validate() (err error) {
+	invalid := func(name string, format string, args ...any) error {
+		return fmt.Errorf("%w: %s: %s", ErrConfigInvalid, name, fmt.Sprintf(format, args...))
+	}
+
+	// server
+	_, port, err := net.SplitHostPort(c.ServerAddress)
+	if err != nil {
+		return invalid("server_address", "%v", err)
+	}
+	if _, errPort := strconv.ParseUint(port, 10, 16); errPort != nil {
+		return invalid("server_address", "invalid port %q", port)
+	}
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{name: "read_header_timeout", value: c.ReadHeaderTimeout},
+		{name: "read_timeout", value: c.ReadTimeout},
+		{name: "write_timeout", value: c.WriteTimeout},
+		{name: "idle_timeout", value: c.IdleTimeout},
+		{name: "shutdown_timeout", value: c.ShutdownTimeout},
+	} {
+		if timeout.value <= 0 {
+			return invalid(timeout.name, "must be positive")
+		}
+	}
+	for route, d := range c.RouteTimeouts {
+		method, pattern, ok := strings.Cut(route, " ")
+		if !ok || method == "" || method != strings.ToUpper(method) || !strings.HasPrefix(pattern, "/") {
+			return invalid("route_timeouts", "invalid route %q, expected METHOD /pattern", route)
+		}
+		if d <= 0 {
+			return invalid("route_timeouts", "%s: must be positive", route)
+		}
+	}
+
+	// log
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return invalid("log_level", "unknown level %q", c.LogLevel)
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		return invalid("log_format", "unknown format %q", c.LogFormat)
+	}
+
+	// storage
+	switch c.StorageBackend {
+	case application.StorageBackendFile:
+		info, errStat := os.Stat(c.LoaderFilePath)
+		if errStat != nil {
+			return invalid("loader_file_path", "%v", errStat)
+		}
+		if info.IsDir() {
+			return invalid("loader_file_path", "%s is a directory", c.LoaderFilePath)
+		}
+		if _, errFormat := loader.NewLoaderVehicleFile(c.LoaderFilePath, c.LoaderFormat); errFormat != nil {
+			return invalid("loader_format", "%v", errFormat)
+		}
+		if c.CompactionInterval <= 0 {
+			return invalid("compaction_interval", "must be positive")
+		}
+	case application.StorageBackendSQL:
+		if c.DatabaseDriver == "" {
+			return invalid("database_driver", "is required by the sql backend")
+		}
+		if c.DatabaseDSN == "" {
+			return invalid("database_dsn", "is required by the sql backend")
+		}
+	default:
+		return invalid("storage_backend", "unknown backend %q", c.StorageBackend)
+	}
+
+	// auth: secrets are not quoted in the errors
+	if c.AdminToken != "" && len(c.AdminToken) < minSecretLength {
+		return invalid("admin_token", "must have at least %d characters", minSecretLength)
+	}
+	for key, role := range c.APIKeys {
+		if len(key) < minSecretLength {
+			return invalid("api_keys", "every key must have at least %d characters", minSecretLength)
+		}
+		if _, errRole := auth.ParseRole(role); errRole != nil {
+			return invalid("api_keys", "unknown role %q, expected reader, editor or admin", role)
+		}
+	}
+	if c.JWTKey != "" && len(c.JWTKey) < minJWTKeyLength {
+		return invalid("jwt_key", "must have at least %d characters", minJWTKeyLength)
+	}
+	if c.AuthDisabled && (c.AdminToken != "" || len(c.APIKeys) > 0 || c.JWTKey != "") {
+		return invalid("auth_disabled", "credentials are configured")
+	}
+
+	// limits
+	for group, spec := range c.RateLimits {
+		if !slices.Contains(application.RouteGroups, group) {
+			return invalid("rate_limits", "unknown route group %q, expected one of %s", group, strings.Join(application.RouteGroups, ", "))
+		}
+		if _, errLimit := ratelimit.ParseLimit(spec); errLimit != nil {
+			return invalid("rate_limits", "%s: %v", group, errLimit)
+		}
+	}
+	if c.MaxInFlight < 0 {
+		return invalid("max_in_flight", "must not be negative")
+	}
+
+	// cache
+	if c.CacheMaxAge < 0 {
+		return invalid("cache_max_age", "must not be negative")
+	}
+
+	return nil
+}
+
+const (
+	// minSecretLength is the minimum length of the admin token and of the api keys
+	minSecretLength = 16
+	// minJWTKeyLength is the minimum length of the jwt key (the size of a HMAC-SHA256 key)
+	minJWTKeyLength = 32
+)
+
+// Print is a method that writes the configuration as YAML, with the value of the secrets redacted
+func (c Config) Print(w io.Writer) (err error) {
+	for _, o := range options {
+		value := o.get(&c)
+		switch v := value.(type) {
+		case string:
+			if o.secret && v != "" {
+				v = "REDACTED"
+			}
+			value = strconv.Quote(v)
+		case time.Duration:
+			value = strconv.Quote(v.String())
+		}
+		if _, err = fmt.Fprintf(w, "%s: %v\n", o.name, value); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Logger is a method that returns the logger of the configuration, writing to w
+// - an invalid level is taken as info (see Validate)
+func (c Config) Logger(w io.Writer) *slog.Logger {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(c.LogLevel))
+	return logging.NewLogger(w, c.LogFormat, level)
+}
+
+// Application is a method that returns the configuration of the application, logging with logger
+func (c Config) Application(logger *slog.Logger) *application.ConfigApplicationDefault {
+	return &application.ConfigApplicationDefault{
+		Logger:             logger,
+		ServerAddress:      c.ServerAddress,
+		ReadHeaderTimeout:  c.ReadHeaderTimeout,
+		ReadTimeout:        c.ReadTimeout,
+		WriteTimeout:       c.WriteTimeout,
+		IdleTimeout:        c.IdleTimeout,
+		ShutdownTimeout:    c.ShutdownTimeout,
+		RequestTimeout:     c.RequestTimeout,
+		RouteTimeouts:      c.RouteTimeouts,
+		StorageBackend:     c.StorageBackend,
+		DatabaseDriver:     c.DatabaseDriver,
+		DatabaseDSN:        c.DatabaseDSN,
+		LoaderFilePath:     c.LoaderFilePath,
+		LoaderFormat:       c.LoaderFormat,
+		LoaderStrict:       c.LoaderStrict,
+		WALFilePath:        c.WALFilePath,
+		CompactionInterval: c.CompactionInterval,
+		ReloadInterval:     c.ReloadInterval,
+		AdminToken:         c.AdminToken,
+		APIKeys:            c.APIKeys,
+		JWTKey:             c.JWTKey,
+		JWTIssuer:          c.JWTIssuer,
+		JWTAudience:        c.JWTAudience,
+		AuthDisabled:       c.AuthDisabled,
+		RateLimits:         c.RateLimits,
+		MaxInFlight:        c.MaxInFlight,
+		CacheMaxAge:        c.CacheMaxAge,
+	}
+}
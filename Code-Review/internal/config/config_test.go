@@ -0,0 +1,217 @@
+package config_test
+
+import (
+	"app/internal/config"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// env is a function that returns a lookupEnv func over a fixed set of variables
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (value string, ok bool) {
+		value, ok = vars[key]
+		return
+	}
+}
+
+// writeFile is a function that writes a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("case - defaults", func(t *testing.T) {
+		// arrange
+		// act
+		c, printConfig, err := config.Load(nil, env(nil))
+		// assert
+		require.NoError(t, err)
+		require.False(t, printConfig)
+		require.Equal(t, config.Default(), c)
+	})
+
+	t.Run("case - layers, file < env < flags", func(t *testing.T) {
+		// arrange
+		path := writeFile(t, "config.yaml", "server_address: \":9000\"\nloader_strict: true\nreload_interval: 10s\nadmin_token: from-file\n")
+		lookupEnv := env(map[string]string{
+			"VEHICLES_CONFIG":          path,
+			"VEHICLES_RELOAD_INTERVAL": "20s",
+			"VEHICLES_ADMIN_TOKEN":     "from-env",
+		})
+		args := []string{"--admin-token", "from-flag", "--print-config"}
+		// act
+		c, printConfig, err := config.Load(args, lookupEnv)
+		// assert
+		require.NoError(t, err)
+		require.True(t, printConfig)
+		require.Equal(t, ":9000", c.ServerAddress)
+		require.True(t, c.LoaderStrict)
+		require.Equal(t, 20*time.Second, c.ReloadInterval)
+		require.Equal(t, "from-flag", c.AdminToken)
+		require.Equal(t, time.Minute, c.CompactionInterval)
+	})
+
+	t.Run("case - api keys as a mapping or a list", func(t *testing.T) {
+		// arrange
+		path := writeFile(t, "config.yaml", "api_keys:\n  reader-key-0123456789: reader\n  editor-key-0123456789: editor\n")
+		// act
+		fromFile, _, errFile := config.Load([]string{"--config", path}, env(nil))
+		fromEnv, _, errEnv := config.Load(nil, env(map[string]string{"VEHICLES_API_KEYS": "reader-key-0123456789=reader, editor-key-0123456789=editor"}))
+		// assert
+		require.NoError(t, errFile)
+		require.NoError(t, errEnv)
+		expected := map[string]string{"reader-key-0123456789": "reader", "editor-key-0123456789": "editor"}
+		require.Equal(t, expected, fromFile.APIKeys)
+		require.Equal(t, expected, fromEnv.APIKeys)
+	})
+
+	t.Run("case - limits", func(t *testing.T) {
+		// arrange
+		path := writeFile(t, "config.yaml", "rate_limits:\n  aggregate: 2/s:5\nmax_in_flight: 64\n")
+		// act
+		fromFile, _, errFile := config.Load([]string{"--config", path}, env(nil))
+		fromEnv, _, errEnv := config.Load(nil, env(map[string]string{"VEHICLES_RATE_LIMITS": "aggregate=2/s:5", "VEHICLES_MAX_IN_FLIGHT": "64"}))
+		// assert
+		require.NoError(t, errFile)
+		require.NoError(t, errEnv)
+		for _, c := range []config.Config{fromFile, fromEnv} {
+			require.Equal(t, map[string]string{"aggregate": "2/s:5"}, c.RateLimits)
+			require.Equal(t, 64, c.MaxInFlight)
+		}
+	})
+
+	t.Run("case - json file given by flag", func(t *testing.T) {
+		// arrange
+		path := writeFile(t, "config.json", `{"storage_backend": "sql", "database_dsn": "file::memory:", "loader_strict": false, "compaction_interval": "30s"}`)
+		// act
+		c, _, err := config.Load([]string{"--config", path}, env(nil))
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "sql", c.StorageBackend)
+		require.Equal(t, "file::memory:", c.DatabaseDSN)
+		require.Equal(t, 30*time.Second, c.CompactionInterval)
+	})
+
+	t.Run("case - route timeouts, flag and yaml mapping", func(t *testing.T) {
+		// arrange
+		path := writeFile(t, "config.yaml", "route_timeouts:\n  GET /vehicles/stats: 30s\n  POST /admin/reload: 1m\n")
+		// act
+		fromFile, _, errFile := config.Load([]string{"--config", path}, env(nil))
+		fromFlag, _, errFlag := config.Load([]string{"--route-timeouts", "GET /vehicles/stats=30s, POST /admin/reload=1m"}, env(nil))
+		// assert
+		require.NoError(t, errFile)
+		require.NoError(t, errFlag)
+		expected := map[string]time.Duration{"GET /vehicles/stats": 30 * time.Second, "POST /admin/reload": time.Minute}
+		require.Equal(t, expected, fromFile.RouteTimeouts)
+		require.Equal(t, expected, fromFlag.RouteTimeouts)
+	})
+
+	t.Run("case error, invalid values", func(t *testing.T) {
+		cases := []struct {
+			name string
+			args []string
+			env  map[string]string
+		}{
+			{name: "unknown key", args: []string{"--config", writeFile(t, "config.yaml", "server_adress: \":9000\"\n")}},
+			{name: "unknown extension", args: []string{"--config", writeFile(t, "config.toml", "")}},
+			{name: "missing file", args: []string{"--config", filepath.Join(t.TempDir(), "config.json")}},
+			{name: "file duration", args: []string{"--config", writeFile(t, "config.json", `{"reload_interval": 5}`)}},
+			{name: "env bool", env: map[string]string{"VEHICLES_LOADER_STRICT": "maybe"}},
+			{name: "flag duration", args: []string{"--compaction-interval", "soon"}},
+			{name: "flag durations", args: []string{"--route-timeouts", "GET /vehicles/stats"}},
+			{name: "flag int", args: []string{"--max-in-flight", "many"}},
+			{name: "unknown flag", args: []string{"--server-adress", ":9000"}},
+		}
+		for _, cs := range cases {
+			// act
+			_, _, err := config.Load(cs.args, env(cs.env))
+			// assert
+			require.Error(t, err, cs.name)
+		}
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("case - success", func(t *testing.T) {
+		// arrange
+		c := config.Default()
+		c.LoaderFilePath = writeFile(t, "vehicles.csv", "")
+		// act
+		err := c.Validate()
+		// assert
+		require.NoError(t, err)
+	})
+
+	t.Run("case error, invalid config", func(t *testing.T) {
+		file := writeFile(t, "vehicles.json", "[]")
+		cases := []struct {
+			name   string
+			modify func(c *config.Config)
+		}{
+			{name: "address without port", modify: func(c *config.Config) { c.ServerAddress = "localhost" }},
+			{name: "address with invalid port", modify: func(c *config.Config) { c.ServerAddress = ":99999" }},
+			{name: "missing file", modify: func(c *config.Config) { c.LoaderFilePath = filepath.Join(t.TempDir(), "vehicles.json") }},
+			{name: "directory", modify: func(c *config.Config) { c.LoaderFilePath = t.TempDir() }},
+			{name: "unknown format", modify: func(c *config.Config) { c.LoaderFormat = "xml" }},
+			{name: "write timeout", modify: func(c *config.Config) { c.WriteTimeout = 0 }},
+			{name: "shutdown timeout", modify: func(c *config.Config) { c.ShutdownTimeout = -time.Second }},
+			{name: "route without method", modify: func(c *config.Config) { c.RouteTimeouts = map[string]time.Duration{"/vehicles/stats": time.Second} }},
+			{name: "route timeout", modify: func(c *config.Config) { c.RouteTimeouts = map[string]time.Duration{"GET /vehicles/stats": 0} }},
+			{name: "compaction interval", modify: func(c *config.Config) { c.CompactionInterval = 0 }},
+			{name: "unknown log level", modify: func(c *config.Config) { c.LogLevel = "verbose" }},
+			{name: "unknown log format", modify: func(c *config.Config) { c.LogFormat = "xml" }},
+			{name: "unknown backend", modify: func(c *config.Config) { c.StorageBackend = "redis" }},
+			{name: "sql without dsn", modify: func(c *config.Config) { c.StorageBackend = "sql" }},
+			{name: "short admin token", modify: func(c *config.Config) { c.AdminToken = "secret" }},
+			{name: "short api key", modify: func(c *config.Config) { c.APIKeys = map[string]string{"key": "reader"} }},
+			{name: "unknown role", modify: func(c *config.Config) { c.APIKeys = map[string]string{"0123456789abcdef": "root"} }},
+			{name: "short jwt key", modify: func(c *config.Config) { c.JWTKey = "0123456789abcdef" }},
+			{name: "auth disabled with credentials", modify: func(c *config.Config) { c.AuthDisabled, c.AdminToken = true, "admin-token-0123456789" }},
+			{name: "unknown route group", modify: func(c *config.Config) { c.RateLimits = map[string]string{"vehicles": "10/s"} }},
+			{name: "rate limit", modify: func(c *config.Config) { c.RateLimits = map[string]string{"aggregate": "10/day"} }},
+			{name: "max in flight", modify: func(c *config.Config) { c.MaxInFlight = -1 }},
+			{name: "cache max age", modify: func(c *config.Config) { c.CacheMaxAge = -time.Second }},
+		}
+		for _, cs := range cases {
+			// arrange
+			c := config.Default()
+			c.LoaderFilePath = file
+			cs.modify(&c)
+			// act
+			err := c.Validate()
+			// assert
+			require.ErrorIs(t, err, config.ErrConfigInvalid, cs.name)
+		}
+	})
+}
+
+func TestConfig_Print(t *testing.T) {
+	// arrange
+	c := config.Default()
+	c.DatabaseDSN = "user:password@/vehicles"
+	c.AdminToken = "secret"
+	c.APIKeys = map[string]string{"key-0123456789abcdef": "reader"}
+	c.RouteTimeouts = map[string]time.Duration{"POST /admin/reload": time.Minute, "GET /vehicles/stats": 30 * time.Second}
+	var b bytes.Buffer
+	// act
+	err := c.Print(&b)
+	// assert
+	require.NoError(t, err)
+	require.Contains(t, b.String(), "server_address: \":8080\"\n")
+	require.Contains(t, b.String(), "compaction_interval: \"1m0s\"\n")
+	require.Contains(t, b.String(), "loader_strict: false\n")
+	require.Contains(t, b.String(), "route_timeouts: \"GET /vehicles/stats=30s,POST /admin/reload=1m0s\"\n")
+	require.Contains(t, b.String(), "admin_token: \"REDACTED\"\n")
+	require.Contains(t, b.String(), "database_dsn: \"REDACTED\"\n")
+	require.NotContains(t, b.String(), "secret")
+	require.NotContains(t, b.String(), "password")
+	require.Contains(t, b.String(), "api_keys: \"REDACTED\"\n")
+	require.NotContains(t, b.String(), "key-0123456789abcdef")
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"app/internal"
+	"app/platform/web/logging"
+	"app/platform/web/response"
+	"errors"
+	"net/http"
+)
+
+// HandlerAdmin is a struct with methods that represent handlers for the administration of the service
+type HandlerAdmin struct {
+	// rl is the reloader of the vehicles
+	rl internal.ReloaderVehicle
+}
+
+// NewHandlerAdmin is a function that returns a new instance of HandlerAdmin
+// - the handlers do not authenticate the requests, their routes must require the admin role (see auth.Require)
+func NewHandlerAdmin(rl internal.ReloaderVehicle) *HandlerAdmin {
+	return &HandlerAdmin{rl: rl}
+}
+
+// ReloadReportJSON is a struct that represents the result of a reload in JSON format
+type ReloadReportJSON struct {
+	// Records is the number of records read
+	Records int `json:"records"`
+	// Skipped is the number of invalid records skipped
+	Skipped int `json:"skipped"`
+	// Problems is the number of problems found (errors and warnings)
+	Problems int `json:"problems"`
+}
+
+// Reload returns a handler that reloads the vehicles from their source
+func (h *HandlerAdmin) Reload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// process
+		report, err := h.rl.Reload()
+		if err != nil {
+			if errors.Is(err, internal.ErrReloadInvalid) {
+				logging.FromContext(r.Context()).WarnContext(r.Context(), "handler: reload rejected", "error", err)
+			}
+			writeError(w, r, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "vehicles reloaded",
+			"data": ReloadReportJSON{
+				Records:  report.Records,
+				Skipped:  report.Skipped,
+				Problems: len(report.Problems),
+			},
+		})
+	}
+}
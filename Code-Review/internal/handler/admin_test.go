@@ -0,0 +1,71 @@
+package handler_test
+
+import (
+	"app/internal"
+	"app/internal/handler"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// reloaderStub is a struct that implements internal.ReloaderVehicle with a fixed result
+type reloaderStub struct {
+	report internal.ValidationReport
+	err    error
+	calls  int
+}
+
+// Reload is a method that returns the fixed result
+func (r *reloaderStub) Reload() (internal.ValidationReport, error) {
+	r.calls++
+	return r.report, r.err
+}
+
+func TestHandlerAdmin_Reload(t *testing.T) {
+	// serve is a function that runs a reload request
+	serve := func(rl internal.ReloaderVehicle) *httptest.ResponseRecorder {
+		hd := handler.NewHandlerAdmin(rl)
+		r := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+		w := httptest.NewRecorder()
+		hd.Reload().ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("case - success", func(t *testing.T) {
+		// arrange
+		rl := &reloaderStub{report: internal.ValidationReport{
+			Records: 3, Skipped: 1,
+			Problems: []internal.ValidationProblem{{Field: "id", Severity: internal.ValidationError}},
+		}}
+		// act
+		w := serve(rl)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		expectBody := `{
+			"message": "vehicles reloaded",
+			"data": {"records": 3, "skipped": 1, "problems": 1}
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		require.Equal(t, 1, rl.calls)
+	})
+
+	t.Run("case error, invalid file", func(t *testing.T) {
+		// arrange
+		rl := &reloaderStub{err: fmt.Errorf("%w: %w", internal.ErrReloadInvalid, internal.ErrValidationFailed)}
+		// act
+		w := serve(rl)
+		// assert
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Unprocessable Entity",
+			"status": 422,
+			"detail": "invalid vehicles, the current ones are kept",
+			"instance": "/admin/reload"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+	})
+}
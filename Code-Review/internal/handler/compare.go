@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ComparisonJSON is a struct that represents some vehicles aligned attribute by attribute in JSON format
+type ComparisonJSON struct {
+	// Ids is the id of each compared vehicle, the order of the values of every attribute
+	Ids []int `json:"ids"`
+	// Attributes is the comparison of each attribute, the text ones first
+	Attributes []ComparedAttributeJSON `json:"attributes"`
+}
+
+// ComparedAttributeJSON is a struct that represents an attribute of the compared vehicles in JSON format
+// - best, ranks and deltas are only set for the numeric attributes
+type ComparedAttributeJSON struct {
+	// Name is the name of the attribute
+	Name string `json:"name"`
+	// Values is the value of each vehicle
+	Values any `json:"values"`
+	// Differ is true if any two vehicles have different values
+	Differ bool `json:"differ"`
+	// Best is the value that ranks first: max or min
+	Best string `json:"best,omitempty"`
+	// Ranks is the rank of each vehicle, from 1
+	Ranks []int `json:"ranks,omitempty"`
+	// Deltas is the distance of each vehicle to the best value
+	Deltas []float64 `json:"deltas,omitempty"`
+}
+
+// parseCompareIds is a function that decodes the ids of a comparison from the query of a request
+// - ids: comma separated ids (e.g. ids=1,7,42), their number is checked by the service
+func parseCompareIds(q url.Values) (ids []int, err error) {
+	if q.Get("ids") == "" {
+		err = errors.New("invalid ids: missing")
+		return
+	}
+	for _, s := range strings.Split(q.Get("ids"), ",") {
+		id, errId := strconv.Atoi(strings.TrimSpace(s))
+		if errId != nil {
+			err = fmt.Errorf("invalid ids: %q is not an id", s)
+			return
+		}
+		ids = append(ids, id)
+	}
+	return
+}
+
+// comparisonToJSON is a function that maps a comparison to JSON
+// - the values and deltas of the attributes with a unit are converted to the unit system
+func comparisonToJSON(c internal.VehicleComparison, u internal.UnitSystem) ComparisonJSON {
+	cj := ComparisonJSON{
+		Ids:        make([]int, len(c.Vehicles)),
+		Attributes: make([]ComparedAttributeJSON, 0, len(c.Texts)+len(c.Numbers)),
+	}
+	for i, v := range c.Vehicles {
+		cj.Ids[i] = v.Id
+	}
+	for _, t := range c.Texts {
+		cj.Attributes = append(cj.Attributes, ComparedAttributeJSON{Name: t.Name, Values: t.Values, Differ: t.Differ})
+	}
+	for _, n := range c.Numbers {
+		if q, ok := internal.VehicleQuantities[n.Name]; ok {
+			n.Values, n.Deltas = convertFromCanonical(u, q, n.Values), convertFromCanonical(u, q, n.Deltas)
+		}
+		cj.Attributes = append(cj.Attributes, ComparedAttributeJSON{
+			Name:   n.Name,
+			Values: n.Values,
+			Differ: n.Differ,
+			Best:   string(n.Best),
+			Ranks:  n.Ranks,
+			Deltas: n.Deltas,
+		})
+	}
+	return cj
+}
+
+// convertFromCanonical is a function that returns the values of a quantity converted from canonical units to the unit system
+func convertFromCanonical(u internal.UnitSystem, q internal.Quantity, values []float64) []float64 {
+	converted := make([]float64, len(values))
+	for i, value := range values {
+		converted[i] = u.FromCanonical(q, value)
+	}
+	return converted
+}
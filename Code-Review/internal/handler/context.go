@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"app/platform/web/logging"
+	"net/http"
+)
+
+// StatusClientClosedRequest is the status of a request cancelled by the client before the response (nginx 499)
+// - it is only logged: the client is gone, so nothing is sent
+const StatusClientClosedRequest = 499
+
+// writeCanceled is a function that records a request cancelled by the client
+func writeCanceled(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context()).WarnContext(r.Context(), "handler: request cancelled by the client", "method", r.Method, "path", r.URL.Path)
+	w.WriteHeader(StatusClientClosedRequest)
+}
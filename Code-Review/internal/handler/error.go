@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"app/internal"
+	"app/platform/web/logging"
+	"app/platform/web/metrics"
+	"app/platform/web/response"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ErrorKindNoVehicles is the metrics kind of the requests that found no vehicles (internal.ErrServiceNoVehicles)
+	ErrorKindNoVehicles = "no_vehicles"
+	// ErrorKindInternal is the metrics kind of the requests that failed with an unexpected error
+	ErrorKindInternal = "internal"
+)
+
+// problems is the registry of the problems written for the errors returned by the services
+var problems = newProblemRegistry()
+
+// newProblemRegistry is a function that returns the registry of the domain errors
+// - specific errors go before the ones they wrap (e.g. the repository conflicts before the service one)
+func newProblemRegistry() *response.ProblemRegistry {
+	rg := response.NewProblemRegistry()
+	// - invalid requests
+	rg.RegisterFunc(func(err error) (p response.Problem, ok bool) {
+		var fieldErr *internal.FilterFieldError
+		if !errors.As(err, &fieldErr) {
+			return
+		}
+		p = response.Problem{
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("invalid %s: %s", fieldErr.Field, fieldErr.Message),
+			Errors: []response.ProblemField{{Field: fieldErr.Field, Message: fieldErr.Message}},
+		}
+		ok = true
+		return
+	})
+	rg.Register(internal.ErrServiceInvalidFind, http.StatusBadRequest, "invalid find")
+	rg.Register(internal.ErrRepositoryInvalidFind, http.StatusBadRequest, "invalid find")
+	rg.Register(internal.ErrServiceInvalidSearch, http.StatusBadRequest, "invalid search")
+	rg.Register(internal.ErrServiceInvalidStats, http.StatusBadRequest, "invalid stats")
+	rg.Register(internal.ErrPageInvalid, http.StatusBadRequest, "invalid page")
+	rg.RegisterFunc(func(err error) (p response.Problem, ok bool) {
+		var reportErr *internal.ValidationReportError
+		if !errors.Is(err, internal.ErrServiceInvalidVehicle) || !errors.As(err, &reportErr) {
+			return
+		}
+		// the attributes are well formed but break the rules of the vehicles
+		p = response.Problem{Status: http.StatusUnprocessableEntity, Detail: "invalid vehicle"}
+		for _, pr := range reportErr.Report.Problems {
+			p.Errors = append(p.Errors, response.ProblemField{Field: pr.Field, Message: pr.Message})
+		}
+		ok = true
+		return
+	})
+	rg.Register(internal.ErrServiceInvalidVehicle, http.StatusBadRequest, "invalid vehicle")
+	rg.Register(internal.ErrServiceInvalidSimilar, http.StatusBadRequest, "invalid similar query")
+	rg.RegisterFunc(func(err error) (p response.Problem, ok bool) {
+		var compareErr *internal.CompareError
+		if !errors.As(err, &compareErr) {
+			return
+		}
+		p = response.Problem{
+			Status: http.StatusBadRequest,
+			Detail: "invalid ids: " + compareErr.Reason,
+			Errors: []response.ProblemField{{Field: "ids", Message: compareErr.Reason}},
+		}
+		ok = true
+		return
+	})
+	rg.RegisterFunc(func(err error) (p response.Problem, ok bool) {
+		var notAcceptableErr *NotAcceptableError
+		if !errors.As(err, &notAcceptableErr) {
+			return
+		}
+		p = response.Problem{
+			Status: http.StatusNotAcceptable,
+			Detail: "not acceptable, supported media types: " + strings.Join(notAcceptableErr.Offers, ", "),
+		}
+		ok = true
+		return
+	})
+	rg.Register(internal.ErrReloadInvalid, http.StatusUnprocessableEntity, "invalid vehicles, the current ones are kept")
+	// - missing vehicles
+	rg.RegisterFunc(func(err error) (p response.Problem, ok bool) {
+		var notFoundErr *internal.VehiclesNotFoundError
+		if !errors.As(err, &notFoundErr) {
+			return
+		}
+		p = response.Problem{Status: http.StatusNotFound, Detail: notFoundErr.Error()}
+		for _, id := range notFoundErr.Ids {
+			p.Errors = append(p.Errors, response.ProblemField{Field: "ids", Message: fmt.Sprintf("vehicle %d not found", id)})
+		}
+		ok = true
+		return
+	})
+	rg.RegisterFunc(func(err error) (p response.Problem, ok bool) {
+		var noVehiclesErr *internal.NoVehiclesError
+		if !errors.As(err, &noVehiclesErr) {
+			return
+		}
+		p = response.Problem{Status: http.StatusNotFound, Detail: "vehicles not found"}
+		for _, s := range noVehiclesErr.Suggestions {
+			p.Suggestions = append(p.Suggestions, response.ProblemSuggestion{Field: s.Attribute, Value: s.Value, DidYouMean: s.Suggestions})
+		}
+		ok = true
+		return
+	})
+	rg.Register(internal.ErrServiceNoVehicles, http.StatusNotFound, "vehicles not found")
+	rg.Register(internal.ErrServiceVehicleNotFound, http.StatusNotFound, "vehicle not found")
+	// - conflicts
+	rg.Register(internal.ErrRepositoryRegistrationDuplicated, http.StatusConflict, "registration already exists")
+	rg.Register(internal.ErrServiceVehicleConflict, http.StatusConflict, "vehicle already exists")
+	// - timeouts
+	rg.Register(context.DeadlineExceeded, http.StatusGatewayTimeout, "request timed out")
+	return rg
+}
+
+// writeError is a function that writes the problem of an error returned by a service
+// - a request cancelled by the client gets no body (see writeCanceled)
+// - an error that is not registered is logged with its wrapped causes, by the logger of the request (see logging.FromContext)
+// - the client only gets "internal error" and the request id, to match the response with the log
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) {
+		writeCanceled(w, r)
+		return
+	}
+
+	p, ok := problems.Problem(err)
+	switch {
+	case !ok:
+		metrics.RecordError(r.Context(), ErrorKindInternal)
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "handler: internal error", "method", r.Method, "path", r.URL.Path, "error", err)
+	case errors.Is(err, internal.ErrServiceNoVehicles):
+		metrics.RecordError(r.Context(), ErrorKindNoVehicles)
+	}
+	response.WriteProblem(w, r, p)
+}
+
+// writeBadRequest is a function that writes the problem of a request that could not be decoded
+func writeBadRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	response.WriteProblem(w, r, response.Problem{Status: http.StatusBadRequest, Detail: detail})
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"app/internal"
+	"app/platform/web/response"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageMetaJSON is a struct that represents the metadata of a page of vehicles in JSON format
+type PageMetaJSON struct {
+	// Total is the number of vehicles before paging
+	Total int `json:"total"`
+	// Limit is the maximum number of vehicles in the page (omitted if there is no limit)
+	Limit int `json:"limit,omitempty"`
+	// Offset is the number of vehicles skipped (0 on the pages of a cursor)
+	Offset int `json:"offset"`
+	// NextCursor is the cursor of the next page (null if this is the last page)
+	NextCursor *string `json:"next_cursor"`
+	// Units is the units of the quantities of the vehicles
+	Units UnitsMetaJSON `json:"units"`
+}
+
+// cursorJSON is a struct that represents the content of an opaque page cursor
+// - only what the sort compares of the last vehicle of the previous page: the values of the sort fields and the id
+type cursorJSON struct {
+	// Sort is the sort the cursor was issued for
+	Sort string `json:"s"`
+	// Keys is the value of each sort field, in the order of Sort
+	Keys []json.RawMessage `json:"k"`
+	// Id is the id (the last tie-breaker of the sort)
+	Id int `json:"i"`
+}
+
+// cursorFields is the attribute of a vehicle of each sort field of internal.VehicleSortFields, to encode and decode a cursor
+var cursorFields = map[string]func(v *internal.Vehicle) any{
+	"id":           func(v *internal.Vehicle) any { return &v.Id },
+	"brand":        func(v *internal.Vehicle) any { return &v.Brand },
+	"model":        func(v *internal.Vehicle) any { return &v.Model },
+	"registration": func(v *internal.Vehicle) any { return &v.Registration },
+	"color":        func(v *internal.Vehicle) any { return &v.Color },
+	"year":         func(v *internal.Vehicle) any { return &v.FabricationYear },
+	"capacity":     func(v *internal.Vehicle) any { return &v.Capacity },
+	"max_speed":    func(v *internal.Vehicle) any { return &v.MaxSpeed },
+	"fuel_type":    func(v *internal.Vehicle) any { return &v.FuelType },
+	"transmission": func(v *internal.Vehicle) any { return &v.Transmission },
+	"weight":       func(v *internal.Vehicle) any { return &v.Weight },
+	"height":       func(v *internal.Vehicle) any { return &v.Height },
+	"length":       func(v *internal.Vehicle) any { return &v.Length },
+	"width":        func(v *internal.Vehicle) any { return &v.Width },
+}
+
+// parsePageQuery is a function that decodes a PageQuery from the query of a request
+// - limit, offset: numeric paging
+// - cursor: opaque cursor returned as meta.next_cursor (takes precedence over offset, which is then 0)
+// - sort: comma separated attributes, prefixed with - for descending order (e.g. sort=brand,-year)
+func parsePageQuery(q url.Values) (pq internal.PageQuery, err error) {
+	// limit and offset
+	if q.Has("limit") {
+		pq.Limit, err = strconv.Atoi(q.Get("limit"))
+		if err != nil || pq.Limit < 0 {
+			err = errors.New("invalid limit")
+			return
+		}
+	}
+	if q.Has("offset") {
+		pq.Offset, err = strconv.Atoi(q.Get("offset"))
+		if err != nil || pq.Offset < 0 {
+			err = errors.New("invalid offset")
+			return
+		}
+	}
+
+	// sort
+	if s := q.Get("sort"); s != "" {
+		for _, name := range strings.Split(s, ",") {
+			field := internal.SortField{Name: strings.TrimPrefix(name, "-"), Desc: strings.HasPrefix(name, "-")}
+			if _, ok := internal.VehicleSortFields[field.Name]; !ok {
+				err = fmt.Errorf("invalid sort field %s", field.Name)
+				return
+			}
+			pq.Sort = append(pq.Sort, field)
+		}
+	}
+
+	// cursor
+	if q.Has("cursor") {
+		var after internal.Vehicle
+		after, err = decodeCursor(pq.Sort, q.Get("cursor"))
+		if err != nil {
+			return
+		}
+		pq.After, pq.Offset = &after, 0
+	}
+
+	return
+}
+
+// formatSort is a function that encodes a list of sort fields as the sort query parameter
+func formatSort(fields []internal.SortField) string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f.Desc {
+			names = append(names, "-"+f.Name)
+			continue
+		}
+		names = append(names, f.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// encodeCursor is a function that encodes the cursor of the page after the given vehicle
+func encodeCursor(fields []internal.SortField, after internal.Vehicle) string {
+	c := cursorJSON{Sort: formatSort(fields), Keys: make([]json.RawMessage, 0, len(fields)), Id: after.Id}
+	for _, f := range fields {
+		key, _ := json.Marshal(cursorFields[f.Name](&after))
+		c.Keys = append(c.Keys, key)
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor is a function that decodes a cursor issued for the sort fields
+// - the vehicle has only the attributes the sort compares set
+func decodeCursor(fields []internal.SortField, cursor string) (after internal.Vehicle, err error) {
+	var c cursorJSON
+	b, e := base64.RawURLEncoding.DecodeString(cursor)
+	if e != nil || json.Unmarshal(b, &c) != nil || c.Sort != formatSort(fields) || len(c.Keys) != len(fields) {
+		err = errors.New("invalid cursor")
+		return
+	}
+	for i, f := range fields {
+		if json.Unmarshal(c.Keys[i], cursorFields[f.Name](&after)) != nil {
+			after, err = internal.Vehicle{}, errors.New("invalid cursor")
+			return
+		}
+	}
+	after.Id = c.Id
+	return
+}
+
+// writeVehicles is a function that sorts and pages a list of vehicles and writes it as the response (see writePage)
+func writeVehicles(w http.ResponseWriter, r *http.Request, message string, v []internal.Vehicle, pq internal.PageQuery, vw vehicleView) {
+	page, p, err := internal.PageVehicles(v, pq)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writePage(w, r, message, page, p, pq, vw)
+}
+
+// writePage is a function that writes a page of vehicles as the response
+// - in the format of the view: json with the metadata of the page in the body, or a record format (see writeVehicleRecords)
+func writePage(w http.ResponseWriter, r *http.Request, message string, page []internal.Vehicle, p internal.Page, pq internal.PageQuery, vw vehicleView) {
+	meta := PageMetaJSON{Total: p.Total, Limit: pq.Limit, Offset: pq.Offset, Units: unitsMetaToJSON(vw.units)}
+	if p.Next != nil {
+		cursor := encodeCursor(pq.Sort, *p.Next)
+		meta.NextCursor = &cursor
+	}
+
+	if vw.format != vehicleFormatJSON {
+		writeVehicleRecords(w, r, internal.NewVehicleIteratorSlice(page), meta, vw)
+		return
+	}
+	response.JSONMediaType(w, http.StatusOK, MediaTypeVehicleV1, map[string]any{
+		"message": message,
+		"data":    vw.renderAll(page),
+		"meta":    meta,
+	})
+}
+
+// streamable is a function that returns true if a list of vehicles can be streamed from the repository as it is read
+// - a record format (see writeVehicleRecords), in id order (the order of the repository) and not paged by limit or cursor
+// - an offset only skips the first vehicles, so it does not need them in memory
+func streamable(pq internal.PageQuery, vw vehicleView) bool {
+	if vw.format == vehicleFormatJSON || pq.Limit > 0 || pq.After != nil {
+		return false
+	}
+	for _, f := range pq.Sort {
+		if f.Name != "id" || f.Desc {
+			return false
+		}
+	}
+	return true
+}
+
+// streamVehicles is a function that writes the vehicles of a streamable list as they are read from the iteration
+// - the vehicles before the offset are read and dropped, the iteration is closed once written
+func streamVehicles(w http.ResponseWriter, r *http.Request, it internal.VehicleIterator, pq internal.PageQuery, vw vehicleView) {
+	meta := PageMetaJSON{Total: it.Total(), Offset: pq.Offset, Units: unitsMetaToJSON(vw.units)}
+	for skipped := 0; skipped < pq.Offset; skipped++ {
+		if !it.Next() {
+			break
+		}
+	}
+	writeVehicleRecords(w, r, it, meta, vw)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"app/internal"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SimilarVehicleJSON is a struct that represents a vehicle similar to another one in JSON format
+type SimilarVehicleJSON struct {
+	// Vehicle is the similar vehicle
+	Vehicle VehicleJSON `json:"vehicle"`
+	// Distance is the distance to the other vehicle, from 0 (same attributes) to 1
+	Distance float64 `json:"distance"`
+}
+
+// SimilarMetaJSON is a struct that represents the query of the similar vehicles in JSON format
+type SimilarMetaJSON struct {
+	// Id is the id of the vehicle the others are similar to
+	Id int `json:"id"`
+	// K is the maximum number of similar vehicles
+	K int `json:"k"`
+	// Weights is the weight of each numeric attribute
+	Weights map[string]float64 `json:"weights"`
+	// Same is the list of attributes the similar vehicles share
+	Same []string `json:"same"`
+	// Units is the units of the quantities of the similar vehicles
+	Units UnitsMetaJSON `json:"units"`
+}
+
+// parseSimilarQuery is a function that decodes a SimilarQuery from the query of a request
+// - k: number of similar vehicles (default: internal.SimilarDefaultK)
+// - weights: comma separated attribute:weight of the numeric attributes (optional, e.g. weights=max_speed:2,capacity:1, missing ones weigh 1)
+// - same: comma separated attributes the similar vehicles must share: fuel_type, transmission (optional)
+func parseSimilarQuery(q url.Values) (sq internal.SimilarQuery, err error) {
+	sq.K = internal.SimilarDefaultK
+	if q.Has("k") {
+		sq.K, err = strconv.Atoi(q.Get("k"))
+		if err != nil || sq.K < 1 || sq.K > internal.SimilarMaxK {
+			err = fmt.Errorf("invalid k, expected a number between 1 and %d", internal.SimilarMaxK)
+			return
+		}
+	}
+
+	// report the first unknown parameter value, as the client sent it
+	if s := q.Get("weights"); s != "" {
+		sq.Weights = make(map[string]float64)
+		for _, item := range strings.Split(s, ",") {
+			name, value, ok := strings.Cut(item, ":")
+			if _, known := internal.VehicleStatsMetrics[name]; !ok || !known {
+				err = fmt.Errorf("invalid weights %s", item)
+				return
+			}
+			w, errWeight := strconv.ParseFloat(value, 64)
+			if errWeight != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				err = fmt.Errorf("invalid weights %s", item)
+				return
+			}
+			sq.Weights[name] = w
+		}
+	}
+	if s := q.Get("same"); s != "" {
+		sq.Same = strings.Split(s, ",")
+		for _, name := range sq.Same {
+			if _, ok := internal.VehicleSimilarSame[name]; !ok {
+				err = fmt.Errorf("invalid same %s", name)
+				return
+			}
+		}
+	}
+	return
+}
+
+// similarToJSON is a function that maps the similar vehicles to JSON
+// - the distances do not depend on the unit system: the attributes are normalized
+func similarToJSON(matches []internal.SimilarVehicle, u internal.UnitSystem) []SimilarVehicleJSON {
+	data := make([]SimilarVehicleJSON, 0, len(matches))
+	for _, m := range matches {
+		data = append(data, SimilarVehicleJSON{Vehicle: vehicleToJSON(u.Vehicle(m.Vehicle)), Distance: m.Distance})
+	}
+	return data
+}
+
+// similarMetaToJSON is a function that maps the query of the similar vehicles to JSON, with the weight of every attribute
+func similarMetaToJSON(id int, sq internal.SimilarQuery, u internal.UnitSystem) SimilarMetaJSON {
+	meta := SimilarMetaJSON{
+		Id:      id,
+		K:       sq.K,
+		Weights: make(map[string]float64),
+		Same:    append([]string{}, sq.Same...),
+		Units:   unitsMetaToJSON(u),
+	}
+	for _, name := range internal.VehicleCompareNumbers {
+		meta.Weights[name] = 1
+		if w, ok := sq.Weights[name]; ok {
+			meta.Weights[name] = w
+		}
+	}
+	return meta
+}
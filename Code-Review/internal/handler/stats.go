@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"app/internal"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// StatsGroupJSON is a struct that represents the aggregates of a group of vehicles in JSON format
+type StatsGroupJSON struct {
+	// Group is the value of each group_by attribute
+	Group map[string]string `json:"group"`
+	// Values is the value of each aggregate
+	Values map[string]float64 `json:"values"`
+}
+
+// StatsMetaJSON is a struct that represents the query of the aggregates in JSON format
+type StatsMetaJSON struct {
+	// GroupBy is the list of attributes that make the key of a group
+	GroupBy []string `json:"group_by"`
+	// Metric is the aggregated attribute
+	Metric string `json:"metric"`
+	// Aggregates is the list of aggregates
+	Aggregates []string `json:"agg"`
+	// Units is the units of the quantities of the aggregates
+	Units UnitsMetaJSON `json:"units"`
+}
+
+// defaultStatsAggregates is the list of aggregates computed if the agg parameter is missing
+var defaultStatsAggregates = []string{"count", "avg", "min", "max"}
+
+// parseStatsQuery is a function that decodes a StatsQuery from the query of a request
+// - group_by: comma separated categorical attributes (optional, e.g. group_by=brand,fuel_type)
+// - metric: numeric attribute to aggregate (e.g. metric=max_speed)
+// - agg: comma separated aggregates: count, sum, avg, min, max, pN (default: count,avg,min,max)
+func parseStatsQuery(q url.Values) (sq internal.StatsQuery, err error) {
+	if s := q.Get("group_by"); s != "" {
+		sq.GroupBy = strings.Split(s, ",")
+	}
+	sq.Metric = q.Get("metric")
+	sq.Aggregates = defaultStatsAggregates
+	if s := q.Get("agg"); s != "" {
+		sq.Aggregates = strings.Split(s, ",")
+	}
+
+	// report the first unknown parameter value, as the client sent it
+	for _, name := range sq.GroupBy {
+		if _, ok := internal.VehicleStatsGroups[name]; !ok {
+			err = fmt.Errorf("invalid group_by %s", name)
+			return
+		}
+	}
+	if _, ok := internal.VehicleStatsMetrics[sq.Metric]; !ok {
+		err = fmt.Errorf("invalid metric %s", sq.Metric)
+		return
+	}
+	for _, name := range sq.Aggregates {
+		if (internal.StatsQuery{Metric: sq.Metric, Aggregates: []string{name}}).Validate() != nil {
+			err = fmt.Errorf("invalid agg %s", name)
+			return
+		}
+	}
+	return
+}
+
+// statsToJSON is a function that maps the groups of a stats query to JSON
+// - the aggregates of a metric with a unit are converted to the unit system, but the count
+func statsToJSON(sq internal.StatsQuery, groups []internal.StatsGroup, u internal.UnitSystem) []StatsGroupJSON {
+	quantity, convert := internal.VehicleQuantities[sq.Metric]
+	data := make([]StatsGroupJSON, 0, len(groups))
+	for _, g := range groups {
+		gj := StatsGroupJSON{
+			Group:  make(map[string]string, len(sq.GroupBy)),
+			Values: make(map[string]float64, len(sq.Aggregates)),
+		}
+		for i, name := range sq.GroupBy {
+			gj.Group[name] = g.Key[i]
+		}
+		for i, name := range sq.Aggregates {
+			gj.Values[name] = g.Values[i]
+			if convert && name != "count" {
+				gj.Values[name] = u.FromCanonical(quantity, g.Values[i])
+			}
+		}
+		data = append(data, gj)
+	}
+	return data
+}
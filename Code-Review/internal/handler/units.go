@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"app/internal"
+	"fmt"
+	"net/http"
+)
+
+// HeaderAcceptUnits is the header a client sets to choose the unit system of the responses (see parseUnits)
+const HeaderAcceptUnits = "Accept-Units"
+
+// UnitsMetaJSON is a struct that represents the units of the quantities of a response in JSON format
+type UnitsMetaJSON struct {
+	// System is the unit system: metric or imperial
+	System string `json:"system"`
+	// Symbols is the symbol of the unit of each attribute with a unit (e.g. weight: lb)
+	Symbols map[string]string `json:"symbols"`
+}
+
+// parseUnits is a function that decodes the unit system of the quantities of a request and its response
+// - units: metric or imperial (takes precedence over the Accept-Units header, default: internal.UnitsCanonical)
+// - the range filters of weight, max_speed and the dimensions are in these units, as the values of the response
+// - the bodies of the writes are always in canonical units, the vehicles of their responses in these units
+func parseUnits(r *http.Request) (u internal.UnitSystem, err error) {
+	name := r.URL.Query().Get("units")
+	if name == "" {
+		name = r.Header.Get(HeaderAcceptUnits)
+	}
+	u, err = internal.ParseUnitSystem(name)
+	if err != nil {
+		err = fmt.Errorf("invalid units, expected %s or %s", internal.UnitsMetric, internal.UnitsImperial)
+		return
+	}
+	return
+}
+
+// unitsMetaToJSON is a function that maps a unit system to JSON
+func unitsMetaToJSON(u internal.UnitSystem) UnitsMetaJSON {
+	return UnitsMetaJSON{System: string(u), Symbols: u.Symbols()}
+}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"app/internal"
+	"app/platform/web/request"
 	"app/platform/web/response"
-	"errors"
+	"context"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
@@ -21,77 +25,91 @@ func NewHandlerVehicle(sv internal.ServiceVehicle) *HandlerVehicle {
 	return &HandlerVehicle{sv: sv}
 }
 
-// FindByColorAndYear returns a handler that returns a map of vehicles that match the color and fabrication year
+// FindByColorAndYear returns a handler that returns a page of vehicles that match the color and fabrication year
 func (h *HandlerVehicle) FindByColorAndYear() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
 		color := chi.URLParam(r, "color")
 		year, err := strconv.Atoi(chi.URLParam(r, "year"))
 		if err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid year")
+			writeBadRequest(w, r, "invalid year")
 			return
 		}
-		/*
-			// process
-			v, err := h.sv.FindByColorAndYear(color, year)
-			if err != nil {
-				response.Error(w, http.StatusInternalServerError, "internal error")
-				return
-			}
-		*/
-		// refactor process for best control error
-		v, err := h.sv.FindByColorAndYear(color, year)
-		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrServiceNoVehicles):
-				response.Error(w, http.StatusNotFound, "vehicles not found")
-			default:
-				response.Error(w, http.StatusInternalServerError, "internal error")
-			}
+		pq, err := parsePageQuery(r.URL.Query())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
 			return
 		}
-
-		// response
-		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "vehicles found",
-			"data":    v,
-		})
+		vw, err := parseVehicleListView(r)
+		if err != nil {
+			writeViewError(w, r, err)
+			return
+		}
+		ctx, err := textMatchContext(r)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		// process: a streamable list is read from the repository as it is written (see streamable), else the repository pages it
+		filter := internal.VehicleFilter{Color: &color, FabricationYear: internal.Range[int]{Min: &year, Max: &year}}
+		if streamable(pq, vw) {
+			h.stream(w, r, ctx, filter, pq, vw)
+			return
+		}
+		h.page(w, r, ctx, filter, pq, vw)
 	}
 }
 
-// FindByBrandAndYearRange returns a handler that returns a map of vehicles that match the brand and a range of fabrication years
+// FindByBrandAndYearRange returns a handler that returns a page of vehicles that match the brand and a range of fabrication years
 func (h *HandlerVehicle) FindByBrandAndYearRange() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
 		brand := chi.URLParam(r, "brand")
 		startYear, err := strconv.Atoi(chi.URLParam(r, "start_year"))
 		if err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid start_year")
+			writeBadRequest(w, r, "invalid start_year")
 			return
 		}
 		endYear, err := strconv.Atoi(chi.URLParam(r, "end_year"))
 		if err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid end_year")
+			writeBadRequest(w, r, "invalid end_year")
 			return
 		}
-
-		// process
-		v, err := h.sv.FindByBrandAndYearRange(brand, startYear, endYear)
+		pq, err := parsePageQuery(r.URL.Query())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		vw, err := parseVehicleListView(r)
+		if err != nil {
+			writeViewError(w, r, err)
+			return
+		}
+		ctx, err := textMatchContext(r)
 		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrServiceNoVehicles):
-				response.Error(w, http.StatusNotFound, "vehicles not found")
-			default:
-				response.Error(w, http.StatusInternalServerError, "internal error")
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+
+		// process: a streamable list is read from the repository as it is written (see streamable), else the repository pages it
+		// - an inverted range is not a valid filter, it finds no vehicles as the service says
+		if startYear <= endYear {
+			filter := internal.VehicleFilter{Brand: &brand, FabricationYear: internal.Range[int]{Min: &startYear, Max: &endYear}}
+			if streamable(pq, vw) {
+				h.stream(w, r, ctx, filter, pq, vw)
+				return
 			}
+			h.page(w, r, ctx, filter, pq, vw)
+			return
+		}
+		v, err := h.sv.FindByBrandAndYearRange(ctx, brand, startYear, endYear)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
 		// response
-		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "vehicles found",
-			"data":    v,
-		})
+		writeVehicles(w, r, "vehicles found", v, pq, vw)
 	}
 }
 
@@ -100,23 +118,29 @@ func (h *HandlerVehicle) AverageMaxSpeedByBrand() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
 		brand := chi.URLParam(r, "brand")
+		ctx, err := textMatchContext(r)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		u, err := parseUnits(r)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
 
 		// process
-		average, err := h.sv.AverageMaxSpeedByBrand(brand)
+		average, err := h.sv.AverageMaxSpeedByBrand(ctx, brand)
 		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrServiceNoVehicles):
-				response.Error(w, http.StatusNotFound, "vehicles not found")
-			default:
-				response.Error(w, http.StatusInternalServerError, "internal error")
-			}
+			writeError(w, r, err)
 			return
 		}
 
 		// response
 		response.JSON(w, http.StatusOK, map[string]any{
 			"message": "average max speed found",
-			"data":    average,
+			"data":    u.FromCanonical(internal.QuantitySpeed, average),
+			"meta":    map[string]any{"units": unitsMetaToJSON(u)},
 		})
 	}
 }
@@ -126,16 +150,16 @@ func (h *HandlerVehicle) AverageCapacityByBrand() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
 		brand := chi.URLParam(r, "brand")
+		ctx, err := textMatchContext(r)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
 
 		// process
-		average, err := h.sv.AverageCapacityByBrand(brand)
+		average, err := h.sv.AverageCapacityByBrand(ctx, brand)
 		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrServiceNoVehicles):
-				response.Error(w, http.StatusNotFound, "vehicles not found")
-			default:
-				response.Error(w, http.StatusInternalServerError, "internal error")
-			}
+			writeError(w, r, err)
 			return
 		}
 
@@ -147,45 +171,466 @@ func (h *HandlerVehicle) AverageCapacityByBrand() http.HandlerFunc {
 	}
 }
 
-// SearchByWeightRange returns a handler that returns a map of vehicles that match the weight range
+// SearchByWeightRange returns a handler that returns a page of vehicles that match the weight range
 func (h *HandlerVehicle) SearchByWeightRange() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
 		var query internal.SearchQuery
 
 		// check if query exists and decode
-		ok := r.URL.Query().Has("weight_min") && r.URL.Query().Has("weight_max")
+		// - any bound may be omitted (open-ended range)
+		ok := r.URL.Query().Has("weight_min") || r.URL.Query().Has("weight_max")
 		if ok {
+			query.FromWeight, query.ToWeight = math.Inf(-1), math.Inf(1)
+
 			var err error
-			query.FromWeight, err = strconv.ParseFloat(r.URL.Query().Get("weight_min"), 64)
-			if err != nil {
-				response.Error(w, http.StatusBadRequest, "invalid weight_min")
-				return
+			if r.URL.Query().Has("weight_min") {
+				query.FromWeight, err = strconv.ParseFloat(r.URL.Query().Get("weight_min"), 64)
+				if err != nil {
+					writeBadRequest(w, r, "invalid weight_min")
+					return
+				}
 			}
-
-			query.ToWeight, err = strconv.ParseFloat(r.URL.Query().Get("weight_max"), 64)
-			if err != nil {
-				response.Error(w, http.StatusBadRequest, "invalid weight_max")
+			if r.URL.Query().Has("weight_max") {
+				query.ToWeight, err = strconv.ParseFloat(r.URL.Query().Get("weight_max"), 64)
+				if err != nil {
+					writeBadRequest(w, r, "invalid weight_max")
+					return
+				}
+			}
+			if query.FromWeight > query.ToWeight {
+				writeBadRequest(w, r, "invalid weight_min: greater than weight_max")
 				return
 			}
 		}
+		pq, err := parsePageQuery(r.URL.Query())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		vw, err := parseVehicleListView(r)
+		if err != nil {
+			writeViewError(w, r, err)
+			return
+		}
+
+		// process: the weights are in the units of the view
+		query.FromWeight = vw.units.ToCanonical(internal.QuantityMass, query.FromWeight)
+		query.ToWeight = vw.units.ToCanonical(internal.QuantityMass, query.ToWeight)
+		// - a streamable list is read from the repository as it is written (see streamable), else the repository pages it
+		var filter internal.VehicleFilter
+		if ok && !math.IsInf(query.FromWeight, -1) {
+			filter.Weight.Min = &query.FromWeight
+		}
+		if ok && !math.IsInf(query.ToWeight, 1) {
+			filter.Weight.Max = &query.ToWeight
+		}
+		if streamable(pq, vw) {
+			h.stream(w, r, r.Context(), filter, pq, vw)
+			return
+		}
+		h.page(w, r, r.Context(), filter, pq, vw)
+	}
+}
+
+// Search returns a handler that returns a page of vehicles that match any combination of filters (query)
+// - text: brand, model, color, fuel_type, transmission (matched as ?match= says, see textMatchContext)
+// - range: {field}_gte and {field}_lte for year, capacity, max_speed, weight, height, length, width (in the units, see parseUnits)
+func (h *HandlerVehicle) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		filter, err := parseVehicleFilter(r.URL.Query())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		pq, err := parsePageQuery(r.URL.Query())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		vw, err := parseVehicleListView(r)
+		if err != nil {
+			writeViewError(w, r, err)
+			return
+		}
+		ctx, err := textMatchContext(r)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+
+		// process: the ranges are in the units of the view, a streamable list is read from the repository as it is written,
+		// else the repository pages it
+		if streamable(pq, vw) {
+			h.stream(w, r, ctx, vw.units.Filter(filter), pq, vw)
+			return
+		}
+		h.page(w, r, ctx, vw.units.Filter(filter), pq, vw)
+	}
+}
+
+// Stats returns a handler that returns the aggregates of a metric per group of the vehicles that match the search filters
+func (h *HandlerVehicle) Stats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		filter, err := parseVehicleFilter(r.URL.Query())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		sq, err := parseStatsQuery(r.URL.Query())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		ctx, err := textMatchContext(r)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		u, err := parseUnits(r)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+
+		// process: the ranges are in the units
+		groups, err := h.sv.Stats(ctx, u.Filter(filter), sq)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "stats found",
+			"data":    statsToJSON(sq, groups, u),
+			"meta": StatsMetaJSON{
+				GroupBy:    append([]string{}, sq.GroupBy...),
+				Metric:     sq.Metric,
+				Aggregates: sq.Aggregates,
+				Units:      unitsMetaToJSON(u),
+			},
+		})
+	}
+}
+
+// Compare returns a handler that returns the vehicles of the ids aligned attribute by attribute (query)
+// - ids: comma separated ids, between internal.CompareMinIds and internal.CompareMaxIds
+func (h *HandlerVehicle) Compare() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		ids, err := parseCompareIds(r.URL.Query())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		u, err := parseUnits(r)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
 
 		// process
-		v, err := h.sv.SearchByWeightRange(query, ok)
+		c, err := h.sv.Compare(r.Context(), ids)
 		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrServiceNoVehicles):
-				response.Error(w, http.StatusNotFound, "vehicles not found")
-			default:
-				response.Error(w, http.StatusInternalServerError, "internal error")
-			}
+			writeError(w, r, err)
 			return
 		}
 
 		// response
 		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "vehicles found",
-			"data":    v,
+			"message": "vehicles compared",
+			"data":    comparisonToJSON(c, u),
+			"meta":    map[string]any{"units": unitsMetaToJSON(u)},
 		})
 	}
 }
+
+// Similar returns a handler that returns the vehicles most like a vehicle, nearest first (query)
+// - k, weights and same: see parseSimilarQuery
+func (h *HandlerVehicle) Similar() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeBadRequest(w, r, "invalid id")
+			return
+		}
+		sq, err := parseSimilarQuery(r.URL.Query())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		u, err := parseUnits(r)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+
+		// process
+		matches, err := h.sv.Similar(r.Context(), id, sq)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "similar vehicles found",
+			"data":    similarToJSON(matches, u),
+			"meta":    similarMetaToJSON(id, sq, u),
+		})
+	}
+}
+
+// Create returns a handler that creates a new vehicle
+func (h *HandlerVehicle) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		vw, err := parseVehicleView(r)
+		if err != nil {
+			writeViewError(w, r, err)
+			return
+		}
+		var body VehicleJSON
+		if err := request.JSON(r, &body); err != nil {
+			writeBadRequest(w, r, "invalid request body")
+			return
+		}
+
+		// process
+		v := internal.Vehicle{
+			Id:                body.Id,
+			VehicleAttributes: vehicleAttributesFromJSON(body),
+		}
+		err = h.sv.Save(r.Context(), &v)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		// response
+		response.JSONMediaType(w, http.StatusCreated, MediaTypeVehicleV1, map[string]any{
+			"message": "vehicle created",
+			"data":    vw.render(v),
+			"meta":    map[string]any{"units": unitsMetaToJSON(vw.units)},
+		})
+	}
+}
+
+// Update returns a handler that replaces the attributes of an existing vehicle
+func (h *HandlerVehicle) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeBadRequest(w, r, "invalid id")
+			return
+		}
+		vw, err := parseVehicleView(r)
+		if err != nil {
+			writeViewError(w, r, err)
+			return
+		}
+		var body VehicleJSON
+		if err := request.JSON(r, &body); err != nil {
+			writeBadRequest(w, r, "invalid request body")
+			return
+		}
+
+		// process
+		v := internal.Vehicle{
+			Id:                id,
+			VehicleAttributes: vehicleAttributesFromJSON(body),
+		}
+		err = h.sv.Update(r.Context(), &v)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		// response
+		response.JSONMediaType(w, http.StatusOK, MediaTypeVehicleV1, map[string]any{
+			"message": "vehicle updated",
+			"data":    vw.render(v),
+			"meta":    map[string]any{"units": unitsMetaToJSON(vw.units)},
+		})
+	}
+}
+
+// Patch returns a handler that updates only the given attributes of an existing vehicle
+func (h *HandlerVehicle) Patch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeBadRequest(w, r, "invalid id")
+			return
+		}
+		vw, err := parseVehicleView(r)
+		if err != nil {
+			writeViewError(w, r, err)
+			return
+		}
+		var body VehiclePatchJSON
+		if err := request.JSON(r, &body); err != nil {
+			writeBadRequest(w, r, "invalid request body")
+			return
+		}
+
+		// process
+		v, err := h.sv.Patch(r.Context(), id, internal.VehicleAttributesPatch{
+			Brand:           body.Brand,
+			Model:           body.Model,
+			Registration:    body.Registration,
+			Color:           body.Color,
+			FabricationYear: body.FabricationYear,
+			Capacity:        body.Capacity,
+			MaxSpeed:        body.MaxSpeed,
+			FuelType:        body.FuelType,
+			Transmission:    body.Transmission,
+			Weight:          body.Weight,
+			Height:          body.Height,
+			Length:          body.Length,
+			Width:           body.Width,
+		})
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		// response
+		response.JSONMediaType(w, http.StatusOK, MediaTypeVehicleV1, map[string]any{
+			"message": "vehicle updated",
+			"data":    vw.render(v),
+			"meta":    map[string]any{"units": unitsMetaToJSON(vw.units)},
+		})
+	}
+}
+
+// Delete returns a handler that deletes a vehicle
+func (h *HandlerVehicle) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeBadRequest(w, r, "invalid id")
+			return
+		}
+
+		// process
+		err = h.sv.Delete(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// parseVehicleFilter is a function that decodes a VehicleFilter from the query of a request
+func parseVehicleFilter(q url.Values) (f internal.VehicleFilter, err error) {
+	// text matches
+	for _, field := range []struct {
+		name string
+		ptr  **string
+	}{
+		{"brand", &f.Brand},
+		{"model", &f.Model},
+		{"color", &f.Color},
+		{"fuel_type", &f.FuelType},
+		{"transmission", &f.Transmission},
+	} {
+		if q.Has(field.name) {
+			value := q.Get(field.name)
+			*field.ptr = &value
+		}
+	}
+
+	// ranges
+	for _, field := range []struct {
+		name string
+		rg   *internal.Range[int]
+	}{
+		{"year", &f.FabricationYear},
+		{"capacity", &f.Capacity},
+	} {
+		if field.rg.Min, err = parseBound(q, field.name+"_gte", strconv.Atoi); err != nil {
+			return
+		}
+		if field.rg.Max, err = parseBound(q, field.name+"_lte", strconv.Atoi); err != nil {
+			return
+		}
+	}
+	parseFloat := func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }
+	for _, field := range []struct {
+		name string
+		rg   *internal.Range[float64]
+	}{
+		{"max_speed", &f.MaxSpeed},
+		{"weight", &f.Weight},
+		{"height", &f.Height},
+		{"length", &f.Length},
+		{"width", &f.Width},
+	} {
+		if field.rg.Min, err = parseBound(q, field.name+"_gte", parseFloat); err != nil {
+			return
+		}
+		if field.rg.Max, err = parseBound(q, field.name+"_lte", parseFloat); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// stream is a method that writes a streamable list of the vehicles that match the filter, read from the service as it is written
+func (h *HandlerVehicle) stream(w http.ResponseWriter, r *http.Request, ctx context.Context, filter internal.VehicleFilter, pq internal.PageQuery, vw vehicleView) {
+	it, err := h.sv.Stream(ctx, filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	streamVehicles(w, r, it, pq, vw)
+}
+
+// page is a method that writes the page of the vehicles that match the filter, sorted and paged by the repository
+func (h *HandlerVehicle) page(w http.ResponseWriter, r *http.Request, ctx context.Context, filter internal.VehicleFilter, pq internal.PageQuery, vw vehicleView) {
+	v, p, err := h.sv.Page(ctx, filter, pq)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writePage(w, r, "vehicles found", v, p, pq, vw)
+}
+
+// textMatchContext is a function that returns the context of a request with the text match mode asked with ?match=
+// - normalized (default): text attributes match ignoring case, surrounding spaces and accents
+// - fuzzy: text attributes also match with a few typos
+func textMatchContext(r *http.Request) (ctx context.Context, err error) {
+	m, err := internal.ParseTextMatch(r.URL.Query().Get("match"))
+	if err != nil {
+		err = fmt.Errorf("invalid match, expected %s or %s", internal.TextMatchNormalized, internal.TextMatchFuzzy)
+		return
+	}
+	ctx = internal.NewTextMatchContext(r.Context(), m)
+	return
+}
+
+// parseBound is a function that decodes an optional bound of a range from the query of a request
+func parseBound[T int | float64](q url.Values, name string, parse func(string) (T, error)) (bound *T, err error) {
+	if !q.Has(name) {
+		return
+	}
+	value, err := parse(q.Get(name))
+	if err != nil {
+		err = fmt.Errorf("invalid %s", name)
+		return
+	}
+	bound = &value
+	return
+}
@@ -0,0 +1,227 @@
+package handler_test
+
+import (
+	"app/internal"
+	"app/internal/handler"
+	"app/internal/repository"
+	"app/internal/service"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// backends is the list of repositories the handlers are tested on, each with the given vehicles
+var backends = map[string]func(t *testing.T, v []internal.Vehicle) internal.RepositoryVehicle{
+	"memory": func(t *testing.T, v []internal.Vehicle) internal.RepositoryVehicle {
+		db := make(map[int]internal.Vehicle)
+		for _, v := range v {
+			db[v.Id] = v
+		}
+		return repository.NewRepositoryVehicleIndexed(db)
+	},
+	"sqlite": func(t *testing.T, v []internal.Vehicle) internal.RepositoryVehicle {
+		db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "vehicles.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		require.NoError(t, repository.MigrateVehicleSQL(db))
+		rp := repository.NewRepositoryVehicleSQL(db)
+		require.NoError(t, rp.Import(context.Background(), v))
+		return rp
+	},
+}
+
+// newBackendRouter returns the vehicle routes served by the default service on the given repository
+func newBackendRouter(rp internal.RepositoryVehicle) *chi.Mux {
+	hd := handler.NewHandlerVehicle(service.NewServiceVehicleDefault(rp))
+	rt := chi.NewRouter()
+	rt.Route("/vehicles", func(r chi.Router) {
+		r.Get("/color/{color}/year/{year}", hd.FindByColorAndYear())
+		r.Get("/brand/{brand}/between/{start_year}/{end_year}", hd.FindByBrandAndYearRange())
+		r.Get("/average_speed/brand/{brand}", hd.AverageMaxSpeedByBrand())
+		r.Get("/average_capacity/brand/{brand}", hd.AverageCapacityByBrand())
+		r.Get("/stats", hd.Stats())
+		r.Get("/weight", hd.SearchByWeightRange())
+		r.Get("/", hd.Search())
+		r.Get("/compare", hd.Compare())
+		r.Get("/{id}/similar", hd.Similar())
+		r.Post("/", hd.Create())
+		r.Put("/{id}", hd.Update())
+		r.Patch("/{id}", hd.Patch())
+		r.Delete("/{id}", hd.Delete())
+	})
+	return rt
+}
+
+func TestHandlerVehicle_Backends(t *testing.T) {
+	// steps run in order on the same backend
+	steps := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		expectCode int
+		expectBody string
+	}{
+		{"find by color and year", http.MethodGet, "/vehicles/color/red/year/2010", "", http.StatusOK, ExpectBody},
+		{"find by brand and year range", http.MethodGet, "/vehicles/brand/Ford/between/2000/2020", "", http.StatusOK, ExpectBody},
+		{"search by weight range", http.MethodGet, "/vehicles/weight?weight_min=500", "", http.StatusOK, ExpectBody},
+		{"search", http.MethodGet, "/vehicles/?brand=Ford&year_gte=2005&max_speed_lte=200", "", http.StatusOK, ExpectBody},
+		{"average max speed", http.MethodGet, "/vehicles/average_speed/brand/Ford", "", http.StatusOK,
+			`{"message": "average max speed found", "data": 180, "meta": {"units": ` + MetricUnits + `}}`},
+		{"compare", http.MethodGet, "/vehicles/compare?ids=1,99", "", http.StatusNotFound,
+			`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "vehicles not found: 99", "instance": "/vehicles/compare",
+			"errors": [{"field": "ids", "message": "vehicle 99 not found"}]}`},
+		{"stats", http.MethodGet, "/vehicles/stats?group_by=brand&metric=capacity&agg=count,avg", "", http.StatusOK,
+			`{"message": "stats found", "data": [{"group": {"brand": "Ford"}, "values": {"count": 1, "avg": 5}}],
+			"meta": {"group_by": ["brand"], "metric": "capacity", "agg": ["count", "avg"], "units": ` + MetricUnits + `}}`},
+		{"imperial bounds of a value read in imperial", http.MethodGet,
+			"/vehicles/?units=imperial&weight_gte=2204.622622&weight_lte=2204.622622&fields=id,weight", "", http.StatusOK,
+			`{"message": "vehicles found", "data": [{"id": 1, "weight": 2204.622622}],
+			"meta": {"total": 1, "offset": 0, "next_cursor": null, "units": {"system": "imperial",
+			"symbols": {"max_speed": "mph", "weight": "lb", "height": "in", "length": "in", "width": "in"}}}}`},
+		{"not found", http.MethodGet, "/vehicles/color/blue/year/2010", "", http.StatusNotFound,
+			`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "vehicles not found", "instance": "/vehicles/color/blue/year/2010"}`},
+		{"create", http.MethodPost, "/vehicles/", `{"id": 2, "brand": "GMC", "model": "Sierra", "registration": "XYZ-789",
+			"year": 2015, "fuel_type": "diesel", "transmission": "manual", "weight": 2500, "height": 2, "width": 2}`, http.StatusCreated, ""},
+		{"create invalid", http.MethodPost, "/vehicles/", `{"id": 3, "brand": "GMC", "model": "Sierra", "registration": "XYZ-790",
+			"year": 2015, "fuel_type": "steam", "transmission": "manual", "weight": -1, "width": 2}`, http.StatusUnprocessableEntity,
+			`{"type": "about:blank", "title": "Unprocessable Entity", "status": 422, "detail": "invalid vehicle", "instance": "/vehicles/",
+			"errors": [{"field": "weight", "message": "-1 is not positive"}, {"field": "height", "message": "0 is not positive"}, {"field": "fuel_type", "message": "\"steam\" is unknown"}]}`},
+		{"create conflict", http.MethodPost, "/vehicles/", `{"id": 3, "brand": "GMC", "model": "Sierra", "registration": "XYZ-789",
+			"year": 2015, "fuel_type": "diesel", "transmission": "manual", "weight": 2500, "height": 2, "width": 2}`, http.StatusConflict, `{"type": "about:blank", "title": "Conflict", "status": 409, "detail": "registration already exists", "instance": "/vehicles/"}`},
+		{"patch", http.MethodPatch, "/vehicles/2?units=imperial&fields=id,brand,weight", `{"brand": "Chevrolet"}`, http.StatusOK,
+			`{"message": "vehicle updated", "data": {"id": 2, "brand": "Chevrolet", "weight": 5511.556555}, "meta": {"units": {"system": "imperial",
+			"symbols": {"max_speed": "mph", "weight": "lb", "height": "in", "length": "in", "width": "in"}}}}`},
+		{"patch invalid", http.MethodPatch, "/vehicles/2", `{"year": 1800}`, http.StatusUnprocessableEntity,
+			`{"type": "about:blank", "title": "Unprocessable Entity", "status": 422, "detail": "invalid vehicle", "instance": "/vehicles/2",
+			"errors": [{"field": "year", "message": "1800 is out of range [1886, ` + strconv.Itoa(time.Now().Year()) + `]"}]}`},
+		{"average max speed by normalized brand", http.MethodGet, "/vehicles/average_speed/brand/%20chevrolet", "", http.StatusOK,
+			`{"message": "average max speed found", "data": 0, "meta": {"units": ` + MetricUnits + `}}`},
+		{"did you mean", http.MethodGet, "/vehicles/brand/chevrolte/between/2000/2030", "", http.StatusNotFound,
+			`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "vehicles not found", "instance": "/vehicles/brand/chevrolte/between/2000/2030",
+			"suggestions": [{"field": "brand", "value": "chevrolte", "did_you_mean": ["Chevrolet"]}]}`},
+		{"fuzzy", http.MethodGet, "/vehicles/brand/chevrolte/between/0/2030?match=fuzzy&fields=id", "", http.StatusOK,
+			`{"message": "vehicles found", "data": [{"id": 2}], "meta": {"total": 1, "offset": 0, "next_cursor": null, "units": ` + MetricUnits + `}}`},
+		{"search after writes", http.MethodGet, "/vehicles/?brand=Chevrolet&fields=id,brand", "", http.StatusOK,
+			`{"message": "vehicles found", "data": [{"id": 2, "brand": "Chevrolet"}],
+			"meta": {"total": 1, "offset": 0, "next_cursor": null, "units": ` + MetricUnits + `}}`},
+		{"delete", http.MethodDelete, "/vehicles/1", "", http.StatusNoContent, ""},
+		{"delete not found", http.MethodDelete, "/vehicles/1", "", http.StatusNotFound,
+			`{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "vehicle not found", "instance": "/vehicles/1"}`},
+	}
+
+	for name, newRepository := range backends {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			rt := newBackendRouter(newRepository(t, Vehicles))
+			for _, step := range steps {
+				r := httptest.NewRequest(step.method, step.target, strings.NewReader(step.body))
+				if step.body != "" {
+					r.Header.Set("Content-Type", "application/json")
+				}
+				w := httptest.NewRecorder()
+				// act
+				rt.ServeHTTP(w, r)
+				// assert
+				require.Equal(t, step.expectCode, w.Code, step.name)
+				if step.expectBody != "" {
+					require.JSONEq(t, step.expectBody, w.Body.String(), step.name)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerVehicle_Backends_Page(t *testing.T) {
+	// fleet is a list of vehicles to page through, with ties in the sort fields
+	fleet := []internal.Vehicle{
+		{Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Model: "Fiesta", Registration: "A-1", Color: "red", FabricationYear: 2010,
+			Capacity: 5, MaxSpeed: 180, FuelType: "gasoline", Transmission: "manual", Weight: 1000, Dimensions: internal.Dimensions{Height: 1.5, Length: 4, Width: 1.8}}},
+		{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Chevrolet", Model: "Spark", Registration: "A-2", Color: "blue", FabricationYear: 2015,
+			Capacity: 4, MaxSpeed: 150.5, FuelType: "gasoline", Transmission: "automatic", Weight: 900.25, Dimensions: internal.Dimensions{Height: 1.5, Length: 3.6, Width: 1.6}}},
+		{Id: 3, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Model: "Focus", Registration: "A-3", Color: "red", FabricationYear: 2015,
+			Capacity: 5, MaxSpeed: 200, FuelType: "diesel", Transmission: "manual", Weight: 1200, Dimensions: internal.Dimensions{Height: 1.4, Length: 4.4, Width: 1.8}}},
+		{Id: 4, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", Model: "Sierra", Registration: "A-4", Color: "black", FabricationYear: 2001,
+			Capacity: 2, MaxSpeed: 150.5, FuelType: "diesel", Transmission: "automatic", Weight: 2500, Dimensions: internal.Dimensions{Height: 1.9, Length: 5.8, Width: 2}}},
+		{Id: 5, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Model: "Ka", Registration: "A-5", Color: "blue", FabricationYear: 2010,
+			Capacity: 4, MaxSpeed: 160, FuelType: "gasoline", Transmission: "manual", Weight: 900.25, Dimensions: internal.Dimensions{Height: 1.4, Length: 3.6, Width: 1.6}}},
+	}
+
+	for name, newRepository := range backends {
+		t.Run(name, func(t *testing.T) {
+			rt := newBackendRouter(newRepository(t, fleet))
+
+			t.Run("limit, offset and sort", func(t *testing.T) {
+				// act
+				w := httptest.NewRecorder()
+				rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vehicles/?sort=-year,brand&limit=2&offset=1", nil))
+				// assert
+				require.Equal(t, http.StatusOK, w.Code)
+				got, meta := decodePage(t, w.Body.String())
+				require.Equal(t, []int{3, 1}, got)
+				require.Equal(t, 5, meta.Total)
+				require.NotNil(t, meta.NextCursor)
+			})
+
+			t.Run("the pages of a cursor are the sorted vehicles, for every sort field", func(t *testing.T) {
+				for field := range internal.VehicleSortFields {
+					for _, sort := range []string{field, "-" + field} {
+						// arrange
+						compare, err := internal.CompareVehicles([]internal.SortField{{Name: field, Desc: sort != field}})
+						require.NoError(t, err)
+						sorted := slices.Clone(fleet)
+						slices.SortFunc(sorted, compare)
+						var expected []int
+						for _, v := range sorted {
+							expected = append(expected, v.Id)
+						}
+						// act
+						var got []int
+						target := "/vehicles/?sort=" + sort + "&limit=2"
+						for pages := 0; target != "" && pages < len(fleet); pages++ {
+							w := httptest.NewRecorder()
+							rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+							require.Equal(t, http.StatusOK, w.Code, sort)
+							ids, meta := decodePage(t, w.Body.String())
+							require.Equal(t, len(fleet), meta.Total, sort)
+							got, target = append(got, ids...), ""
+							if meta.NextCursor != nil {
+								target = "/vehicles/?sort=" + sort + "&limit=2&cursor=" + *meta.NextCursor
+							}
+						}
+						// assert
+						require.Equal(t, expected, got, sort)
+					}
+				}
+			})
+
+			t.Run("a cursor of a filtered list", func(t *testing.T) {
+				// arrange
+				w := httptest.NewRecorder()
+				rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vehicles/?brand=Ford&sort=-max_speed&limit=2", nil))
+				first, meta := decodePage(t, w.Body.String())
+				require.Equal(t, []int{3, 1}, first)
+				// act
+				w = httptest.NewRecorder()
+				rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vehicles/?brand=Ford&sort=-max_speed&limit=2&cursor="+*meta.NextCursor, nil))
+				// assert
+				require.Equal(t, http.StatusOK, w.Code)
+				next, meta := decodePage(t, w.Body.String())
+				require.Equal(t, []int{5}, next)
+				require.Equal(t, 3, meta.Total)
+				require.Nil(t, meta.NextCursor)
+			})
+		})
+	}
+}
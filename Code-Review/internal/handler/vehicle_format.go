@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"app/platform/web/logging"
+	"app/platform/web/request"
+	"app/platform/web/response"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// vehicleFormat is the encoding of the vehicles in a response
+type vehicleFormat string
+
+const (
+	vehicleFormatJSON   vehicleFormat = "json"
+	vehicleFormatCSV    vehicleFormat = "csv"
+	vehicleFormatXML    vehicleFormat = "xml"
+	vehicleFormatNDJSON vehicleFormat = "ndjson"
+)
+
+// vehicleMediaType is a struct that represents a media type of the vehicles and its format
+type vehicleMediaType struct {
+	// mediaType is the media type negotiated with the Accept header
+	mediaType string
+	// format is the encoding written for it
+	format vehicleFormat
+}
+
+// vehicleMediaTypes is the list of media types of the vehicles, in server preference order
+// - the first two are the json ones, the only ones for a single vehicle
+var vehicleMediaTypes = []vehicleMediaType{
+	{MediaTypeVehicleV1, vehicleFormatJSON},
+	{"application/json", vehicleFormatJSON},
+	{response.MediaTypeCSV, vehicleFormatCSV},
+	{response.MediaTypeXML, vehicleFormatXML},
+	{"text/xml", vehicleFormatXML},
+	{response.MediaTypeNDJSON, vehicleFormatNDJSON},
+	{"application/ndjson", vehicleFormatNDJSON},
+}
+
+// parseVehicleListView is a function that decodes a vehicleView of a list of vehicles from a request
+// - format: json, csv, xml or ndjson (takes precedence over the Accept header)
+// - accept: any of vehicleMediaTypes (a generic json media type is json)
+// - fields: comma separated names of VehicleJSON fields, also the columns of csv (e.g. fields=id,brand,year)
+// - units: the unit system of the quantities, see parseUnits
+func parseVehicleListView(r *http.Request) (vw vehicleView, err error) {
+	vw.format, err = negotiateVehicleFormat(r.Header.Get("Accept"), r.URL.Query().Get("format"), vehicleMediaTypes)
+	if err != nil {
+		return
+	}
+	vw.fields, err = parseVehicleFields(r.URL.Query())
+	if err != nil {
+		return
+	}
+	vw.units, err = parseUnits(r)
+	return
+}
+
+// negotiateVehicleFormat is a function that returns the format of the response among the given media types
+// - format is the value of the format parameter, if it is empty the Accept header is negotiated
+func negotiateVehicleFormat(accept string, format string, mediaTypes []vehicleMediaType) (f vehicleFormat, err error) {
+	offers := make([]string, 0, len(mediaTypes))
+	for _, mt := range mediaTypes {
+		if format != "" && string(mt.format) == strings.ToLower(format) {
+			f = mt.format
+			return
+		}
+		offers = append(offers, mt.mediaType)
+	}
+
+	if format == "" {
+		if mediaType, ok := request.NegotiateMediaType(accept, offers...); ok {
+			for _, mt := range mediaTypes {
+				if mt.mediaType == mediaType {
+					f = mt.format
+					return
+				}
+			}
+		}
+	}
+	err = &NotAcceptableError{Offers: offers}
+	return
+}
+
+// columns is a method that returns the names of the rendered fields
+// - every field, in the order of the loader csv format, if there is no sparse fieldset
+func (vw vehicleView) columns() []string {
+	if vw.fields != nil {
+		return vw.fields
+	}
+	return loader.VehicleCSVColumns
+}
+
+// record is a method that returns the rendered fields of a vehicle formatted as text, in column order
+func (vw vehicleView) record(v internal.Vehicle) []string {
+	body := vehicleToJSON(vw.units.Vehicle(v))
+	columns := vw.columns()
+	record := make([]string, len(columns))
+	for i, c := range columns {
+		record[i] = formatVehicleField(vehicleJSONFields[c](body))
+	}
+	return record
+}
+
+// formatVehicleField is a function that formats the value of a field as the loader csv format does
+func formatVehicleField(value any) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+// vehicleXML is a struct that represents a vehicle in XML format, an element per rendered field
+type vehicleXML struct {
+	// names is the name of each field
+	names []string
+	// values is the value of each field, formatted as text
+	values []string
+}
+
+// MarshalXML encodes the vehicle as a <vehicle> element
+func (v vehicleXML) MarshalXML(e *xml.Encoder, start xml.StartElement) (err error) {
+	start = xml.StartElement{Name: xml.Name{Local: "vehicle"}}
+	if err = e.EncodeToken(start); err != nil {
+		return
+	}
+	for i, name := range v.names {
+		if err = e.EncodeElement(v.values[i], xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return
+		}
+	}
+	err = e.EncodeToken(start.End())
+	return
+}
+
+// writeVehicleRecords is a function that writes the vehicles of an iteration in a record format (csv, xml or ndjson)
+// - the metadata goes in the X-Total-Count, X-Next-Cursor and X-Units headers
+// - the vehicles are encoded as they are read from the iteration, one at a time; it is closed once written
+// - an iteration that fails once the status code is sent aborts the response, so the client does not take it as complete
+func writeVehicleRecords(w http.ResponseWriter, r *http.Request, it internal.VehicleIterator, meta PageMetaJSON, vw vehicleView) {
+	defer it.Close()
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(meta.Total))
+	if meta.NextCursor != nil {
+		w.Header().Set("X-Next-Cursor", *meta.NextCursor)
+	}
+	w.Header().Set("X-Units", meta.Units.System)
+
+	names := vw.columns()
+	switch vw.format {
+	case vehicleFormatCSV:
+		response.CSV(w, http.StatusOK, names, func() (record []string, ok bool) {
+			if ok = it.Next(); ok {
+				record = vw.record(it.Vehicle())
+			}
+			return
+		})
+	case vehicleFormatXML:
+		response.XML(w, http.StatusOK, "vehicles", func() (element any, ok bool) {
+			if ok = it.Next(); ok {
+				element = vehicleXML{names: names, values: vw.record(it.Vehicle())}
+			}
+			return
+		})
+	case vehicleFormatNDJSON:
+		response.NDJSON(w, http.StatusOK, func() (doc any, ok bool) {
+			if ok = it.Next(); ok {
+				doc = vw.render(it.Vehicle())
+			}
+			return
+		})
+	}
+
+	if err := it.Err(); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "handler: vehicles stream failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		}
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// VehicleJSON is a struct that represents a vehicle in JSON format (request bodies and responses)
+// - same fields as the data file read by the loader, decoupled from internal.Vehicle
+type VehicleJSON struct {
+	Id              int     `json:"id"`
+	Brand           string  `json:"brand"`
+	Model           string  `json:"model"`
+	Registration    string  `json:"registration"`
+	Color           string  `json:"color"`
+	FabricationYear int     `json:"year"`
+	Capacity        int     `json:"passengers"`
+	MaxSpeed        float64 `json:"max_speed"`
+	FuelType        string  `json:"fuel_type"`
+	Transmission    string  `json:"transmission"`
+	Weight          float64 `json:"weight"`
+	Height          float64 `json:"height"`
+	Length          float64 `json:"length"`
+	Width           float64 `json:"width"`
+}
+
+// VehiclePatchJSON is a struct that represents a partial vehicle in JSON format
+// - absent fields are left untouched
+type VehiclePatchJSON struct {
+	Brand           *string  `json:"brand"`
+	Model           *string  `json:"model"`
+	Registration    *string  `json:"registration"`
+	Color           *string  `json:"color"`
+	FabricationYear *int     `json:"year"`
+	Capacity        *int     `json:"passengers"`
+	MaxSpeed        *float64 `json:"max_speed"`
+	FuelType        *string  `json:"fuel_type"`
+	Transmission    *string  `json:"transmission"`
+	Weight          *float64 `json:"weight"`
+	Height          *float64 `json:"height"`
+	Length          *float64 `json:"length"`
+	Width           *float64 `json:"width"`
+}
+
+// vehicleAttributesFromJSON is a function that maps a VehicleJSON to the attributes of a vehicle
+func vehicleAttributesFromJSON(body VehicleJSON) internal.VehicleAttributes {
+	return internal.VehicleAttributes{
+		Brand:           body.Brand,
+		Model:           body.Model,
+		Registration:    body.Registration,
+		Color:           body.Color,
+		FabricationYear: body.FabricationYear,
+		Capacity:        body.Capacity,
+		MaxSpeed:        body.MaxSpeed,
+		FuelType:        body.FuelType,
+		Transmission:    body.Transmission,
+		Weight:          body.Weight,
+		Dimensions: internal.Dimensions{
+			Height: body.Height,
+			Length: body.Length,
+			Width:  body.Width,
+		},
+	}
+}
+
+// vehicleToJSON is a function that maps a vehicle to its public JSON representation
+func vehicleToJSON(v internal.Vehicle) VehicleJSON {
+	return VehicleJSON{
+		Id:              v.Id,
+		Brand:           v.Brand,
+		Model:           v.Model,
+		Registration:    v.Registration,
+		Color:           v.Color,
+		FabricationYear: v.FabricationYear,
+		Capacity:        v.Capacity,
+		MaxSpeed:        v.MaxSpeed,
+		FuelType:        v.FuelType,
+		Transmission:    v.Transmission,
+		Weight:          v.Weight,
+		Height:          v.Height,
+		Length:          v.Length,
+		Width:           v.Width,
+	}
+}
+
+// MediaTypeVehicleV1 is the versioned media type of the public JSON representation of vehicles
+const MediaTypeVehicleV1 = "application/vnd.vehicles.v1+json"
+
+var (
+	// ErrHandlerNotAcceptable is an error that represents a request that does not accept any supported media type
+	// (or asks with ?format= for an unknown one)
+	ErrHandlerNotAcceptable = errors.New("handler: not acceptable")
+)
+
+// NotAcceptableError is an error that represents a request that does not accept any of the offered media types
+type NotAcceptableError struct {
+	// Offers is the list of media types supported, in server preference order
+	Offers []string
+}
+
+// Error returns the message of the error
+func (e *NotAcceptableError) Error() string {
+	return ErrHandlerNotAcceptable.Error() + ", supported media types: " + strings.Join(e.Offers, ", ")
+}
+
+// Unwrap returns ErrHandlerNotAcceptable so callers can check the error with errors.Is
+func (e *NotAcceptableError) Unwrap() error {
+	return ErrHandlerNotAcceptable
+}
+
+// vehicleJSONFields is the getter of each field of VehicleJSON that can be selected with ?fields=, by name
+// - the names are the columns of the loader csv format (loader.VehicleCSVColumns), which also gives their order
+var vehicleJSONFields = map[string]func(v VehicleJSON) any{
+	"id":           func(v VehicleJSON) any { return v.Id },
+	"brand":        func(v VehicleJSON) any { return v.Brand },
+	"model":        func(v VehicleJSON) any { return v.Model },
+	"registration": func(v VehicleJSON) any { return v.Registration },
+	"color":        func(v VehicleJSON) any { return v.Color },
+	"year":         func(v VehicleJSON) any { return v.FabricationYear },
+	"passengers":   func(v VehicleJSON) any { return v.Capacity },
+	"max_speed":    func(v VehicleJSON) any { return v.MaxSpeed },
+	"fuel_type":    func(v VehicleJSON) any { return v.FuelType },
+	"transmission": func(v VehicleJSON) any { return v.Transmission },
+	"weight":       func(v VehicleJSON) any { return v.Weight },
+	"height":       func(v VehicleJSON) any { return v.Height },
+	"length":       func(v VehicleJSON) any { return v.Length },
+	"width":        func(v VehicleJSON) any { return v.Width },
+}
+
+// vehicleView is a struct that represents how vehicles are rendered in a response
+type vehicleView struct {
+	// format is the encoding of the response
+	format vehicleFormat
+	// fields is the names of the sparse fieldset selected with ?fields= (nil means every field)
+	fields []string
+	// units is the unit system of the quantities (canonical if empty)
+	units internal.UnitSystem
+}
+
+// parseVehicleView is a function that decodes a vehicleView of a single vehicle from a request
+// - accept: the client must accept MediaTypeVehicleV1 (or a generic json media type)
+// - fields: comma separated names of VehicleJSON fields (e.g. fields=id,brand,year)
+// - units: the unit system of the quantities, see parseUnits
+func parseVehicleView(r *http.Request) (vw vehicleView, err error) {
+	vw.format, err = negotiateVehicleFormat(r.Header.Get("Accept"), "", vehicleMediaTypes[:2])
+	if err != nil {
+		return
+	}
+	vw.fields, err = parseVehicleFields(r.URL.Query())
+	if err != nil {
+		return
+	}
+	vw.units, err = parseUnits(r)
+	return
+}
+
+// parseVehicleFields is a function that decodes the sparse fieldset of a vehicleView (nil means every field)
+func parseVehicleFields(q url.Values) (fields []string, err error) {
+	if !q.Has("fields") {
+		return
+	}
+	fields = []string{}
+	for _, name := range strings.Split(q.Get("fields"), ",") {
+		if _, ok := vehicleJSONFields[name]; !ok {
+			fields, err = nil, fmt.Errorf("invalid field %s", name)
+			return
+		}
+		fields = append(fields, name)
+	}
+	return
+}
+
+// render is a method that returns the public representation of a vehicle
+func (vw vehicleView) render(v internal.Vehicle) any {
+	body := vehicleToJSON(vw.units.Vehicle(v))
+	if vw.fields == nil {
+		return body
+	}
+	sparse := make(map[string]any, len(vw.fields))
+	for _, name := range vw.fields {
+		sparse[name] = vehicleJSONFields[name](body)
+	}
+	return sparse
+}
+
+// renderAll is a method that returns the public representation of a list of vehicles
+func (vw vehicleView) renderAll(v []internal.Vehicle) []any {
+	data := make([]any, 0, len(v))
+	for _, vh := range v {
+		data = append(data, vw.render(vh))
+	}
+	return data
+}
+
+// writeViewError is a function that writes the response for an error returned by parseVehicleView or parseVehicleListView
+func writeViewError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrHandlerNotAcceptable) {
+		writeError(w, r, err)
+		return
+	}
+	writeBadRequest(w, r, err.Error())
+}
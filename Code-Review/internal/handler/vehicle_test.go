@@ -3,10 +3,20 @@ package handler_test
 import (
 	"app/internal"
 	"app/internal/handler"
+	"app/internal/loader"
 	"app/internal/service"
+	"app/platform/web/logging"
+	"app/platform/web/requestid"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -14,8 +24,8 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var VehicleMap = map[int]internal.Vehicle{
-	1: {
+var Vehicles = []internal.Vehicle{
+	{
 		Id: 1,
 		VehicleAttributes: internal.VehicleAttributes{
 			Brand:           "Ford",
@@ -39,33 +49,44 @@ var VehicleMap = map[int]internal.Vehicle{
 
 var ExpectBody = `{
 	"message": "vehicles found",
-	"data": {
-		"1": {
-			"Id": 1,
-			"Brand": "Ford",
-			"Model": "Fiesta",
-			"Registration": "ABC-123",
-			"Color": "red",
-			"FabricationYear": 2010,
-			"Capacity": 5,
-			"MaxSpeed": 180,
-			"FuelType": "gasoline",
-			"Transmission": "manual",
-			"Weight": 1000,
-			"Height": 1.5,
-			"Length": 4,
-			"Width": 1.8
+	"data": [
+		{
+			"id": 1,
+			"brand": "Ford",
+			"model": "Fiesta",
+			"registration": "ABC-123",
+			"color": "red",
+			"year": 2010,
+			"passengers": 5,
+			"max_speed": 180,
+			"fuel_type": "gasoline",
+			"transmission": "manual",
+			"weight": 1000,
+			"height": 1.5,
+			"length": 4,
+			"width": 1.8
 		}
+	],
+	"meta": {
+		"total": 1,
+		"offset": 0,
+		"next_cursor": null,
+		"units": ` + MetricUnits + `
 	}
 }`
 
+// MetricUnits is the units meta of the responses in canonical units
+const MetricUnits = `{"system": "metric", "symbols": {"max_speed": "km/h", "weight": "kg", "height": "cm", "length": "cm", "width": "cm"}}`
+
 func TestHandlerVehicle_FindByColorAndYear(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		// arrange
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.FindByColorAndYear()
-		s.On("FindByColorAndYear", "red", 2010).Return(VehicleMap, nil)
+		color, year := "red", 2010
+		s.On("Page", mock.Anything, internal.VehicleFilter{Color: &color, FabricationYear: internal.Range[int]{Min: &year, Max: &year}}, internal.PageQuery{}).
+			Return(Vehicles, internal.Page{Total: 1}, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/color/", nil)
@@ -81,7 +102,7 @@ func TestHandlerVehicle_FindByColorAndYear(t *testing.T) {
 		require.Equal(t, http.StatusOK, w.Code)
 		require.JSONEq(t, ExpectBody, w.Body.String())
 		s.AssertExpectations(t)
-		s.AssertNumberOfCalls(t, "FindByColorAndYear", 1)
+		s.AssertNumberOfCalls(t, "Page", 1)
 	})
 
 	t.Run("case error, year invalid in request", func(t *testing.T) {
@@ -89,7 +110,9 @@ func TestHandlerVehicle_FindByColorAndYear(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.FindByColorAndYear()
-		s.On("FindByColorAndYear", "red", 2010).Return(map[int]internal.Vehicle{}, nil)
+		color, year := "red", 2010
+		s.On("Page", mock.Anything, internal.VehicleFilter{Color: &color, FabricationYear: internal.Range[int]{Min: &year, Max: &year}}, internal.PageQuery{}).
+			Return([]internal.Vehicle{}, internal.Page{}, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/color/", nil)
@@ -104,8 +127,11 @@ func TestHandlerVehicle_FindByColorAndYear(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusBadRequest, w.Code)
 		expectBody := `{
-			"status": "Bad Request",
-			"message": "invalid year"
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid year",
+			"instance": "/vehicles/color/"
 		}`
 
 		require.JSONEq(t, expectBody, w.Body.String())
@@ -117,7 +143,9 @@ func TestHandlerVehicle_FindByColorAndYear(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.FindByColorAndYear()
-		s.On("FindByColorAndYear", "redd", 20100).Return(map[int]internal.Vehicle{}, internal.ErrServiceNoVehicles)
+		color, year := "redd", 20100
+		s.On("Page", mock.Anything, internal.VehicleFilter{Color: &color, FabricationYear: internal.Range[int]{Min: &year, Max: &year}}, internal.PageQuery{}).
+			Return([]internal.Vehicle{}, internal.Page{}, internal.ErrServiceNoVehicles)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/color/", nil)
@@ -132,13 +160,69 @@ func TestHandlerVehicle_FindByColorAndYear(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusNotFound, w.Code)
 		expectBody := `{
-			"status": "Not Found",
-			"message": "vehicles not found"
+			"type": "about:blank",
+			"title": "Not Found",
+			"status": 404,
+			"detail": "vehicles not found",
+			"instance": "/vehicles/color/"
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
 		s.AssertExpectations(t)
-		s.AssertNumberOfCalls(t, "FindByColorAndYear", 1)
+		s.AssertNumberOfCalls(t, "Page", 1)
+
+	})
+
+	t.Run("case error, deadline exceeded", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.FindByColorAndYear()
+		color, year := "red", 2010
+		s.On("Page", mock.Anything, internal.VehicleFilter{Color: &color, FabricationYear: internal.Range[int]{Min: &year, Max: &year}}, internal.PageQuery{}).
+			Return([]internal.Vehicle(nil), internal.Page{}, context.DeadlineExceeded)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/color/", nil)
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("color", "red")
+		ctx.URLParams.Add("year", "2010")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusGatewayTimeout, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Gateway Timeout",
+			"status": 504,
+			"detail": "request timed out",
+			"instance": "/vehicles/color/"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+	})
+
+	t.Run("case error, cancelled by the client", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.FindByColorAndYear()
+		color, year := "red", 2010
+		s.On("Page", mock.Anything, internal.VehicleFilter{Color: &color, FabricationYear: internal.Range[int]{Min: &year, Max: &year}}, internal.PageQuery{}).
+			Return([]internal.Vehicle(nil), internal.Page{}, context.Canceled)
 
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/color/", nil)
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("color", "red")
+		ctx.URLParams.Add("year", "2010")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, handler.StatusClientClosedRequest, w.Code)
+		require.Empty(t, w.Body.String())
 	})
 }
 
@@ -148,7 +232,9 @@ func TestHandlerVehicle_FindByBrandAndYearRange(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.FindByBrandAndYearRange()
-		s.On("FindByBrandAndYearRange", "Ford", 2010, 2015).Return(VehicleMap, nil)
+		brand, startYear, endYear := "Ford", 2010, 2015
+		s.On("Page", mock.Anything, internal.VehicleFilter{Brand: &brand, FabricationYear: internal.Range[int]{Min: &startYear, Max: &endYear}}, internal.PageQuery{}).
+			Return(Vehicles, internal.Page{Total: 1}, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/brand/", nil)
@@ -165,14 +251,16 @@ func TestHandlerVehicle_FindByBrandAndYearRange(t *testing.T) {
 		require.Equal(t, http.StatusOK, w.Code)
 		require.JSONEq(t, ExpectBody, w.Body.String())
 		s.AssertExpectations(t)
-		s.AssertNumberOfCalls(t, "FindByBrandAndYearRange", 1)
+		s.AssertNumberOfCalls(t, "Page", 1)
 	})
 	t.Run("case error, start_year invalid in request", func(t *testing.T) {
 		// arrange
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.FindByBrandAndYearRange()
-		s.On("FindByBrandAndYearRange", "Ford", 2010, 2015).Return(map[int]internal.Vehicle{}, nil)
+		brand, startYear, endYear := "Ford", 2010, 2015
+		s.On("Page", mock.Anything, internal.VehicleFilter{Brand: &brand, FabricationYear: internal.Range[int]{Min: &startYear, Max: &endYear}}, internal.PageQuery{}).
+			Return([]internal.Vehicle{}, internal.Page{}, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/brand/", nil)
@@ -188,8 +276,11 @@ func TestHandlerVehicle_FindByBrandAndYearRange(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusBadRequest, w.Code)
 		expectBody := `{
-			"status": "Bad Request",
-			"message": "invalid start_year"
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid start_year",
+			"instance": "/vehicles/brand/"
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
 	})
@@ -199,7 +290,9 @@ func TestHandlerVehicle_FindByBrandAndYearRange(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.FindByBrandAndYearRange()
-		s.On("FindByBrandAndYearRange", "Ford", 2010, 2015).Return(map[int]internal.Vehicle{}, nil)
+		brand, startYear, endYear := "Ford", 2010, 2015
+		s.On("Page", mock.Anything, internal.VehicleFilter{Brand: &brand, FabricationYear: internal.Range[int]{Min: &startYear, Max: &endYear}}, internal.PageQuery{}).
+			Return([]internal.Vehicle{}, internal.Page{}, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/brand/", nil)
@@ -215,8 +308,11 @@ func TestHandlerVehicle_FindByBrandAndYearRange(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusBadRequest, w.Code)
 		expectBody := `{
-			"status": "Bad Request",
-			"message": "invalid end_year"
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid end_year",
+			"instance": "/vehicles/brand/"
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
 	})
@@ -226,7 +322,9 @@ func TestHandlerVehicle_FindByBrandAndYearRange(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.FindByBrandAndYearRange()
-		s.On("FindByBrandAndYearRange", "Ford", 2010, 2015).Return(map[int]internal.Vehicle{}, internal.ErrServiceNoVehicles)
+		brand, startYear, endYear := "Ford", 2010, 2015
+		s.On("Page", mock.Anything, internal.VehicleFilter{Brand: &brand, FabricationYear: internal.Range[int]{Min: &startYear, Max: &endYear}}, internal.PageQuery{}).
+			Return([]internal.Vehicle{}, internal.Page{}, internal.ErrServiceNoVehicles)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/brand/", nil)
@@ -242,12 +340,15 @@ func TestHandlerVehicle_FindByBrandAndYearRange(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusNotFound, w.Code)
 		expectBody := `{
-			"status": "Not Found",
-			"message": "vehicles not found"
+			"type": "about:blank",
+			"title": "Not Found",
+			"status": 404,
+			"detail": "vehicles not found",
+			"instance": "/vehicles/brand/"
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
 		s.AssertExpectations(t)
-		s.AssertNumberOfCalls(t, "FindByBrandAndYearRange", 1)
+		s.AssertNumberOfCalls(t, "Page", 1)
 	})
 
 }
@@ -258,7 +359,7 @@ func Test_handler_AverageMaxSpeedByBrand(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.AverageMaxSpeedByBrand()
-		s.On("AverageMaxSpeedByBrand", "Ford").Return(180.0, nil)
+		s.On("AverageMaxSpeedByBrand", mock.Anything, "Ford").Return(180.0, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/average_speed/brand/", nil)
@@ -273,7 +374,8 @@ func Test_handler_AverageMaxSpeedByBrand(t *testing.T) {
 		require.Equal(t, http.StatusOK, w.Code)
 		expectBody := `{
 			"message": "average max speed found",
-			"data": 180
+			"data": 180,
+			"meta": {"units": ` + MetricUnits + `}
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
 		s.AssertExpectations(t)
@@ -285,7 +387,7 @@ func Test_handler_AverageMaxSpeedByBrand(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.AverageMaxSpeedByBrand()
-		s.On("AverageMaxSpeedByBrand", "Ford").Return(0.0, internal.ErrServiceNoVehicles)
+		s.On("AverageMaxSpeedByBrand", mock.Anything, "Ford").Return(0.0, internal.ErrServiceNoVehicles)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/average_speed/brand/", nil)
@@ -299,13 +401,91 @@ func Test_handler_AverageMaxSpeedByBrand(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusNotFound, w.Code)
 		expectBody := `{
-			"status": "Not Found",
-			"message": "vehicles not found"
+			"type": "about:blank",
+			"title": "Not Found",
+			"status": 404,
+			"detail": "vehicles not found",
+			"instance": "/vehicles/average_speed/brand/"
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
 		s.AssertExpectations(t)
 		s.AssertNumberOfCalls(t, "AverageMaxSpeedByBrand", 1)
 	})
+
+	t.Run("case error, vehicles not found with suggestions", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.AverageMaxSpeedByBrand()
+		err := &internal.NoVehiclesError{Suggestions: []internal.TextSuggestion{
+			{Attribute: "brand", Value: "chevrolte", Suggestions: []string{"Chevrolet"}},
+		}}
+		s.On("AverageMaxSpeedByBrand", mock.Anything, "chevrolte").Return(0.0, err)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/average_speed/brand/", nil)
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("brand", "chevrolte")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusNotFound, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Not Found",
+			"status": 404,
+			"detail": "vehicles not found",
+			"instance": "/vehicles/average_speed/brand/",
+			"suggestions": [{"field": "brand", "value": "chevrolte", "did_you_mean": ["Chevrolet"]}]
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case - match fuzzy is passed to the service", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.AverageMaxSpeedByBrand()
+		fuzzy := mock.MatchedBy(func(ctx context.Context) bool {
+			return internal.TextMatchFromContext(ctx) == internal.TextMatchFuzzy
+		})
+		s.On("AverageMaxSpeedByBrand", fuzzy, "chevrolte").Return(180.0, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/average_speed/brand/?match=fuzzy", nil)
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("brand", "chevrolte")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case error, invalid match", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.AverageMaxSpeedByBrand()
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/average_speed/brand/?match=soundex", nil)
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("brand", "Ford")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), `"detail":"invalid match, expected normalized or fuzzy"`)
+		s.AssertNotCalled(t, "AverageMaxSpeedByBrand")
+	})
 }
 
 func Test_handler_AverageCapacityByBrand(t *testing.T) {
@@ -314,7 +494,7 @@ func Test_handler_AverageCapacityByBrand(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.AverageCapacityByBrand()
-		s.On("AverageCapacityByBrand", "Ford").Return(5, nil)
+		s.On("AverageCapacityByBrand", mock.Anything, "Ford").Return(5.0, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/average_capacity/brand/", nil)
@@ -341,7 +521,7 @@ func Test_handler_AverageCapacityByBrand(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.AverageCapacityByBrand()
-		s.On("AverageCapacityByBrand", "Ford").Return(0, internal.ErrServiceNoVehicles)
+		s.On("AverageCapacityByBrand", mock.Anything, "Ford").Return(0.0, internal.ErrServiceNoVehicles)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/average_capacity/brand/", nil)
@@ -355,8 +535,11 @@ func Test_handler_AverageCapacityByBrand(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusNotFound, w.Code)
 		expectBody := `{
-			"status": "Not Found",
-			"message": "vehicles not found"
+			"type": "about:blank",
+			"title": "Not Found",
+			"status": 404,
+			"detail": "vehicles not found",
+			"instance": "/vehicles/average_capacity/brand/"
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
 		s.AssertExpectations(t)
@@ -370,7 +553,7 @@ func Test_handler_SearchByWeightRange(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.SearchByWeightRange()
-		s.On("SearchByWeightRange", mock.AnythingOfType("internal.SearchQuery"), mock.AnythingOfType("bool")).Return(VehicleMap, nil)
+		s.On("Page", mock.Anything, mock.AnythingOfType("internal.VehicleFilter"), internal.PageQuery{}).Return(Vehicles, internal.Page{Total: 1}, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/weight/", nil)
@@ -386,7 +569,7 @@ func Test_handler_SearchByWeightRange(t *testing.T) {
 		require.Equal(t, http.StatusOK, w.Code)
 		require.JSONEq(t, ExpectBody, w.Body.String())
 		s.AssertExpectations(t)
-		s.AssertNumberOfCalls(t, "SearchByWeightRange", 1)
+		s.AssertNumberOfCalls(t, "Page", 1)
 	})
 
 	t.Run("case error, weight_min is not a number", func(t *testing.T) {
@@ -394,8 +577,7 @@ func Test_handler_SearchByWeightRange(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.SearchByWeightRange()
-		s.On("SearchByWeightRange", mock.AnythingOfType("internal.SearchQuery"), mock.AnythingOfType("bool")).Return(
-			map[int]internal.Vehicle{}, nil)
+		s.On("Page", mock.Anything, mock.AnythingOfType("internal.VehicleFilter"), internal.PageQuery{}).Return([]internal.Vehicle{}, internal.Page{}, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/weight/", nil)
@@ -410,8 +592,11 @@ func Test_handler_SearchByWeightRange(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusBadRequest, w.Code)
 		expectBody := `{
-			"status": "Bad Request",
-			"message": "invalid weight_min"
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid weight_min",
+			"instance": "/vehicles/weight/"
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
 
@@ -422,8 +607,7 @@ func Test_handler_SearchByWeightRange(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.SearchByWeightRange()
-		s.On("SearchByWeightRange", mock.AnythingOfType("internal.SearchQuery"), mock.AnythingOfType("bool")).Return(
-			map[int]internal.Vehicle{}, nil)
+		s.On("Page", mock.Anything, mock.AnythingOfType("internal.VehicleFilter"), internal.PageQuery{}).Return([]internal.Vehicle{}, internal.Page{}, nil)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/weight/", nil)
@@ -438,8 +622,11 @@ func Test_handler_SearchByWeightRange(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusBadRequest, w.Code)
 		expectBody := `{
-			"status": "Bad Request",
-			"message": "invalid weight_max"
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid weight_max",
+			"instance": "/vehicles/weight/"
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
 
@@ -450,8 +637,7 @@ func Test_handler_SearchByWeightRange(t *testing.T) {
 		s := service.NewServiceVehicleDefaultMock()
 		hd := handler.NewHandlerVehicle(s)
 		h := hd.SearchByWeightRange()
-		s.On("SearchByWeightRange", mock.AnythingOfType("internal.SearchQuery"), mock.AnythingOfType("bool")).Return(
-			map[int]internal.Vehicle{}, internal.ErrServiceNoVehicles)
+		s.On("Page", mock.Anything, mock.AnythingOfType("internal.VehicleFilter"), internal.PageQuery{}).Return([]internal.Vehicle{}, internal.Page{}, internal.ErrServiceNoVehicles)
 
 		//request
 		r := httptest.NewRequest(http.MethodGet, "/vehicles/weight/", nil)
@@ -466,13 +652,1159 @@ func Test_handler_SearchByWeightRange(t *testing.T) {
 		// assert
 		require.Equal(t, http.StatusNotFound, w.Code)
 		expectBody := `{
-			"status": "Not Found",
-			"message": "vehicles not found"
+			"type": "about:blank",
+			"title": "Not Found",
+			"status": 404,
+			"detail": "vehicles not found",
+			"instance": "/vehicles/weight/"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+
+		s.AssertExpectations(t)
+		s.AssertNumberOfCalls(t, "Page", 1)
+	})
+
+}
+
+func Test_handler_SearchByWeightRange_OpenEnded(t *testing.T) {
+	t.Run("case - only weight_min", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.SearchByWeightRange()
+		fromWeight := 1000.0
+		s.On("Page", mock.Anything, internal.VehicleFilter{Weight: internal.Range[float64]{Min: &fromWeight}}, internal.PageQuery{}).Return(Vehicles, internal.Page{Total: 1}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/weight?weight_min=1000", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case error, weight_min greater than weight_max", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.SearchByWeightRange()
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/weight?weight_min=2000&weight_max=1000", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid weight_min: greater than weight_max",
+			"instance": "/vehicles/weight"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertNotCalled(t, "Page")
+	})
+}
+
+func TestHandlerVehicle_Search(t *testing.T) {
+	t.Run("case - success", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		brand, fromYear, toSpeed := "Ford", 2005, 200.0
+		s.On("Page", mock.Anything, internal.VehicleFilter{
+			Brand:           &brand,
+			FabricationYear: internal.Range[int]{Min: &fromYear},
+			MaxSpeed:        internal.Range[float64]{Max: &toSpeed},
+		}, internal.PageQuery{}).Return(Vehicles, internal.Page{Total: 1}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?brand=Ford&year_gte=2005&max_speed_lte=200", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, ExpectBody, w.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case error, invalid bound", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?capacity_gte=abc", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid capacity_gte",
+			"instance": "/vehicles"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertNotCalled(t, "Page")
+	})
+
+	t.Run("case error, min above max", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		s.On("Page", mock.Anything, mock.AnythingOfType("internal.VehicleFilter"), internal.PageQuery{}).Return([]internal.Vehicle{}, internal.Page{},
+			fmt.Errorf("%w: %w", internal.ErrServiceInvalidSearch, &internal.FilterFieldError{Field: "year", Message: "min is greater than max"}))
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?year_gte=2020&year_lte=2010", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid year: min is greater than max",
+			"instance": "/vehicles",
+			"errors": [{"field": "year", "message": "min is greater than max"}]
+		}`
+		require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		require.JSONEq(t, expectBody, w.Body.String())
+	})
+}
+
+func TestHandlerVehicle_Units(t *testing.T) {
+	// ImperialUnits is the units meta of the responses in imperial units
+	const ImperialUnits = `{"system": "imperial", "symbols": {"max_speed": "mph", "weight": "lb", "height": "in", "length": "in", "width": "in"}}`
+
+	t.Run("case - imperial values and range filters", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		fromWeight, toSpeed := 907.1847, 180.0
+		s.On("Page", mock.Anything, internal.VehicleFilter{
+			Weight:   internal.Range[float64]{Min: &fromWeight},
+			MaxSpeed: internal.Range[float64]{Max: &toSpeed},
+		}, internal.PageQuery{}).Return(Vehicles, internal.Page{Total: 1}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?units=imperial&weight_gte=2000&max_speed_lte=111.846815", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		expectBody := `{
+			"message": "vehicles found",
+			"data": [{"id": 1, "brand": "Ford", "model": "Fiesta", "registration": "ABC-123", "color": "red", "year": 2010,
+				"passengers": 5, "max_speed": 111.846815, "fuel_type": "gasoline", "transmission": "manual", "weight": 2204.622622,
+				"height": 0.590551, "length": 1.574803, "width": 0.708661}],
+			"meta": {"total": 1, "offset": 0, "next_cursor": null, "units": ` + ImperialUnits + `}
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case - imperial weight range", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.SearchByWeightRange()
+		fromWeight := 1000.0
+		s.On("Page", mock.Anything, internal.VehicleFilter{Weight: internal.Range[float64]{Min: &fromWeight}}, internal.PageQuery{}).Return(Vehicles, internal.Page{Total: 1}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/weight?weight_min=2204.622622&fields=id,weight", nil)
+		r.Header.Set(handler.HeaderAcceptUnits, "imperial")
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		expectBody := `{
+			"message": "vehicles found",
+			"data": [{"id": 1, "weight": 2204.622622}],
+			"meta": {"total": 1, "offset": 0, "next_cursor": null, "units": ` + ImperialUnits + `}
 		}`
 		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case - the units parameter takes precedence over the header", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		s.On("Page", mock.Anything, internal.VehicleFilter{}, internal.PageQuery{}).Return(Vehicles, internal.Page{Total: 1}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?units=metric", nil)
+		r.Header.Set(handler.HeaderAcceptUnits, "imperial")
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, ExpectBody, w.Body.String())
+	})
+
+	t.Run("case - the response of a write is in the units, the system in any case", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Create()
+		v := Vehicles[0]
+		s.On("Save", mock.Anything, &v).Return(nil)
 
+		//request
+		r := httptest.NewRequest(http.MethodPost, "/vehicles?units=Imperial&fields=id,weight,height", strings.NewReader(`{"id": 1, "brand": "Ford",
+			"model": "Fiesta", "registration": "ABC-123", "color": "red", "year": 2010, "passengers": 5, "max_speed": 180,
+			"fuel_type": "gasoline", "transmission": "manual", "weight": 1000, "height": 1.5, "length": 4, "width": 1.8}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusCreated, w.Code)
+		expectBody := `{
+			"message": "vehicle created",
+			"data": {"id": 1, "weight": 2204.622622, "height": 0.590551},
+			"meta": {"units": ` + ImperialUnits + `}
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
 		s.AssertExpectations(t)
-		s.AssertNumberOfCalls(t, "SearchByWeightRange", 1)
 	})
 
+	t.Run("case - imperial aggregates but the count", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Stats()
+		s.On("Stats", mock.Anything, internal.VehicleFilter{}, internal.StatsQuery{Metric: "max_speed", Aggregates: []string{"count", "avg"}}).
+			Return([]internal.StatsGroup{{Key: []string{}, Values: []float64{2, 180}}}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/stats?metric=max_speed&agg=count,avg&units=imperial", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		expectBody := `{
+			"message": "stats found",
+			"data": [{"group": {}, "values": {"count": 2, "avg": 111.846815}}],
+			"meta": {"group_by": [], "metric": "max_speed", "agg": ["count", "avg"], "units": ` + ImperialUnits + `}
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+	})
+
+	t.Run("case - streamed formats name the units in a header", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		s.On("Stream", mock.Anything, internal.VehicleFilter{}).Return(internal.NewVehicleIteratorSlice(Vehicles), nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?format=csv&fields=id,weight&units=imperial", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "imperial", w.Header().Get("X-Units"))
+		require.Equal(t, "id,weight\n1,2204.622622\n", w.Body.String())
+	})
+
+	t.Run("case error, invalid units", func(t *testing.T) {
+		for name, h := range map[string]http.HandlerFunc{
+			"search":  handler.NewHandlerVehicle(service.NewServiceVehicleDefaultMock()).Search(),
+			"stats":   handler.NewHandlerVehicle(service.NewServiceVehicleDefaultMock()).Stats(),
+			"compare": handler.NewHandlerVehicle(service.NewServiceVehicleDefaultMock()).Compare(),
+			"create":  handler.NewHandlerVehicle(service.NewServiceVehicleDefaultMock()).Create(),
+		} {
+			//request
+			r := httptest.NewRequest(http.MethodGet, "/vehicles?metric=weight&ids=1,2&units=nautical", nil)
+			w := httptest.NewRecorder()
+			// act
+			h(w, r)
+			// assert
+			require.Equal(t, http.StatusBadRequest, w.Code, name)
+			require.Contains(t, w.Body.String(), `"detail":"invalid units, expected metric or imperial"`, name)
+		}
+	})
+}
+
+func TestHandlerVehicle_Stats(t *testing.T) {
+	t.Run("case - success", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Stats()
+		fromYear := 2005
+		s.On("Stats",
+			mock.Anything,
+			internal.VehicleFilter{FabricationYear: internal.Range[int]{Min: &fromYear}},
+			internal.StatsQuery{GroupBy: []string{"brand", "fuel_type"}, Metric: "max_speed", Aggregates: []string{"avg", "p95", "count"}},
+		).Return([]internal.StatsGroup{
+			{Key: []string{"Ford", "gasoline"}, Values: []float64{180.5, 199, 2}},
+		}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/stats?year_gte=2005&group_by=brand,fuel_type&metric=max_speed&agg=avg,p95,count", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		expectBody := `{
+			"message": "stats found",
+			"data": [
+				{"group": {"brand": "Ford", "fuel_type": "gasoline"}, "values": {"avg": 180.5, "p95": 199, "count": 2}}
+			],
+			"meta": {"group_by": ["brand", "fuel_type"], "metric": "max_speed", "agg": ["avg", "p95", "count"], "units": ` + MetricUnits + `}
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case - default aggregates without groups", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Stats()
+		s.On("Stats", mock.Anything, internal.VehicleFilter{}, internal.StatsQuery{Metric: "weight", Aggregates: []string{"count", "avg", "min", "max"}}).
+			Return([]internal.StatsGroup{{Key: []string{}, Values: []float64{1, 1000, 1000, 1000}}}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/stats?metric=weight", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		expectBody := `{
+			"message": "stats found",
+			"data": [{"group": {}, "values": {"count": 1, "avg": 1000, "min": 1000, "max": 1000}}],
+			"meta": {"group_by": [], "metric": "weight", "agg": ["count", "avg", "min", "max"], "units": ` + MetricUnits + `}
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+	})
+
+	t.Run("case error, invalid parameters", func(t *testing.T) {
+		for query, message := range map[string]string{
+			"group_by=registration&metric=weight": "invalid group_by registration",
+			"metric=brand":                        "invalid metric brand",
+			"metric=weight&agg=avg,median":        "invalid agg median",
+			"metric=weight&agg=p120":              "invalid agg p120",
+		} {
+			// arrange
+			s := service.NewServiceVehicleDefaultMock()
+			hd := handler.NewHandlerVehicle(s)
+			h := hd.Stats()
+
+			//request
+			r := httptest.NewRequest(http.MethodGet, "/vehicles/stats?"+query, nil)
+			w := httptest.NewRecorder()
+			// act
+			h(w, r)
+			// assert
+			require.Equal(t, http.StatusBadRequest, w.Code, query)
+			require.JSONEq(t, `{"type": "about:blank", "title": "Bad Request", "status": 400, "detail": "`+message+`", "instance": "/vehicles/stats"}`, w.Body.String(), query)
+			s.AssertNotCalled(t, "Stats")
+		}
+	})
+
+	t.Run("case error, vehicles not found", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Stats()
+		s.On("Stats", mock.Anything, mock.Anything, mock.Anything).Return([]internal.StatsGroup{}, internal.ErrServiceNoVehicles)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/stats?brand=Tesla&metric=weight", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.JSONEq(t, `{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "vehicles not found", "instance": "/vehicles/stats"}`, w.Body.String())
+	})
+}
+
+func TestHandlerVehicle_Compare(t *testing.T) {
+	t.Run("case - success", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Compare()
+		other := Vehicles[0]
+		other.Id, other.Registration, other.MaxSpeed = 7, "XYZ-789", 200
+		s.On("Compare", mock.Anything, []int{1, 7}).Return(internal.NewVehicleComparison([]internal.Vehicle{Vehicles[0], other}), nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/compare?ids=1,7", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		expectBody := `{
+			"message": "vehicles compared",
+			"data": {
+				"ids": [1, 7],
+				"attributes": [
+					{"name": "brand", "values": ["Ford", "Ford"], "differ": false},
+					{"name": "model", "values": ["Fiesta", "Fiesta"], "differ": false},
+					{"name": "registration", "values": ["ABC-123", "XYZ-789"], "differ": true},
+					{"name": "color", "values": ["red", "red"], "differ": false},
+					{"name": "fuel_type", "values": ["gasoline", "gasoline"], "differ": false},
+					{"name": "transmission", "values": ["manual", "manual"], "differ": false},
+					{"name": "year", "values": [2010, 2010], "differ": false, "best": "max", "ranks": [1, 1], "deltas": [0, 0]},
+					{"name": "capacity", "values": [5, 5], "differ": false, "best": "max", "ranks": [1, 1], "deltas": [0, 0]},
+					{"name": "max_speed", "values": [180, 200], "differ": true, "best": "max", "ranks": [2, 1], "deltas": [20, 0]},
+					{"name": "weight", "values": [1000, 1000], "differ": false, "best": "min", "ranks": [1, 1], "deltas": [0, 0]},
+					{"name": "height", "values": [1.5, 1.5], "differ": false, "best": "min", "ranks": [1, 1], "deltas": [0, 0]},
+					{"name": "length", "values": [4, 4], "differ": false, "best": "min", "ranks": [1, 1], "deltas": [0, 0]},
+					{"name": "width", "values": [1.8, 1.8], "differ": false, "best": "min", "ranks": [1, 1], "deltas": [0, 0]}
+				]
+			},
+			"meta": {"units": ` + MetricUnits + `}
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case error, invalid ids", func(t *testing.T) {
+		for query, message := range map[string]string{
+			"":            "invalid ids: missing",
+			"ids=1,seven": `invalid ids: \"seven\" is not an id`,
+		} {
+			// arrange
+			s := service.NewServiceVehicleDefaultMock()
+			hd := handler.NewHandlerVehicle(s)
+			h := hd.Compare()
+
+			//request
+			r := httptest.NewRequest(http.MethodGet, "/vehicles/compare?"+query, nil)
+			w := httptest.NewRecorder()
+			// act
+			h(w, r)
+			// assert
+			require.Equal(t, http.StatusBadRequest, w.Code, query)
+			require.JSONEq(t, `{"type": "about:blank", "title": "Bad Request", "status": 400, "detail": "`+message+`", "instance": "/vehicles/compare"}`, w.Body.String(), query)
+			s.AssertNotCalled(t, "Compare")
+		}
+	})
+
+	t.Run("case error, ids rejected by the service", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Compare()
+		s.On("Compare", mock.Anything, []int{1}).
+			Return(internal.VehicleComparison{}, fmt.Errorf("%w: %w", internal.ErrServiceInvalidCompare, internal.ValidateCompareIds([]int{1})))
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/compare?ids=1", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.JSONEq(t, `{"type": "about:blank", "title": "Bad Request", "status": 400,
+			"detail": "invalid ids: expected between 2 and 10 ids, got 1", "instance": "/vehicles/compare",
+			"errors": [{"field": "ids", "message": "expected between 2 and 10 ids, got 1"}]}`, w.Body.String())
+	})
+
+	t.Run("case error, vehicles not found", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Compare()
+		s.On("Compare", mock.Anything, []int{1, 7, 42}).Return(internal.VehicleComparison{}, &internal.VehiclesNotFoundError{Ids: []int{7, 42}})
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/compare?ids=1,7,42", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.JSONEq(t, `{"type": "about:blank", "title": "Not Found", "status": 404,
+			"detail": "vehicles not found: 7, 42", "instance": "/vehicles/compare",
+			"errors": [{"field": "ids", "message": "vehicle 7 not found"}, {"field": "ids", "message": "vehicle 42 not found"}]}`, w.Body.String())
+	})
+}
+
+func TestHandlerVehicle_Similar(t *testing.T) {
+	// serve is a function that serves the handler behind its route, so the id is a URL parameter
+	serve := func(s *service.Mock, target string) *httptest.ResponseRecorder {
+		rt := chi.NewRouter()
+		rt.Get("/vehicles/{id}/similar", handler.NewHandlerVehicle(s).Similar())
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("case - success", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		s.On("Similar", mock.Anything, 7, internal.SimilarQuery{K: 1, Weights: map[string]float64{"max_speed": 2, "capacity": 0.5}, Same: []string{"fuel_type"}}).
+			Return([]internal.SimilarVehicle{{Vehicle: Vehicles[0], Distance: 0.25}}, nil)
+		// act
+		w := serve(s, "/vehicles/7/similar?k=1&weights=max_speed:2,capacity:0.5&same=fuel_type")
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		expectBody := `{
+			"message": "similar vehicles found",
+			"data": [{"vehicle": {"id": 1, "brand": "Ford", "model": "Fiesta", "registration": "ABC-123", "color": "red", "year": 2010,
+				"passengers": 5, "max_speed": 180, "fuel_type": "gasoline", "transmission": "manual", "weight": 1000,
+				"height": 1.5, "length": 4, "width": 1.8}, "distance": 0.25}],
+			"meta": {"id": 7, "k": 1, "same": ["fuel_type"],
+				"weights": {"year": 1, "capacity": 0.5, "max_speed": 2, "weight": 1, "height": 1, "length": 1, "width": 1},
+				"units": ` + MetricUnits + `}
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case - defaults", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		s.On("Similar", mock.Anything, 7, internal.SimilarQuery{K: internal.SimilarDefaultK}).Return([]internal.SimilarVehicle{}, nil)
+		// act
+		w := serve(s, "/vehicles/7/similar")
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case error, invalid parameters", func(t *testing.T) {
+		for target, message := range map[string]string{
+			"/vehicles/seven/similar":                  "invalid id",
+			"/vehicles/7/similar?k=0":                  "invalid k, expected a number between 1 and 100",
+			"/vehicles/7/similar?weights=brand:1":      "invalid weights brand:1",
+			"/vehicles/7/similar?weights=max_speed":    "invalid weights max_speed",
+			"/vehicles/7/similar?weights=max_speed:-1": "invalid weights max_speed:-1",
+			"/vehicles/7/similar?same=fuel_type,color": "invalid same color",
+		} {
+			// arrange
+			s := service.NewServiceVehicleDefaultMock()
+			// act
+			w := serve(s, target)
+			// assert
+			require.Equal(t, http.StatusBadRequest, w.Code, target)
+			require.JSONEq(t, `{"type": "about:blank", "title": "Bad Request", "status": 400, "detail": "`+message+`", "instance": "`+strings.Split(target, "?")[0]+`"}`, w.Body.String(), target)
+			s.AssertNotCalled(t, "Similar")
+		}
+	})
+
+	t.Run("case error, vehicle not found", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		s.On("Similar", mock.Anything, 99, mock.Anything).Return([]internal.SimilarVehicle{}, internal.ErrServiceVehicleNotFound)
+		// act
+		w := serve(s, "/vehicles/99/similar")
+		// assert
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.JSONEq(t, `{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "vehicle not found", "instance": "/vehicles/99/similar"}`, w.Body.String())
+	})
+}
+
+func TestHandlerVehicle_Create(t *testing.T) {
+	body := `{"id": 1, "brand": "Ford", "model": "Fiesta", "registration": "ABC-123", "color": "red", "year": 2010,
+		"passengers": 5, "max_speed": 180, "fuel_type": "gasoline", "transmission": "manual", "weight": 1000,
+		"height": 1.5, "length": 4, "width": 1.8}`
+
+	t.Run("case - success", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Create()
+		v := Vehicles[0]
+		s.On("Save", mock.Anything, &v).Return(nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodPost, "/vehicles", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusCreated, w.Code)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case error, invalid body", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Create()
+
+		//request
+		r := httptest.NewRequest(http.MethodPost, "/vehicles", strings.NewReader(`{"id": "a"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid request body",
+			"instance": "/vehicles"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertNotCalled(t, "Save")
+	})
+
+	t.Run("case error, vehicle already exists", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Create()
+		s.On("Save", mock.Anything, mock.AnythingOfType("*internal.Vehicle")).Return(internal.ErrServiceVehicleConflict)
+
+		//request
+		r := httptest.NewRequest(http.MethodPost, "/vehicles", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusConflict, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Conflict",
+			"status": 409,
+			"detail": "vehicle already exists",
+			"instance": "/vehicles"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertExpectations(t)
+	})
+}
+
+func TestHandlerVehicle_Update(t *testing.T) {
+	t.Run("case error, vehicle not found", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Update()
+		s.On("Update", mock.Anything, mock.AnythingOfType("*internal.Vehicle")).Return(internal.ErrServiceVehicleNotFound)
+
+		//request
+		r := httptest.NewRequest(http.MethodPut, "/vehicles/2", strings.NewReader(`{"brand": "Ford", "model": "Ka", "registration": "XYZ-789"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", "2")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusNotFound, w.Code)
+		s.AssertExpectations(t)
+	})
+}
+
+func TestHandlerVehicle_Patch(t *testing.T) {
+	t.Run("case - success", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Patch()
+		color := "red"
+		s.On("Patch", mock.Anything, 1, internal.VehicleAttributesPatch{Color: &color}).Return(Vehicles[0], nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodPatch, "/vehicles/1", strings.NewReader(`{"color": "red"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", "1")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		s.AssertExpectations(t)
+	})
+}
+
+func TestHandlerVehicle_Delete(t *testing.T) {
+	t.Run("case - success", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Delete()
+		s.On("Delete", mock.Anything, 1).Return(nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodDelete, "/vehicles/1", nil)
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", "1")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusNoContent, w.Code)
+		require.Empty(t, w.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case error, vehicle not found", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Delete()
+		s.On("Delete", mock.Anything, 2).Return(internal.ErrServiceVehicleNotFound)
+
+		//request
+		r := httptest.NewRequest(http.MethodDelete, "/vehicles/2", nil)
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", "2")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusNotFound, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Not Found",
+			"status": 404,
+			"detail": "vehicle not found",
+			"instance": "/vehicles/2"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case error, internal error logged with its cause and request id", func(t *testing.T) {
+		// arrange
+		var logs bytes.Buffer
+		logger := logging.NewLogger(&logs, "json", slog.LevelInfo)
+
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := requestid.Middleware(logging.Middleware(logger)(hd.Delete()))
+		s.On("Delete", mock.Anything, 3).Return(fmt.Errorf("wal: %w", errors.New("disk full")))
+
+		//request
+		r := httptest.NewRequest(http.MethodDelete, "/vehicles/3", nil)
+		r.Header.Set(requestid.Header, "abc-123")
+		w := httptest.NewRecorder()
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", "3")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+		// act
+		h.ServeHTTP(w, r)
+		// assert
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Internal Server Error",
+			"status": 500,
+			"detail": "internal error",
+			"instance": "/vehicles/3",
+			"request_id": "abc-123"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		require.Contains(t, logs.String(), `"error":"wal: disk full"`)
+		require.Contains(t, logs.String(), `"request_id":"abc-123"`)
+	})
+}
+
+func TestHandlerVehicle_Search_Page(t *testing.T) {
+	// fleet is a list of vehicles to page through
+	fleet := []internal.Vehicle{
+		{Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", FabricationYear: 2010}},
+		{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Chevrolet", FabricationYear: 2015}},
+		{Id: 3, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", FabricationYear: 2015}},
+		{Id: 4, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", FabricationYear: 2001}},
+	}
+
+	t.Run("case - the page query is passed to the service, its page written", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		pq := internal.PageQuery{Sort: []internal.SortField{{Name: "year", Desc: true}, {Name: "brand"}}, Limit: 2, Offset: 1}
+		s.On("Page", mock.Anything, internal.VehicleFilter{}, pq).Return([]internal.Vehicle{fleet[2], fleet[0]}, internal.Page{Total: 4, Next: &fleet[0]}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?sort=-year,brand&limit=2&offset=1", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		got, meta := decodePage(t, w.Body.String())
+		require.Equal(t, []int{3, 1}, got)
+		require.Equal(t, 4, meta.Total)
+		require.Equal(t, 2, meta.Limit)
+		require.Equal(t, 1, meta.Offset)
+		require.NotNil(t, meta.NextCursor)
+		// - the cursor holds the sort keys and the id of the last vehicle, not the vehicle
+		b, err := base64.RawURLEncoding.DecodeString(*meta.NextCursor)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"s": "-year,brand", "k": [2010, "Ford"], "i": 1}`, string(b))
+	})
+
+	t.Run("case - cursor", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		cursor := base64.RawURLEncoding.EncodeToString([]byte(`{"s": "brand", "k": ["Ford"], "i": 3}`))
+		pq := internal.PageQuery{Sort: []internal.SortField{{Name: "brand"}}, Limit: 3, After: &internal.Vehicle{Id: 3, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford"}}}
+		s.On("Page", mock.Anything, internal.VehicleFilter{}, pq).Return(fleet[3:], internal.Page{Total: 4}, nil)
+
+		//request: the offset is ignored
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?sort=brand&limit=3&offset=2&cursor="+cursor, nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		got, meta := decodePage(t, w.Body.String())
+		require.Equal(t, []int{4}, got)
+		require.Nil(t, meta.NextCursor)
+		require.Equal(t, 0, meta.Offset)
+		s.AssertExpectations(t)
+	})
+
+	t.Run("case error, cursor of another version", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		cursor := base64.RawURLEncoding.EncodeToString([]byte(`{"s": "brand", "a": {"Id": 3, "Brand": "Ford"}}`))
+		// act
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, "/vehicles?sort=brand&cursor="+cursor, nil))
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), `"detail":"invalid cursor"`)
+		s.AssertNotCalled(t, "Page")
+	})
+
+	t.Run("case error, cursor issued for another sort", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		s.On("Page", mock.Anything, internal.VehicleFilter{}, mock.Anything).Return(fleet[1:2], internal.Page{Total: 4, Next: &fleet[1]}, nil)
+
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, "/vehicles?sort=brand&limit=1", nil))
+		_, meta := decodePage(t, w.Body.String())
+
+		// act
+		w = httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, "/vehicles?sort=year&cursor="+*meta.NextCursor, nil))
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid cursor",
+			"instance": "/vehicles"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+	})
+
+	t.Run("case error, unknown sort field", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?sort=price", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid sort field price",
+			"instance": "/vehicles"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertNotCalled(t, "Page")
+	})
+}
+
+// decodePage is a function that decodes the ids and meta of a page from the response body
+func decodePage(t *testing.T, body string) (ids []int, meta handler.PageMetaJSON) {
+	var res struct {
+		Data []handler.VehicleJSON `json:"data"`
+		Meta handler.PageMetaJSON  `json:"meta"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &res))
+	for _, v := range res.Data {
+		ids = append(ids, v.Id)
+	}
+	return ids, res.Meta
+}
+
+func TestHandlerVehicle_Search_View(t *testing.T) {
+	t.Run("case - sparse fieldset and versioned media type", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		s.On("Page", mock.Anything, internal.VehicleFilter{}, internal.PageQuery{}).Return(Vehicles, internal.Page{Total: 1}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?fields=id,brand,passengers", nil)
+		r.Header.Set("Accept", handler.MediaTypeVehicleV1)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "application/vnd.vehicles.v1+json; charset=utf-8", w.Header().Get("Content-Type"))
+		expectBody := `{
+			"message": "vehicles found",
+			"data": [{"id": 1, "brand": "Ford", "passengers": 5}],
+			"meta": {"total": 1, "offset": 0, "next_cursor": null, "units": ` + MetricUnits + `}
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+	})
+
+	t.Run("case error, unknown field", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?fields=id,FabricationYear", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		expectBody := `{
+			"type": "about:blank",
+			"title": "Bad Request",
+			"status": 400,
+			"detail": "invalid field FabricationYear",
+			"instance": "/vehicles"
+		}`
+		require.JSONEq(t, expectBody, w.Body.String())
+		s.AssertNotCalled(t, "Page")
+	})
+
+	t.Run("case error, unsupported media type version", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles", nil)
+		r.Header.Set("Accept", "application/vnd.vehicles.v2+json")
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusNotAcceptable, w.Code)
+		s.AssertNotCalled(t, "Page")
+	})
+}
+
+func TestHandlerVehicle_Search_Format(t *testing.T) {
+	cases := []struct {
+		name              string
+		target            string
+		accept            string
+		expectContentType string
+		expectBody        string
+	}{
+		{"csv - every column, in the loader order", "/vehicles", "text/csv",
+			"text/csv; charset=utf-8",
+			strings.Join(loader.VehicleCSVColumns, ",") + "\n" +
+				"1,Ford,Fiesta,ABC-123,red,2010,5,180,gasoline,manual,1000,1.5,4,1.8\n"},
+		{"csv - sparse fieldset, format parameter over accept", "/vehicles?format=csv&fields=brand,id", "application/json",
+			"text/csv; charset=utf-8",
+			"brand,id\nFord,1\n"},
+		{"ndjson", "/vehicles?fields=id,brand", "application/x-ndjson",
+			"application/x-ndjson",
+			`{"brand":"Ford","id":1}` + "\n"},
+		{"xml", "/vehicles?format=xml&fields=id,max_speed", "",
+			"application/xml; charset=utf-8",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<vehicles><vehicle><id>1</id><max_speed>180</max_speed></vehicle></vehicles>`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// arrange
+			s := service.NewServiceVehicleDefaultMock()
+			hd := handler.NewHandlerVehicle(s)
+			h := hd.Search()
+			s.On("Stream", mock.Anything, mock.Anything).Return(internal.NewVehicleIteratorSlice(Vehicles), nil)
+
+			//request
+			r := httptest.NewRequest(http.MethodGet, c.target, nil)
+			r.Header.Set("Accept", c.accept)
+			w := httptest.NewRecorder()
+			// act
+			h(w, r)
+			// assert
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, c.expectContentType, w.Header().Get("Content-Type"))
+			require.Equal(t, "1", w.Header().Get("X-Total-Count"))
+			require.Equal(t, c.expectBody, w.Body.String())
+			s.AssertNotCalled(t, "Page")
+		})
+	}
+
+	t.Run("case - streamed in id order, the offset skips the first vehicles", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		v := []internal.Vehicle{{Id: 1}, {Id: 2}, {Id: 3}}
+		s.On("Stream", mock.Anything, mock.Anything).Return(internal.NewVehicleIteratorSlice(v), nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?format=csv&fields=id&sort=id&offset=1", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "id\n2\n3\n", w.Body.String())
+		require.Equal(t, "3", w.Header().Get("X-Total-Count"))
+		require.Empty(t, w.Header().Get("X-Next-Cursor"))
+	})
+
+	t.Run("case - other sorts are not streamed", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		s.On("Page", mock.Anything, mock.Anything, mock.Anything).Return([]internal.Vehicle{{Id: 2}, {Id: 1}}, internal.Page{Total: 2}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?format=csv&fields=id&sort=-id", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "id\n2\n1\n", w.Body.String())
+		s.AssertNotCalled(t, "Stream")
+	})
+
+	t.Run("case error, no vehicles to stream", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		s.On("Stream", mock.Anything, mock.Anything).Return(nil, internal.ErrServiceNoVehicles)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?format=ndjson&brand=Nope", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("case error, a stream that fails once sent aborts the response", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		s.On("Stream", mock.Anything, mock.Anything).Return(&failingIterator{VehicleIterator: internal.NewVehicleIteratorSlice(Vehicles), err: errors.New("connection lost")}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?format=csv", nil)
+		w := httptest.NewRecorder()
+		// act & assert
+		require.PanicsWithValue(t, http.ErrAbortHandler, func() { h(w, r) })
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("case - next cursor in a header", func(t *testing.T) {
+		// arrange
+		s := service.NewServiceVehicleDefaultMock()
+		hd := handler.NewHandlerVehicle(s)
+		h := hd.Search()
+		s.On("Page", mock.Anything, mock.Anything, mock.Anything).Return(Vehicles, internal.Page{Total: 2, Next: &Vehicles[0]}, nil)
+
+		//request
+		r := httptest.NewRequest(http.MethodGet, "/vehicles?format=csv&fields=id&limit=1", nil)
+		w := httptest.NewRecorder()
+		// act
+		h(w, r)
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "id\n1\n", w.Body.String())
+		require.Equal(t, "2", w.Header().Get("X-Total-Count"))
+		require.NotEmpty(t, w.Header().Get("X-Next-Cursor"))
+	})
+
+	t.Run("case error, unsupported format", func(t *testing.T) {
+		for target, accept := range map[string]string{
+			"/vehicles?format=yaml": "text/csv",
+			"/vehicles":             "application/yaml",
+		} {
+			// arrange
+			s := service.NewServiceVehicleDefaultMock()
+			hd := handler.NewHandlerVehicle(s)
+			h := hd.Search()
+
+			//request
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			r.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+			// act
+			h(w, r)
+			// assert
+			require.Equal(t, http.StatusNotAcceptable, w.Code, target)
+			require.Contains(t, w.Body.String(), "supported media types: application/vnd.vehicles.v1+json, application/json, text/csv", target)
+			s.AssertNotCalled(t, "Page")
+		}
+	})
+}
+
+// failingIterator is an iteration that fails with err once the vehicles of the wrapped one are read
+type failingIterator struct {
+	internal.VehicleIterator
+	err error
+}
+
+func (it *failingIterator) Err() error {
+	return it.err
 }
@@ -0,0 +1,160 @@
+package loader
+
+import (
+	"app/internal"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLoaderFileChanged is an error that represents a file that changed since it was loaded
+	ErrLoaderFileChanged = errors.New("loader: file changed since it was loaded")
+)
+
+// NewFileWatcher is a function that returns a new instance of FileWatcher
+// - the current state of the file is not known until Sync is called, so the first Changed reports a change
+func NewFileWatcher(path string) *FileWatcher {
+	return &FileWatcher{
+		path: path,
+	}
+}
+
+// FileWatcher is a struct that detects changes of a file by polling
+// - the modification time and size are checked first, the content hash only when they differ,
+// so touching a file without changing it is not a change
+type FileWatcher struct {
+	// path is the path to the watched file
+	path string
+	// mu is the mutex that guards the known state
+	mu sync.Mutex
+	// known is the last state of the file recorded by Sync
+	known fileState
+}
+
+// fileState is a struct that represents the state of a file
+type fileState struct {
+	// modTime is the modification time of the file
+	modTime time.Time
+	// size is the size of the file
+	size int64
+	// hash is the SHA-256 of the content of the file
+	hash [sha256.Size]byte
+}
+
+// Changed is a method that returns true if the file is not the one recorded by the last Sync
+func (w *FileWatcher) Changed() (changed bool, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.changed()
+}
+
+// Sync is a method that records the current state of the file as known
+func (w *FileWatcher) Sync() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.sync()
+}
+
+// Write is a method that runs write only if the file did not change since the last Sync, and records the result as known
+// - returns ErrLoaderFileChanged otherwise, so a file replaced by someone else is not overwritten before it is loaded
+// - Changed waits for write to finish, so the file written is never reported as a change
+func (w *FileWatcher) Write(write func() error) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	changed, err := w.changed()
+	if err != nil {
+		return
+	}
+	if changed {
+		err = ErrLoaderFileChanged
+		return
+	}
+	err = write()
+	if err != nil {
+		return
+	}
+	err = w.sync()
+	return
+}
+
+// changed is a method that returns true if the file is not the one recorded by the last sync
+// - the caller must hold the lock
+func (w *FileWatcher) changed() (changed bool, err error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return
+	}
+	if info.ModTime().Equal(w.known.modTime) && info.Size() == w.known.size {
+		return
+	}
+
+	hash, err := hashFile(w.path)
+	if err != nil {
+		return
+	}
+	if hash == w.known.hash {
+		// same content: remember the new modification time so it is not hashed again
+		w.known.modTime, w.known.size = info.ModTime(), info.Size()
+		return
+	}
+	changed = true
+	return
+}
+
+// sync is a method that records the current state of the file as known
+// - the caller must hold the lock
+func (w *FileWatcher) sync() (err error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return
+	}
+	hash, err := hashFile(w.path)
+	if err != nil {
+		return
+	}
+	w.known = fileState{modTime: info.ModTime(), size: info.Size(), hash: hash}
+	return
+}
+
+// NewStorerVehicleWatched is a function that returns a new instance of StorerVehicleWatched
+func NewStorerVehicleWatched(st internal.StorerVehicle, w *FileWatcher) *StorerVehicleWatched {
+	return &StorerVehicleWatched{st: st, w: w}
+}
+
+// StorerVehicleWatched is a struct that stores the vehicles only if the watched file did not change since it was loaded
+type StorerVehicleWatched struct {
+	// st is the storer that writes the watched file
+	st internal.StorerVehicle
+	// w is the watcher of the file
+	w *FileWatcher
+}
+
+// Store is a method that stores the vehicles (see FileWatcher.Write)
+func (s *StorerVehicleWatched) Store(v map[int]internal.Vehicle) (err error) {
+	err = s.w.Write(func() error { return s.st.Store(v) })
+	return
+}
+
+// hashFile is a function that returns the SHA-256 of the content of a file
+func hashFile(path string) (hash [sha256.Size]byte, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return
+	}
+	copy(hash[:], h.Sum(nil))
+	return
+}
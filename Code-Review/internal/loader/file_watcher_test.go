@@ -0,0 +1,87 @@
+package loader_test
+
+import (
+	"app/internal/loader"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWatcher_Changed(t *testing.T) {
+	t.Run("unknown file is a change", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[]`), 0644))
+		w := loader.NewFileWatcher(path)
+		// act
+		changed, err := w.Changed()
+		// assert
+		require.NoError(t, err)
+		require.True(t, changed)
+	})
+
+	t.Run("touched file with the same content is not a change", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[]`), 0644))
+		w := loader.NewFileWatcher(path)
+		require.NoError(t, w.Sync())
+		later := time.Now().Add(time.Hour)
+		require.NoError(t, os.Chtimes(path, later, later))
+		// act
+		changed, err := w.Changed()
+		// assert
+		require.NoError(t, err)
+		require.False(t, changed)
+	})
+
+	t.Run("new content is a change", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[]`), 0644))
+		w := loader.NewFileWatcher(path)
+		require.NoError(t, w.Sync())
+		require.NoError(t, os.WriteFile(path, []byte(`[{"id": 1}]`), 0644))
+		// act
+		changed, err := w.Changed()
+		// assert
+		require.NoError(t, err)
+		require.True(t, changed)
+	})
+}
+
+func TestFileWatcher_Write(t *testing.T) {
+	t.Run("written file is not a change", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[]`), 0644))
+		w := loader.NewFileWatcher(path)
+		require.NoError(t, w.Sync())
+		// act
+		err := w.Write(func() error { return os.WriteFile(path, []byte(`[{"id": 1}]`), 0644) })
+		// assert
+		require.NoError(t, err)
+		changed, err := w.Changed()
+		require.NoError(t, err)
+		require.False(t, changed)
+	})
+
+	t.Run("changed file is not overwritten", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[]`), 0644))
+		w := loader.NewFileWatcher(path)
+		require.NoError(t, w.Sync())
+		require.NoError(t, os.WriteFile(path, []byte(`[{"id": 2}]`), 0644))
+		// act
+		err := w.Write(func() error { return errors.New("must not be called") })
+		// assert
+		require.ErrorIs(t, err, loader.ErrLoaderFileChanged)
+		b, _ := os.ReadFile(path)
+		require.Equal(t, `[{"id": 2}]`, string(b))
+	})
+}
@@ -0,0 +1,150 @@
+package loader
+
+import (
+	"app/internal"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrLoaderUnknownFormat is an error that represents a file format without loader
+	ErrLoaderUnknownFormat = errors.New("loader: unknown format")
+)
+
+// NewLoaderVehicleFile is a function that returns the loader for the given file format
+// - format: json, csv or ndjson. If empty, it is taken from the file extension (.json, .csv, .ndjson or .jsonl)
+func NewLoaderVehicleFile(path string, format string) (ld internal.LoaderStorerVehicle, err error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		ld = NewLoaderVehicleJSON(path)
+	case "csv":
+		ld = NewLoaderVehicleCSV(path)
+	case "ndjson", "jsonl":
+		ld = NewLoaderVehicleNDJSON(path)
+	default:
+		err = fmt.Errorf("%w: %q", ErrLoaderUnknownFormat, format)
+	}
+	return
+}
+
+// vehicleFromJSON is a function that maps a VehicleJSON to a vehicle
+func vehicleFromJSON(vh VehicleJSON) internal.Vehicle {
+	return internal.Vehicle{
+		Id: vh.Id,
+		VehicleAttributes: internal.VehicleAttributes{
+			Brand:           vh.Brand,
+			Model:           vh.Model,
+			Registration:    vh.Registration,
+			Color:           vh.Color,
+			FabricationYear: vh.FabricationYear,
+			Capacity:        vh.Capacity,
+			MaxSpeed:        vh.MaxSpeed,
+			FuelType:        vh.FuelType,
+			Transmission:    vh.Transmission,
+			Weight:          vh.Weight,
+			Dimensions: internal.Dimensions{
+				Height: vh.Height,
+				Length: vh.Length,
+				Width:  vh.Width,
+			},
+		},
+	}
+}
+
+// vehiclesToMap is a function that indexes the records by id
+// - a duplicated id replaces the previous record (see internal.VehicleValidator to report it)
+func vehiclesToMap(records []internal.Vehicle) map[int]internal.Vehicle {
+	v := make(map[int]internal.Vehicle, len(records))
+	for _, vh := range records {
+		v[vh.Id] = vh
+	}
+	return v
+}
+
+// vehiclesToRecords is a function that returns the vehicles as a list of records ordered by id
+func vehiclesToRecords(v map[int]internal.Vehicle) []internal.Vehicle {
+	records := make([]internal.Vehicle, 0, len(v))
+	for _, vh := range v {
+		records = append(records, vh)
+	}
+	slices.SortFunc(records, func(a, b internal.Vehicle) int { return cmp.Compare(a.Id, b.Id) })
+	return records
+}
+
+// vehiclesToJSON is a function that maps the records to a list of VehicleJSON, in the same order
+func vehiclesToJSON(v []internal.Vehicle) []VehicleJSON {
+	vehiclesJSON := make([]VehicleJSON, 0, len(v))
+	for _, vh := range v {
+		vehiclesJSON = append(vehiclesJSON, VehicleJSON{
+			Id:              vh.Id,
+			Brand:           vh.Brand,
+			Model:           vh.Model,
+			Registration:    vh.Registration,
+			Color:           vh.Color,
+			FabricationYear: vh.FabricationYear,
+			Capacity:        vh.Capacity,
+			MaxSpeed:        vh.MaxSpeed,
+			FuelType:        vh.FuelType,
+			Transmission:    vh.Transmission,
+			Weight:          vh.Weight,
+			Height:          vh.Height,
+			Length:          vh.Length,
+			Width:           vh.Width,
+		})
+	}
+	return vehiclesJSON
+}
+
+// writeFileAtomic is a function that replaces the file at path with the content written by write
+// - the content is written to a temporary file in the same directory, synced and then renamed over the original,
+// so a crash never leaves a partially written file
+func writeFileAtomic(path string, write func(w io.Writer) error) (err error) {
+	// write temporary file
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return
+	}
+
+	// keep the permissions of the original file
+	if info, errStat := os.Stat(path); errStat == nil {
+		if err = os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+			return
+		}
+	}
+
+	// replace original file
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return
+	}
+
+	// sync directory so the rename is durable (not supported on every platform)
+	if d, errOpen := os.Open(dir); errOpen == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+
+	return
+}
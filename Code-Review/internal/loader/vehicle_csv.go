@@ -0,0 +1,177 @@
+package loader
+
+import (
+	"app/internal"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NewLoaderVehicleCSV is a function that returns a new instance of LoaderVehicleCSV
+func NewLoaderVehicleCSV(path string) *LoaderVehicleCSV {
+	return &LoaderVehicleCSV{
+		path: path,
+	}
+}
+
+// LoaderVehicleCSV is a struct that implements the LoaderStorerVehicle interface
+// - the first row is a header with the same field names as VehicleJSON, in any order
+// - unknown columns are ignored, missing columns and empty cells are zero values
+type LoaderVehicleCSV struct {
+	// path is the path to the file that contains the vehicles in CSV format
+	path string
+}
+
+// VehicleCSVColumns is the list of columns of the CSV format, in the order used by Store
+var VehicleCSVColumns = []string{
+	"id", "brand", "model", "registration", "color", "year", "passengers",
+	"max_speed", "fuel_type", "transmission", "weight", "height", "length", "width",
+}
+
+// Load is a method that loads the vehicles
+func (l *LoaderVehicleCSV) Load() (v map[int]internal.Vehicle, err error) {
+	records, err := l.LoadRecords()
+	if err != nil {
+		return
+	}
+	v = vehiclesToMap(records)
+	return
+}
+
+// LoadRecords is a method that loads every row of the file, in file order
+func (l *LoaderVehicleCSV) LoadRecords() (v []internal.Vehicle, err error) {
+	// open file
+	file, err := os.Open(l.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	// decode header
+	rd := csv.NewReader(file)
+	rd.FieldsPerRecord = -1
+	rd.TrimLeadingSpace = true
+	header, err := rd.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("loader: csv header is missing")
+		}
+		return
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	// decode rows
+	v = make([]internal.Vehicle, 0)
+	for line := 2; ; line++ {
+		row, errRead := rd.Read()
+		if errRead == io.EOF {
+			break
+		}
+		if errRead != nil {
+			v, err = nil, errRead
+			return
+		}
+
+		var vh VehicleJSON
+		for i, cell := range row {
+			if i >= len(columns) {
+				break
+			}
+			if err = setVehicleCSVField(&vh, columns[i], strings.TrimSpace(cell)); err != nil {
+				v, err = nil, fmt.Errorf("loader: csv line %d: invalid %s: %w", line, columns[i], err)
+				return
+			}
+		}
+		v = append(v, vehicleFromJSON(vh))
+	}
+
+	return
+}
+
+// Store is a method that stores the vehicles in the same CSV format read by Load, ordered by id
+func (l *LoaderVehicleCSV) Store(v map[int]internal.Vehicle) (err error) {
+	err = l.StoreRecords(vehiclesToRecords(v))
+	return
+}
+
+// StoreRecords is a method that stores the records in the same CSV format read by Load
+// - the file is replaced atomically (see writeFileAtomic)
+func (l *LoaderVehicleCSV) StoreRecords(v []internal.Vehicle) (err error) {
+	err = writeFileAtomic(l.path, func(w io.Writer) error {
+		return WriteVehiclesCSV(w, vehiclesToJSON(v))
+	})
+	return
+}
+
+// WriteVehiclesCSV is a function that writes the vehicles as CSV with a header row of VehicleCSVColumns
+func WriteVehiclesCSV(w io.Writer, v []VehicleJSON) (err error) {
+	cw := csv.NewWriter(w)
+	if err = cw.Write(VehicleCSVColumns); err != nil {
+		return
+	}
+	for _, vh := range v {
+		if err = cw.Write(VehicleCSVRecord(vh)); err != nil {
+			return
+		}
+	}
+	cw.Flush()
+	err = cw.Error()
+	return
+}
+
+// VehicleCSVRecord is a function that returns the CSV record of a vehicle, in the order of VehicleCSVColumns
+func VehicleCSVRecord(vh VehicleJSON) []string {
+	float := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	return []string{
+		strconv.Itoa(vh.Id), vh.Brand, vh.Model, vh.Registration, vh.Color,
+		strconv.Itoa(vh.FabricationYear), strconv.Itoa(vh.Capacity), float(vh.MaxSpeed),
+		vh.FuelType, vh.Transmission, float(vh.Weight), float(vh.Height), float(vh.Length), float(vh.Width),
+	}
+}
+
+// setVehicleCSVField is a function that sets the field of the vehicle named by the column
+// - empty cells and unknown columns are ignored
+func setVehicleCSVField(vh *VehicleJSON, column string, cell string) (err error) {
+	if cell == "" {
+		return
+	}
+
+	switch column {
+	case "id":
+		vh.Id, err = strconv.Atoi(cell)
+	case "brand":
+		vh.Brand = cell
+	case "model":
+		vh.Model = cell
+	case "registration":
+		vh.Registration = cell
+	case "color":
+		vh.Color = cell
+	case "year":
+		vh.FabricationYear, err = strconv.Atoi(cell)
+	case "passengers":
+		vh.Capacity, err = strconv.Atoi(cell)
+	case "max_speed":
+		vh.MaxSpeed, err = strconv.ParseFloat(cell, 64)
+	case "fuel_type":
+		vh.FuelType = cell
+	case "transmission":
+		vh.Transmission = cell
+	case "weight":
+		vh.Weight, err = strconv.ParseFloat(cell, 64)
+	case "height":
+		vh.Height, err = strconv.ParseFloat(cell, 64)
+	case "length":
+		vh.Length, err = strconv.ParseFloat(cell, 64)
+	case "width":
+		vh.Width, err = strconv.ParseFloat(cell, 64)
+	}
+	return
+}
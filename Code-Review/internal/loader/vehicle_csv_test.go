@@ -0,0 +1,96 @@
+package loader_test
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderVehicleCSV_Load(t *testing.T) {
+	t.Run("success - header in any order, unknown and missing columns", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.csv")
+		content := "brand,id,year,passengers,max_speed,notes\n" +
+			"Ford,1,2010,5,180.5,imported\n" +
+			"\"Hummer, Inc\",2,2008,,143,\n"
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		ld := loader.NewLoaderVehicleCSV(path)
+		// act
+		v, err := ld.Load()
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, map[int]internal.Vehicle{
+			1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", FabricationYear: 2010, Capacity: 5, MaxSpeed: 180.5}},
+			2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Hummer, Inc", FabricationYear: 2008, MaxSpeed: 143}},
+		}, v)
+	})
+
+	t.Run("error - invalid number", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.csv")
+		require.NoError(t, os.WriteFile(path, []byte("id,year\n1,2010\n2,abc\n"), 0644))
+		ld := loader.NewLoaderVehicleCSV(path)
+		// act
+		v, err := ld.Load()
+		// assert
+		require.Nil(t, v)
+		require.ErrorContains(t, err, "loader: csv line 3: invalid year")
+	})
+
+	t.Run("error - empty file", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.csv")
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+		ld := loader.NewLoaderVehicleCSV(path)
+		// act
+		_, err := ld.Load()
+		// assert
+		require.EqualError(t, err, "loader: csv header is missing")
+	})
+}
+
+func TestLoaderVehicleFile_RoundTrip(t *testing.T) {
+	// vehicles is the dataset stored and loaded back in every format
+	vehicles := map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{
+			Brand: "Ford", Model: "Fiesta", Registration: "ABC-123", Color: "red", FabricationYear: 2010, Capacity: 5,
+			MaxSpeed: 180, FuelType: "gasoline", Transmission: "manual", Weight: 1000.25,
+			Dimensions: internal.Dimensions{Height: 1.5, Length: 4, Width: 1.8},
+		}},
+		7: {Id: 7, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", Registration: "XYZ-789"}},
+	}
+
+	for _, name := range []string{"vehicles.json", "vehicles.csv", "vehicles.ndjson", "vehicles.jsonl"} {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			path := filepath.Join(t.TempDir(), name)
+			ld, err := loader.NewLoaderVehicleFile(path, "")
+			require.NoError(t, err)
+			// act
+			require.NoError(t, ld.Store(vehicles))
+			v, err := ld.Load()
+			// assert
+			require.NoError(t, err)
+			require.Equal(t, vehicles, v)
+		})
+	}
+
+	t.Run("error - unknown format", func(t *testing.T) {
+		// act
+		_, err := loader.NewLoaderVehicleFile("vehicles.xml", "")
+		// assert
+		require.ErrorIs(t, err, loader.ErrLoaderUnknownFormat)
+	})
+
+	t.Run("explicit format overrides the extension", func(t *testing.T) {
+		// act
+		ld, err := loader.NewLoaderVehicleFile("vehicles.txt", "csv")
+		// assert
+		require.NoError(t, err)
+		require.IsType(t, &loader.LoaderVehicleCSV{}, ld)
+	})
+}
@@ -2,7 +2,9 @@ package loader
 
 import (
 	"app/internal"
+	"bufio"
 	"encoding/json"
+	"io"
 	"os"
 )
 
@@ -13,32 +15,43 @@ func NewLoaderVehicleJSON(path string) *LoaderVehicleJSON {
 	}
 }
 
-// LoaderVehicleJSON is a struct that implements the LoaderVehicle interface
+// LoaderVehicleJSON is a struct that implements the LoaderStorerVehicle interface
 type LoaderVehicleJSON struct {
 	// path is the path to the file that contains the vehicles in JSON format
 	path string
 }
 
 // VehicleJSON is a struct that represents a vehicle in JSON format
+// - the fields are in the order of the data files, which have no length, so a stored file keeps their layout
 type VehicleJSON struct {
 	Id              int     `json:"id"`
 	Brand           string  `json:"brand"`
 	Model           string  `json:"model"`
 	Registration    string  `json:"registration"`
-	Color           string  `json:"color"`
 	FabricationYear int     `json:"year"`
-	Capacity        int     `json:"passengers"`
+	Color           string  `json:"color"`
 	MaxSpeed        float64 `json:"max_speed"`
 	FuelType        string  `json:"fuel_type"`
 	Transmission    string  `json:"transmission"`
-	Weight          float64 `json:"weight"`
+	Capacity        int     `json:"passengers"`
 	Height          float64 `json:"height"`
-	Length          float64 `json:"length"`
+	Length          float64 `json:"length,omitempty"`
 	Width           float64 `json:"width"`
+	Weight          float64 `json:"weight"`
 }
 
 // Load is a method that loads the vehicles
 func (l *LoaderVehicleJSON) Load() (v map[int]internal.Vehicle, err error) {
+	records, err := l.LoadRecords()
+	if err != nil {
+		return
+	}
+	v = vehiclesToMap(records)
+	return
+}
+
+// LoadRecords is a method that loads every record of the file, in file order
+func (l *LoaderVehicleJSON) LoadRecords() (v []internal.Vehicle, err error) {
 	// open file
 	file, err := os.Open(l.path)
 	if err != nil {
@@ -54,29 +67,39 @@ func (l *LoaderVehicleJSON) Load() (v map[int]internal.Vehicle, err error) {
 	}
 
 	// serialize vehicles
-	v = make(map[int]internal.Vehicle)
+	v = make([]internal.Vehicle, 0, len(vehiclesJSON))
 	for _, vh := range vehiclesJSON {
-		v[vh.Id] = internal.Vehicle{
-			Id: vh.Id,
-			VehicleAttributes: internal.VehicleAttributes{
-				Brand:           vh.Brand,
-				Model:           vh.Model,
-				Registration:    vh.Registration,
-				Color:           vh.Color,
-				FabricationYear: vh.FabricationYear,
-				Capacity:        vh.Capacity,
-				MaxSpeed:        vh.MaxSpeed,
-				FuelType:        vh.FuelType,
-				Transmission:    vh.Transmission,
-				Weight:          vh.Weight,
-				Dimensions: internal.Dimensions{
-					Height: vh.Height,
-					Length: vh.Length,
-					Width:  vh.Width,
-				},
-			},
-		}
+		v = append(v, vehicleFromJSON(vh))
 	}
 
 	return
 }
+
+// Store is a method that stores the vehicles in the same JSON format read by Load, ordered by id
+func (l *LoaderVehicleJSON) Store(v map[int]internal.Vehicle) (err error) {
+	err = l.StoreRecords(vehiclesToRecords(v))
+	return
+}
+
+// StoreRecords is a method that stores the records in the same JSON format read by Load
+// - one vehicle per line (CRLF), as the data files are laid out, so a stored file diffs by vehicle
+// - the file is replaced atomically (see writeFileAtomic)
+func (l *LoaderVehicleJSON) StoreRecords(v []internal.Vehicle) (err error) {
+	err = writeFileAtomic(l.path, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		bw.WriteByte('[')
+		for i, vh := range vehiclesToJSON(v) {
+			if i > 0 {
+				bw.WriteString(",\r\n")
+			}
+			b, err := json.Marshal(vh)
+			if err != nil {
+				return err
+			}
+			bw.Write(b)
+		}
+		bw.WriteByte(']')
+		return bw.Flush()
+	})
+	return
+}
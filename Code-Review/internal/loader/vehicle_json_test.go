@@ -0,0 +1,37 @@
+package loader_test
+
+import (
+	"app/internal/loader"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderVehicleJSON_Store(t *testing.T) {
+	// arrange
+	original, err := os.ReadFile("../../docs/db/vehicles_100.json")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "vehicles.json")
+	require.NoError(t, os.WriteFile(path, original, 0644))
+	ld := loader.NewLoaderVehicleJSON(path)
+	v, err := ld.Load()
+	require.NoError(t, err)
+	// records is a function that returns the records of a data file, one per line
+	records := func(b []byte) []string {
+		return strings.Split(strings.TrimSuffix(strings.TrimPrefix(string(b), "["), "]"), ",\r\n")
+	}
+	// act
+	err = ld.Store(v)
+	// assert
+	require.NoError(t, err)
+	stored, err := os.ReadFile(path)
+	require.NoError(t, err)
+	expected, actual := records(original), records(stored)
+	require.Len(t, actual, len(expected), "one vehicle per line")
+	for i := range expected {
+		require.JSONEq(t, expected[i], actual[i])
+	}
+}
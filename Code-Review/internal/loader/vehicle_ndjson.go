@@ -0,0 +1,91 @@
+package loader
+
+import (
+	"app/internal"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// NewLoaderVehicleNDJSON is a function that returns a new instance of LoaderVehicleNDJSON
+func NewLoaderVehicleNDJSON(path string) *LoaderVehicleNDJSON {
+	return &LoaderVehicleNDJSON{
+		path: path,
+	}
+}
+
+// LoaderVehicleNDJSON is a struct that implements the LoaderStorerVehicle interface
+// - one VehicleJSON document per line (newline-delimited JSON), blank lines are skipped
+type LoaderVehicleNDJSON struct {
+	// path is the path to the file that contains the vehicles in NDJSON format
+	path string
+}
+
+// Load is a method that loads the vehicles
+func (l *LoaderVehicleNDJSON) Load() (v map[int]internal.Vehicle, err error) {
+	records, err := l.LoadRecords()
+	if err != nil {
+		return
+	}
+	v = vehiclesToMap(records)
+	return
+}
+
+// LoadRecords is a method that loads every line of the file, in file order
+func (l *LoaderVehicleNDJSON) LoadRecords() (v []internal.Vehicle, err error) {
+	// open file
+	file, err := os.Open(l.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	// decode file, line by line
+	v = make([]internal.Vehicle, 0)
+	sc := bufio.NewScanner(file)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+
+		var vh VehicleJSON
+		if err = json.Unmarshal(sc.Bytes(), &vh); err != nil {
+			v = nil
+			err = fmt.Errorf("loader: ndjson line %d: %w", line, err)
+			return
+		}
+		v = append(v, vehicleFromJSON(vh))
+	}
+	if err = sc.Err(); err != nil {
+		v = nil
+		return
+	}
+
+	return
+}
+
+// Store is a method that stores the vehicles in the same NDJSON format read by Load, ordered by id
+func (l *LoaderVehicleNDJSON) Store(v map[int]internal.Vehicle) (err error) {
+	err = l.StoreRecords(vehiclesToRecords(v))
+	return
+}
+
+// StoreRecords is a method that stores the records in the same NDJSON format read by Load
+// - the file is replaced atomically (see writeFileAtomic)
+func (l *LoaderVehicleNDJSON) StoreRecords(v []internal.Vehicle) (err error) {
+	err = writeFileAtomic(l.path, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		for _, vh := range vehiclesToJSON(v) {
+			if err := enc.Encode(vh); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	})
+	return
+}
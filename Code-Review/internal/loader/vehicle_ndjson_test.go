@@ -0,0 +1,39 @@
+package loader_test
+
+import (
+	"app/internal/loader"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderVehicleNDJSON_Load(t *testing.T) {
+	t.Run("success - blank lines are skipped", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.ndjson")
+		content := `{"id":1,"brand":"Ford","passengers":5}` + "\n\n" + `{"id":2,"brand":"GMC","year":1997}` + "\n"
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		ld := loader.NewLoaderVehicleNDJSON(path)
+		// act
+		v, err := ld.Load()
+		// assert
+		require.NoError(t, err)
+		require.Len(t, v, 2)
+		require.Equal(t, 5, v[1].Capacity)
+		require.Equal(t, 1997, v[2].FabricationYear)
+	})
+
+	t.Run("error - invalid line", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.ndjson")
+		require.NoError(t, os.WriteFile(path, []byte(`{"id":1}`+"\n"+`{"id":`+"\n"), 0644))
+		ld := loader.NewLoaderVehicleNDJSON(path)
+		// act
+		v, err := ld.Load()
+		// assert
+		require.Nil(t, v)
+		require.ErrorContains(t, err, "loader: ndjson line 2")
+	})
+}
@@ -0,0 +1,108 @@
+package loader
+
+import (
+	"app/internal"
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// NewLoaderVehicleValidated is a function that returns a new instance of LoaderVehicleValidated
+// - strict: any record with an error fails the load. Otherwise invalid records are skipped
+func NewLoaderVehicleValidated(ld internal.LoaderStorerVehicle, vd *internal.VehicleValidator, strict bool) *LoaderVehicleValidated {
+	return &LoaderVehicleValidated{
+		LoaderStorerVehicle: ld,
+		vd:                  vd,
+		strict:              strict,
+	}
+}
+
+// LoaderVehicleValidated is a struct that validates the records of the wrapped loader
+// - Store is served by the wrapped loader, with the records skipped by the last load written back as they were read
+type LoaderVehicleValidated struct {
+	// LoaderStorerVehicle is the loader that reads the records
+	internal.LoaderStorerVehicle
+	// vd is the validator of the records
+	vd *internal.VehicleValidator
+	// strict is true if a record with errors fails the load
+	strict bool
+	// mu is the mutex that guards the last report and skipped records
+	mu sync.Mutex
+	// report is the report of the last load
+	report internal.ValidationReport
+	// skipped are the records skipped by the last load, in source order
+	skipped []internal.Vehicle
+}
+
+// Load is a method that loads the valid vehicles
+func (l *LoaderVehicleValidated) Load() (v map[int]internal.Vehicle, err error) {
+	records, err := l.LoadRecords()
+	if err != nil {
+		return
+	}
+	v = vehiclesToMap(records)
+	return
+}
+
+// LoadRecords is a method that loads the valid records, in source order
+// - in strict mode an *internal.ValidationReportError is returned if any record has errors
+func (l *LoaderVehicleValidated) LoadRecords() (v []internal.Vehicle, err error) {
+	records, err := l.LoaderStorerVehicle.LoadRecords()
+	if err != nil {
+		l.mu.Lock()
+		l.report, l.skipped = internal.ValidationReport{}, nil
+		l.mu.Unlock()
+		return
+	}
+
+	v, report := l.vd.Validate(records)
+	var skipped []internal.Vehicle
+	last := -1
+	for _, p := range report.Problems {
+		// - the problems are in record order: a record with several errors is skipped once
+		if p.Severity == internal.ValidationError && p.Index != last {
+			skipped, last = append(skipped, records[p.Index]), p.Index
+		}
+	}
+	l.mu.Lock()
+	l.report, l.skipped = report, skipped
+	l.mu.Unlock()
+
+	if l.strict && report.Errors() > 0 {
+		v, err = nil, &internal.ValidationReportError{Report: report}
+		return
+	}
+	return
+}
+
+// Store is a method that stores the vehicles with the wrapped loader
+// - the vehicles of a file loaded with skipped records do not have them, so the skipped records are stored too, as they were read:
+// the file keeps them until it is fixed, and they are skipped again on the next load
+// - records are ordered by id, the vehicles before the skipped records of the same id (the first record of an id wins on load)
+func (l *LoaderVehicleValidated) Store(v map[int]internal.Vehicle) (err error) {
+	l.mu.Lock()
+	skipped := l.skipped
+	l.mu.Unlock()
+	if len(skipped) == 0 {
+		err = l.LoaderStorerVehicle.Store(v)
+		return
+	}
+
+	records := make([]internal.Vehicle, 0, len(v)+len(skipped))
+	for _, vh := range v {
+		records = append(records, vh)
+	}
+	slices.SortFunc(records, func(a, b internal.Vehicle) int { return cmp.Compare(a.Id, b.Id) })
+	records = append(records, skipped...)
+	slices.SortStableFunc(records, func(a, b internal.Vehicle) int { return cmp.Compare(a.Id, b.Id) })
+	err = l.LoaderStorerVehicle.StoreRecords(records)
+	return
+}
+
+// Report is a method that returns the validation report of the last load (empty if the records could not be read)
+func (l *LoaderVehicleValidated) Report() internal.ValidationReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.report
+}
@@ -0,0 +1,129 @@
+package loader_test
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newValidatedLoader writes the NDJSON content to a temporary file and returns a validated loader for it
+func newValidatedLoader(t *testing.T, content string, strict bool) *loader.LoaderVehicleValidated {
+	path := filepath.Join(t.TempDir(), "vehicles.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	vd := internal.NewVehicleValidatorDefault()
+	vd.MaxYear = 2024
+	return loader.NewLoaderVehicleValidated(loader.NewLoaderVehicleNDJSON(path), vd, strict)
+}
+
+const validatedContent = `{"id":1,"registration":"A","year":2000,"fuel_type":"gas","transmission":"manual","weight":1,"height":1,"length":1,"width":1}
+{"id":1,"registration":"B","year":2000,"fuel_type":"gas","transmission":"manual","weight":1,"height":1,"length":1,"width":1}
+{"id":2,"registration":"","year":2030,"fuel_type":"steam","transmission":"manual","weight":-1,"height":1,"length":1,"width":1}
+{"id":3,"registration":"C","year":2000,"fuel_type":"Diesel","transmission":"automatic","weight":1,"height":1,"width":1}
+`
+
+func TestLoaderVehicleValidated_Load(t *testing.T) {
+	t.Run("lenient - invalid records are skipped and reported", func(t *testing.T) {
+		// arrange
+		ld := newValidatedLoader(t, validatedContent, false)
+		// act
+		v, err := ld.Load()
+		// assert
+		require.NoError(t, err)
+		require.Len(t, v, 2)
+		require.Equal(t, "A", v[1].Registration)
+		require.Contains(t, v, 3)
+		report := ld.Report()
+		require.Equal(t, 4, report.Records)
+		require.Equal(t, 2, report.Skipped)
+		require.Equal(t, 5, report.Errors())
+		expectedProblems := []internal.ValidationProblem{
+			{Index: 1, Id: 1, Field: "id", Message: "duplicated, first seen in record 0", Severity: internal.ValidationError},
+			{Index: 2, Id: 2, Field: "registration", Message: "is empty", Severity: internal.ValidationError},
+			{Index: 2, Id: 2, Field: "year", Message: "2030 is out of range [1886, 2024]", Severity: internal.ValidationError},
+			{Index: 2, Id: 2, Field: "weight", Message: "-1 is not positive", Severity: internal.ValidationError},
+			{Index: 2, Id: 2, Field: "fuel_type", Message: `"steam" is unknown`, Severity: internal.ValidationError},
+			{Index: 3, Id: 3, Field: "length", Message: "is missing or zero", Severity: internal.ValidationWarning},
+		}
+		require.Equal(t, expectedProblems, report.Problems)
+	})
+
+	t.Run("lenient - the skipped records are stored back as they were read", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "vehicles.ndjson")
+		require.NoError(t, os.WriteFile(path, []byte(validatedContent), 0644))
+		vd := internal.NewVehicleValidatorDefault()
+		vd.MaxYear = 2024
+		ld := loader.NewLoaderVehicleValidated(loader.NewLoaderVehicleNDJSON(path), vd, false)
+		v, err := ld.Load()
+		require.NoError(t, err)
+		v[4] = internal.Vehicle{Id: 4, VehicleAttributes: v[3].VehicleAttributes}
+		delete(v, 3)
+		// act
+		err = ld.Store(v)
+		// assert
+		require.NoError(t, err)
+		records, err := loader.NewLoaderVehicleNDJSON(path).LoadRecords()
+		require.NoError(t, err)
+		ids := make([]int, 0, len(records))
+		for _, r := range records {
+			ids = append(ids, r.Id)
+		}
+		require.Equal(t, []int{1, 1, 2, 4}, ids)
+		require.Equal(t, "A", records[0].Registration)
+		require.Equal(t, "B", records[1].Registration)
+		require.Equal(t, "steam", records[2].FuelType)
+		_, err = ld.Load()
+		require.NoError(t, err)
+		require.Equal(t, 2, ld.Report().Skipped)
+	})
+
+	t.Run("strict - any error fails the load", func(t *testing.T) {
+		// arrange
+		ld := newValidatedLoader(t, validatedContent, true)
+		// act
+		v, err := ld.Load()
+		// assert
+		require.Nil(t, v)
+		require.ErrorIs(t, err, internal.ErrValidationFailed)
+		var reportErr *internal.ValidationReportError
+		require.ErrorAs(t, err, &reportErr)
+		require.Equal(t, 2, reportErr.Report.Skipped)
+		require.ErrorContains(t, err, "first error: record 1 (id 1): id: duplicated")
+	})
+
+	t.Run("strict - warnings do not fail the load", func(t *testing.T) {
+		// arrange
+		ld := newValidatedLoader(t, `{"id":3,"registration":"C","year":2000,"fuel_type":"gas","transmission":"manual","weight":1,"height":1,"width":1}`, true)
+		// act
+		v, err := ld.Load()
+		// assert
+		require.NoError(t, err)
+		require.Len(t, v, 1)
+		require.Len(t, ld.Report().Problems, 1)
+	})
+
+	t.Run("default - the max year is the current year when validating", func(t *testing.T) {
+		// arrange
+		year := time.Now().Year()
+		path := filepath.Join(t.TempDir(), "vehicles.ndjson")
+		content := fmt.Sprintf(`{"id":1,"registration":"A","year":%d,"fuel_type":"gas","transmission":"manual","weight":1,"height":1,"length":1,"width":1}
+{"id":2,"registration":"B","year":%d,"fuel_type":"gas","transmission":"manual","weight":1,"height":1,"length":1,"width":1}`, year, year+1)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		ld := loader.NewLoaderVehicleValidated(loader.NewLoaderVehicleNDJSON(path), internal.NewVehicleValidatorDefault(), false)
+		// act
+		v, err := ld.Load()
+		// assert
+		require.NoError(t, err)
+		require.Len(t, v, 1)
+		require.Contains(t, v, 1)
+		require.Equal(t, []internal.ValidationProblem{
+			{Index: 1, Id: 2, Field: "year", Message: fmt.Sprintf("%d is out of range [1886, %d]", year+1, year), Severity: internal.ValidationError},
+		}, ld.Report().Problems)
+	})
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"app/internal"
+	"context"
+	"sync/atomic"
+)
+
+// NewRepositoryVehicleAtomic is a function that returns a new instance of RepositoryVehicleAtomic
+func NewRepositoryVehicleAtomic(rp internal.RepositoryVehicle) *RepositoryVehicleAtomic {
+	r := &RepositoryVehicleAtomic{}
+	r.rp.Store(&rp)
+	return r
+}
+
+// RepositoryVehicleAtomic is a struct that represents a vehicle repository that can be replaced while in use
+// - every call is served by the repository that was current when it started, so a swap never splits a call
+type RepositoryVehicleAtomic struct {
+	// rp is the current repository
+	rp atomic.Pointer[internal.RepositoryVehicle]
+}
+
+// Swap is a method that replaces the current repository and returns the previous one
+func (r *RepositoryVehicleAtomic) Swap(rp internal.RepositoryVehicle) (old internal.RepositoryVehicle) {
+	return *r.rp.Swap(&rp)
+}
+
+// current is a method that returns the current repository
+func (r *RepositoryVehicleAtomic) current() internal.RepositoryVehicle {
+	return *r.rp.Load()
+}
+
+// FindAll is a method that returns a list of all vehicles
+func (r *RepositoryVehicleAtomic) FindAll(ctx context.Context) (v []internal.Vehicle, err error) {
+	return r.current().FindAll(ctx)
+}
+
+// FindByColorAndYear is a method that returns a list of vehicles that match the color and fabrication year
+func (r *RepositoryVehicleAtomic) FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error) {
+	return r.current().FindByColorAndYear(ctx, color, fabricationYear)
+}
+
+// FindByBrandAndYearRange is a method that returns a list of vehicles that match the brand and a range of fabrication years
+func (r *RepositoryVehicleAtomic) FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error) {
+	return r.current().FindByBrandAndYearRange(ctx, brand, startYear, endYear)
+}
+
+// FindByBrand is a method that returns a list of vehicles that match the brand
+func (r *RepositoryVehicleAtomic) FindByBrand(ctx context.Context, brand string) (v []internal.Vehicle, err error) {
+	return r.current().FindByBrand(ctx, brand)
+}
+
+// FindByWeightRange is a method that returns a list of vehicles that match the weight range
+func (r *RepositoryVehicleAtomic) FindByWeightRange(ctx context.Context, fromWeight float64, toWeight float64) (v []internal.Vehicle, err error) {
+	return r.current().FindByWeightRange(ctx, fromWeight, toWeight)
+}
+
+// FindByFilter is a method that returns a list of vehicles that match every set field of the filter
+func (r *RepositoryVehicleAtomic) FindByFilter(ctx context.Context, filter internal.VehicleFilter) (v []internal.Vehicle, err error) {
+	return r.current().FindByFilter(ctx, filter)
+}
+
+// Iterate is a method that returns an iteration over the vehicles that match every set field of the filter
+// - the iteration keeps reading the repository that was current when it started
+func (r *RepositoryVehicleAtomic) Iterate(ctx context.Context, filter internal.VehicleFilter) (it internal.VehicleIterator, err error) {
+	return r.current().Iterate(ctx, filter)
+}
+
+// FindPage is a method that returns the page of the vehicles that match every set field of the filter, sorted and paged as the query says
+func (r *RepositoryVehicleAtomic) FindPage(ctx context.Context, filter internal.VehicleFilter, q internal.PageQuery) (v []internal.Vehicle, p internal.Page, err error) {
+	return r.current().FindPage(ctx, filter, q)
+}
+
+// FindValues is a method that returns the distinct values of a text attribute of the vehicles, sorted
+func (r *RepositoryVehicleAtomic) FindValues(ctx context.Context, attribute string) (values []string, err error) {
+	return r.current().FindValues(ctx, attribute)
+}
+
+// Save is a method that saves a new vehicle
+func (r *RepositoryVehicleAtomic) Save(ctx context.Context, v *internal.Vehicle) (err error) {
+	return r.current().Save(ctx, v)
+}
+
+// Update is a method that replaces the attributes of an existing vehicle
+func (r *RepositoryVehicleAtomic) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	return r.current().Update(ctx, v)
+}
+
+// Patch is a method that updates only the given attributes of an existing vehicle
+func (r *RepositoryVehicleAtomic) Patch(ctx context.Context, id int, patch internal.VehicleAttributesPatch) (v internal.Vehicle, err error) {
+	return r.current().Patch(ctx, id, patch)
+}
+
+// Delete is a method that deletes a vehicle
+func (r *RepositoryVehicleAtomic) Delete(ctx context.Context, id int) (err error) {
+	return r.current().Delete(ctx, id)
+}
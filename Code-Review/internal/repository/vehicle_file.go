@@ -0,0 +1,391 @@
+package repository
+
+import (
+	"app/internal"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// NewRepositoryVehicleFile is a function that returns a new instance of RepositoryVehicleFile
+// - the write-ahead log at walPath is replayed onto rp, so rp must already hold the last snapshot
+func NewRepositoryVehicleFile(rp internal.RepositoryVehicle, st internal.StorerVehicle, walPath string) (r *RepositoryVehicleFile, err error) {
+	// open write-ahead log
+	wal, err := os.OpenFile(walPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+
+	current := NewRepositoryVehicleAtomic(rp)
+	r = &RepositoryVehicleFile{
+		RepositoryVehicle: current,
+		current:           current,
+		st:                st,
+		wal:               wal,
+	}
+
+	// replay write-ahead log
+	err = r.replay(rp)
+	if err != nil {
+		wal.Close()
+		r = nil
+		return
+	}
+
+	return
+}
+
+// RepositoryVehicleFile is a struct that represents a vehicle repository persisted to a file
+// - reads are served by the wrapped repository
+// - writes are appended to a write-ahead log (synced) before being applied to the wrapped repository
+// - a write rejected by the wrapped repository (e.g. a conflict or a missing vehicle) is truncated from the log,
+// so the log only has the accepted writes and a replay onto another snapshot does not apply a rejected one
+// - a write can be cancelled until it is logged, from then on it is applied as it would be on replay
+// - compaction stores a snapshot of the wrapped repository atomically and truncates the log
+// - reload replaces the wrapped repository with a new snapshot, with the log replayed onto it
+type RepositoryVehicleFile struct {
+	// RepositoryVehicle is the repository that holds the vehicles in memory
+	internal.RepositoryVehicle
+	// current is the same repository, to replace it on reload
+	current *RepositoryVehicleAtomic
+	// st is the storer that writes the snapshot of the vehicles
+	st internal.StorerVehicle
+	// mu is the mutex that serializes writes, so the log has the same order as the applied writes
+	mu sync.Mutex
+	// wal is the write-ahead log file
+	wal *os.File
+	// pending is the number of entries in the log since the last compaction
+	pending int
+	// skipped are the entries that failed on the last replay
+	skipped []WALEntryError
+	// stop is the channel that stops the periodic compaction
+	stop chan struct{}
+	// done is the channel closed when the periodic compaction has stopped
+	done chan struct{}
+}
+
+// walOp is the operation of an entry of the write-ahead log
+type walOp string
+
+const (
+	walOpSave   walOp = "save"
+	walOpUpdate walOp = "update"
+	walOpPatch  walOp = "patch"
+	walOpDelete walOp = "delete"
+)
+
+// WALEntryError is an error that represents an entry of the write-ahead log that failed on replay
+type WALEntryError struct {
+	// Line is the line of the entry in the log (starting at 1)
+	Line int
+	// Op is the operation of the entry
+	Op string
+	// Id is the id of the vehicle of the entry
+	Id int
+	// Err is the error returned by the repository
+	Err error
+}
+
+// Error returns the entry and the reason it failed
+func (e WALEntryError) Error() string {
+	return fmt.Sprintf("repository: write-ahead log entry %d (%s of id %d) skipped: %s", e.Line, e.Op, e.Id, e.Err)
+}
+
+// Unwrap returns the error returned by the repository
+func (e WALEntryError) Unwrap() error {
+	return e.Err
+}
+
+// walEntry is a struct that represents an entry of the write-ahead log (one JSON document per line)
+type walEntry struct {
+	// Op is the operation
+	Op walOp `json:"op"`
+	// Id is the id of the vehicle (patch and delete)
+	Id int `json:"id,omitempty"`
+	// Vehicle is the vehicle (save and update)
+	Vehicle *internal.Vehicle `json:"vehicle,omitempty"`
+	// Patch is the patch (patch)
+	Patch *internal.VehicleAttributesPatch `json:"patch,omitempty"`
+}
+
+// Save is a method that saves a new vehicle
+func (r *RepositoryVehicleFile) Save(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = r.write(ctx, walEntry{Op: walOpSave, Vehicle: v}, func(ctx context.Context) error {
+		return r.RepositoryVehicle.Save(ctx, v)
+	})
+	return
+}
+
+// Update is a method that replaces the attributes of an existing vehicle
+func (r *RepositoryVehicleFile) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = r.write(ctx, walEntry{Op: walOpUpdate, Vehicle: v}, func(ctx context.Context) error {
+		return r.RepositoryVehicle.Update(ctx, v)
+	})
+	return
+}
+
+// Patch is a method that updates only the given attributes of an existing vehicle
+func (r *RepositoryVehicleFile) Patch(ctx context.Context, id int, patch internal.VehicleAttributesPatch) (v internal.Vehicle, err error) {
+	err = r.write(ctx, walEntry{Op: walOpPatch, Id: id, Patch: &patch}, func(ctx context.Context) (err error) {
+		v, err = r.RepositoryVehicle.Patch(ctx, id, patch)
+		return
+	})
+	return
+}
+
+// Delete is a method that deletes a vehicle
+func (r *RepositoryVehicleFile) Delete(ctx context.Context, id int) (err error) {
+	err = r.write(ctx, walEntry{Op: walOpDelete, Id: id}, func(ctx context.Context) error {
+		return r.RepositoryVehicle.Delete(ctx, id)
+	})
+	return
+}
+
+// Skipped is a method that returns the entries of the write-ahead log that failed on the last replay (boot or reload)
+// - e.g. a save of an id that the snapshot reloaded already has. The entries stay in the log until the next compaction
+func (r *RepositoryVehicleFile) Skipped() []WALEntryError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.skipped)
+}
+
+// Reload is a method that replaces the wrapped repository with the one returned by load
+// - load must return a repository with the new snapshot: the write-ahead log is replayed onto it, as on boot, so no write is lost
+// - load is called with the lock held, so no write or compaction runs between the read of the snapshot and the swap
+// (a compaction would store the previous vehicles over the new snapshot). Its error is returned as is
+// - calls in flight finish on the previous repository
+func (r *RepositoryVehicleFile) Reload(load func() (internal.RepositoryVehicle, error)) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rp, err := load()
+	if err != nil {
+		return
+	}
+	_, err = r.wal.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+	r.pending = 0
+	err = r.replay(rp)
+	if err != nil {
+		return
+	}
+	r.current.Swap(rp)
+	return
+}
+
+// Compact is a method that stores a snapshot of the vehicles and truncates the write-ahead log
+// - nothing is done if the log is empty
+func (r *RepositoryVehicleFile) Compact() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.compact()
+}
+
+// StartCompaction is a method that compacts the write-ahead log every interval until Close is called
+// - errors are retried on the next tick, the log keeps every write until a compaction succeeds
+func (r *RepositoryVehicleFile) StartCompaction(interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stop != nil || interval <= 0 {
+		return
+	}
+	r.stop, r.done = make(chan struct{}), make(chan struct{})
+
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_ = r.Compact()
+			}
+		}
+	}(r.stop, r.done)
+}
+
+// Close is a method that stops the periodic compaction, compacts the write-ahead log and closes it
+func (r *RepositoryVehicleFile) Close() (err error) {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.compact()
+	if errClose := r.wal.Close(); err == nil {
+		err = errClose
+	}
+	return
+}
+
+// write is a method that logs an entry and applies it to the wrapped repository with apply, under the lock
+// - the write is cancelled if ctx is done before the entry is logged, from then on apply gets a ctx without cancel
+// - an entry whose apply fails is truncated from the log: the wrapped repository did not change
+func (r *RepositoryVehicleFile) write(ctx context.Context, e walEntry, apply func(ctx context.Context) error) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	offset, err := r.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	err = r.append(e)
+	if err == nil {
+		err = apply(context.WithoutCancel(ctx))
+		if err != nil {
+			r.pending--
+		}
+	}
+	if err != nil {
+		// - also a partially written entry: the next one would be appended to it
+		if errTruncate := r.truncate(offset); errTruncate != nil {
+			err = errors.Join(err, errTruncate)
+		}
+	}
+	return
+}
+
+// append is a method that appends an entry to the write-ahead log and syncs it
+// - the caller must hold the lock
+func (r *RepositoryVehicleFile) append(e walEntry) (err error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	_, err = r.wal.Write(append(b, '\n'))
+	if err != nil {
+		return
+	}
+	err = r.wal.Sync()
+	if err != nil {
+		return
+	}
+	r.pending++
+	return
+}
+
+// compact is a method that stores a snapshot of the vehicles and truncates the write-ahead log
+// - the caller must hold the lock
+func (r *RepositoryVehicleFile) compact() (err error) {
+	if r.pending == 0 {
+		return
+	}
+
+	// snapshot
+	v, err := r.RepositoryVehicle.FindAll(context.Background())
+	if err != nil {
+		return
+	}
+	db := make(map[int]internal.Vehicle, len(v))
+	for _, vh := range v {
+		db[vh.Id] = vh
+	}
+	err = r.st.Store(db)
+	if err != nil {
+		return
+	}
+
+	// truncate log
+	err = r.truncate(0)
+	if err != nil {
+		return
+	}
+	r.pending = 0
+	return
+}
+
+// replay is a method that applies every entry of the write-ahead log to the given repository
+// - the log must be positioned at its start
+// - entries that fail (e.g. a save of an id the snapshot already has) are skipped and kept in r.skipped
+// - a torn last entry (crash while appending) is discarded
+// - the caller must hold the lock (or own r, while it is built)
+func (r *RepositoryVehicleFile) replay(rp internal.RepositoryVehicle) (err error) {
+	ctx := context.Background()
+	var offset int64
+	var line int
+	r.skipped = nil
+	rd := bufio.NewReader(r.wal)
+	for {
+		b, errRead := rd.ReadBytes('\n')
+		if errRead == io.EOF {
+			// torn entry (no trailing newline): discard it
+			break
+		}
+		if errRead != nil {
+			err = errRead
+			return
+		}
+
+		var e walEntry
+		if json.Unmarshal(b, &e) != nil {
+			break
+		}
+		offset += int64(len(b))
+		line++
+		r.pending++
+
+		var errApply error
+		switch {
+		case e.Op == walOpSave && e.Vehicle != nil:
+			errApply = rp.Save(ctx, e.Vehicle)
+		case e.Op == walOpUpdate && e.Vehicle != nil:
+			errApply = rp.Update(ctx, e.Vehicle)
+		case e.Op == walOpPatch && e.Patch != nil:
+			_, errApply = rp.Patch(ctx, e.Id, *e.Patch)
+		case e.Op == walOpDelete:
+			errApply = rp.Delete(ctx, e.Id)
+		default:
+			err = errors.New("repository: invalid write-ahead log entry")
+			return
+		}
+		if errApply != nil {
+			id := e.Id
+			if e.Vehicle != nil {
+				id = e.Vehicle.Id
+			}
+			r.skipped = append(r.skipped, WALEntryError{Line: line, Op: string(e.Op), Id: id, Err: errApply})
+		}
+	}
+
+	// drop anything after the last valid entry and continue appending from there
+	err = r.truncate(offset)
+	return
+}
+
+// truncate is a method that truncates the write-ahead log to the given size and moves the offset to its end
+func (r *RepositoryVehicleFile) truncate(size int64) (err error) {
+	err = r.wal.Truncate(size)
+	if err != nil {
+		return
+	}
+	_, err = r.wal.Seek(size, io.SeekStart)
+	if err != nil {
+		return
+	}
+	err = r.wal.Sync()
+	return
+}
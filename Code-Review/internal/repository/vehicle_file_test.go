@@ -0,0 +1,241 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"app/internal/repository"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// openRepositoryVehicleFile loads the snapshot at path and replays its write-ahead log, as the application does on boot
+func openRepositoryVehicleFile(t *testing.T, path string) *repository.RepositoryVehicleFile {
+	ld := loader.NewLoaderVehicleJSON(path)
+	db, err := ld.Load()
+	require.NoError(t, err)
+	rp, err := repository.NewRepositoryVehicleFile(repository.NewRepositoryVehicleIndexed(db), ld, path+".wal")
+	require.NoError(t, err)
+	return rp
+}
+
+// newSnapshot writes a snapshot with VehicleMap to a temporary directory and returns its path
+func newSnapshot(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "vehicles.json")
+	require.NoError(t, loader.NewLoaderVehicleJSON(path).Store(VehicleMap))
+	return path
+}
+
+func TestRepositoryVehicleFile_Replay(t *testing.T) {
+	t.Run("writes survive a restart without compaction", func(t *testing.T) {
+		// arrange
+		path := newSnapshot(t)
+		rp := openRepositoryVehicleFile(t, path)
+		brand := "Chevrolet"
+		v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", Registration: "XYZ-789"}}
+		// act
+		require.NoError(t, rp.Save(context.Background(), &v))
+		_, err := rp.Patch(context.Background(), 2, internal.VehicleAttributesPatch{Brand: &brand})
+		require.NoError(t, err)
+		require.NoError(t, rp.Delete(context.Background(), 1))
+		// - crash: the log is not compacted and the snapshot is untouched
+		rp = openRepositoryVehicleFile(t, path)
+		// assert
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Len(t, vehicles, 1)
+		require.Equal(t, 2, vehicles[0].Id)
+		require.Equal(t, "Chevrolet", vehicles[0].Brand)
+	})
+
+	t.Run("rejected writes are not logged", func(t *testing.T) {
+		// arrange
+		path := newSnapshot(t)
+		rp := openRepositoryVehicleFile(t, path)
+		v := internal.Vehicle{Id: 1, VehicleAttributes: internal.VehicleAttributes{Registration: "XYZ-789"}}
+		// act
+		err := rp.Save(context.Background(), &v)
+		require.ErrorIs(t, err, internal.ErrRepositoryVehicleDuplicated)
+		err = rp.Delete(context.Background(), 7)
+		require.ErrorIs(t, err, internal.ErrRepositoryVehicleNotFound)
+		// assert
+		info, err := os.Stat(path + ".wal")
+		require.NoError(t, err)
+		require.Zero(t, info.Size())
+		// - a snapshot without the id would accept the rejected save if it were replayed
+		err = rp.Reload(func() (internal.RepositoryVehicle, error) {
+			return repository.NewRepositoryVehicleIndexed(map[int]internal.Vehicle{}), nil
+		})
+		require.NoError(t, err)
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Len(t, vehicles, 0)
+		require.Empty(t, rp.Skipped())
+	})
+
+	t.Run("torn last entry is discarded", func(t *testing.T) {
+		// arrange
+		path := newSnapshot(t)
+		rp := openRepositoryVehicleFile(t, path)
+		require.NoError(t, rp.Delete(context.Background(), 1))
+		f, err := os.OpenFile(path+".wal", os.O_APPEND|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		_, err = f.WriteString(`{"op":"save","vehicle":{"Id":3`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		// act
+		rp = openRepositoryVehicleFile(t, path)
+		// assert
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Len(t, vehicles, 0)
+		v := internal.Vehicle{Id: 3, VehicleAttributes: internal.VehicleAttributes{Registration: "XYZ-789"}}
+		require.NoError(t, rp.Save(context.Background(), &v))
+		rp = openRepositoryVehicleFile(t, path)
+		vehicles, _ = rp.FindAll(context.Background())
+		require.Len(t, vehicles, 1)
+	})
+}
+
+func TestRepositoryVehicleFile_Compact(t *testing.T) {
+	t.Run("snapshot is stored and log truncated", func(t *testing.T) {
+		// arrange
+		path := newSnapshot(t)
+		rp := openRepositoryVehicleFile(t, path)
+		v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", Registration: "XYZ-789"}}
+		require.NoError(t, rp.Save(context.Background(), &v))
+		// act
+		err := rp.Close()
+		// assert
+		require.NoError(t, err)
+		info, err := os.Stat(path + ".wal")
+		require.NoError(t, err)
+		require.Zero(t, info.Size())
+		db, err := loader.NewLoaderVehicleJSON(path).Load()
+		require.NoError(t, err)
+		require.Len(t, db, 2)
+		require.Equal(t, v, db[2])
+	})
+
+	t.Run("empty log leaves the snapshot untouched", func(t *testing.T) {
+		// arrange
+		path := newSnapshot(t)
+		require.NoError(t, os.WriteFile(path, []byte(`[{"id": 1, "registration": "ABC-123"}]`), 0644))
+		rp := openRepositoryVehicleFile(t, path)
+		// act
+		err := rp.Compact()
+		// assert
+		require.NoError(t, err)
+		b, _ := os.ReadFile(path)
+		require.Equal(t, `[{"id": 1, "registration": "ABC-123"}]`, string(b))
+	})
+}
+
+func TestRepositoryVehicleFile_Reload(t *testing.T) {
+	t.Run("new snapshot with writes not compacted replayed", func(t *testing.T) {
+		// arrange
+		path := newSnapshot(t)
+		rp := openRepositoryVehicleFile(t, path)
+		v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", Registration: "XYZ-789"}}
+		require.NoError(t, rp.Save(context.Background(), &v))
+		before, _ := rp.FindAll(context.Background())
+		snapshot := map[int]internal.Vehicle{
+			5: {Id: 5, VehicleAttributes: internal.VehicleAttributes{Brand: "Audi", Registration: "AUD-555"}},
+		}
+		// act
+		err := rp.Reload(func() (internal.RepositoryVehicle, error) {
+			return repository.NewRepositoryVehicleIndexed(snapshot), nil
+		})
+		// assert
+		require.NoError(t, err)
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Len(t, vehicles, 2)
+		require.Equal(t, 2, vehicles[0].Id)
+		require.Equal(t, 5, vehicles[1].Id)
+		require.Len(t, before, 2, "results read before the reload are not changed")
+		require.Equal(t, 1, before[0].Id)
+		// - writes after the reload go to the new snapshot and the log
+		require.NoError(t, rp.Delete(context.Background(), 5))
+		require.NoError(t, rp.Close())
+		db, err := loader.NewLoaderVehicleJSON(path).Load()
+		require.NoError(t, err)
+		require.Len(t, db, 1)
+		require.Contains(t, db, 2)
+	})
+
+	t.Run("entries that fail on the new snapshot are reported", func(t *testing.T) {
+		// arrange
+		path := newSnapshot(t)
+		rp := openRepositoryVehicleFile(t, path)
+		v := internal.Vehicle{Id: 5, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", Registration: "XYZ-789"}}
+		require.NoError(t, rp.Save(context.Background(), &v))
+		snapshot := map[int]internal.Vehicle{
+			5: {Id: 5, VehicleAttributes: internal.VehicleAttributes{Brand: "Audi", Registration: "AUD-555"}},
+		}
+		// act
+		err := rp.Reload(func() (internal.RepositoryVehicle, error) {
+			return repository.NewRepositoryVehicleIndexed(snapshot), nil
+		})
+		// assert
+		require.NoError(t, err)
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Len(t, vehicles, 1)
+		require.Equal(t, "Audi", vehicles[0].Brand)
+		skipped := rp.Skipped()
+		require.Len(t, skipped, 1)
+		require.Equal(t, 1, skipped[0].Line)
+		require.Equal(t, "save", skipped[0].Op)
+		require.Equal(t, 5, skipped[0].Id)
+		require.ErrorIs(t, skipped[0], internal.ErrRepositoryVehicleDuplicated)
+	})
+
+	t.Run("compaction waits for the reload in progress", func(t *testing.T) {
+		// arrange
+		path := newSnapshot(t)
+		rp := openRepositoryVehicleFile(t, path)
+		v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", Registration: "XYZ-789"}}
+		require.NoError(t, rp.Save(context.Background(), &v))
+		snapshot := map[int]internal.Vehicle{
+			5: {Id: 5, VehicleAttributes: internal.VehicleAttributes{Brand: "Audi", Registration: "AUD-555"}},
+		}
+		compacted := make(chan error, 1)
+		// act
+		err := rp.Reload(func() (internal.RepositoryVehicle, error) {
+			// - the new snapshot is on disk, a compaction now would store the previous vehicles over it
+			require.NoError(t, loader.NewLoaderVehicleJSON(path).Store(snapshot))
+			go func() { compacted <- rp.Compact() }()
+			select {
+			case err := <-compacted:
+				t.Errorf("compaction ran during the reload: %v", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+			db, err := loader.NewLoaderVehicleJSON(path).Load()
+			return repository.NewRepositoryVehicleIndexed(db), err
+		})
+		// assert
+		require.NoError(t, err)
+		require.NoError(t, <-compacted)
+		db, err := loader.NewLoaderVehicleJSON(path).Load()
+		require.NoError(t, err)
+		require.Len(t, db, 2, "the new snapshot with the writes replayed")
+		require.Contains(t, db, 2)
+		require.Contains(t, db, 5)
+	})
+}
+
+func TestRepositoryVehicleFile_ContextDone(t *testing.T) {
+	// arrange
+	path := newSnapshot(t)
+	rp := openRepositoryVehicleFile(t, path)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", Registration: "XYZ-789"}}
+	// act
+	err := rp.Save(ctx, &v)
+	// assert: the write is not logged, so it is not replayed either
+	require.ErrorIs(t, err, context.Canceled)
+	rp = openRepositoryVehicleFile(t, path)
+	vehicles, _ := rp.FindAll(context.Background())
+	require.Len(t, vehicles, 1)
+}
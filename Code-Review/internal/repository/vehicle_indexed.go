@@ -0,0 +1,592 @@
+package repository
+
+import (
+	"app/internal"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// NewRepositoryVehicleIndexed is a function that returns a new instance of RepositoryVehicleIndexed
+func NewRepositoryVehicleIndexed(db map[int]internal.Vehicle) *RepositoryVehicleIndexed {
+	r := &RepositoryVehicleIndexed{
+		db:             make(map[int]internal.Vehicle),
+		byBrand:        make(map[string]map[int]struct{}),
+		byColorAndYear: make(map[colorYear]map[int]struct{}),
+		byRegistration: make(map[string]map[int]struct{}),
+		byText:         make(map[string]map[string]map[int]struct{}, len(internal.VehicleTextAttributes)),
+	}
+	for name := range internal.VehicleTextAttributes {
+		r.byText[name] = make(map[string]map[int]struct{})
+	}
+	for _, v := range db {
+		r.insert(v, true)
+	}
+	r.byYear.sort()
+	r.byWeight.sort()
+	return r
+}
+
+// RepositoryVehicleIndexed is a struct that represents a vehicle repository with secondary indexes
+// - calls fail with the error of ctx if it is done once the lock is acquired
+// - hash indexes: brand, color and fabrication year, registration, each text attribute (its keys are the distinct values)
+// - sorted indexes: fabrication year, weight (range queries use binary search)
+type RepositoryVehicleIndexed struct {
+	// mu is the mutex that guards db and the indexes against concurrent reads and writes
+	mu sync.RWMutex
+	// db is a map of vehicles
+	db map[int]internal.Vehicle
+	// byBrand is a hash index of vehicle ids by brand
+	byBrand map[string]map[int]struct{}
+	// byColorAndYear is a hash index of vehicle ids by color and fabrication year
+	byColorAndYear map[colorYear]map[int]struct{}
+	// byRegistration is a hash index of vehicle ids by registration
+	byRegistration map[string]map[int]struct{}
+	// byText is a hash index of vehicle ids by value, per attribute of internal.VehicleTextAttributes
+	byText map[string]map[string]map[int]struct{}
+	// byYear is a sorted index of vehicle ids by fabrication year
+	byYear sortedIndex[int]
+	// byWeight is a sorted index of vehicle ids by weight
+	byWeight sortedIndex[float64]
+}
+
+// colorYear is the key of the color and fabrication year index
+type colorYear struct {
+	color string
+	year  int
+}
+
+// FindAll is a method that returns a list of all vehicles
+func (r *RepositoryVehicleIndexed) FindAll(ctx context.Context) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	v = make([]internal.Vehicle, 0, len(r.db))
+
+	// copy db
+	for _, value := range r.db {
+		v = append(v, value)
+	}
+	sortById(v)
+
+	return
+}
+
+// FindByColorAndYear is a method that returns a list of vehicles that match the color and fabrication year
+func (r *RepositoryVehicleIndexed) FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	ids := r.byColorAndYear[colorYear{color: color, year: fabricationYear}]
+	v = make([]internal.Vehicle, 0, len(ids))
+	for id := range ids {
+		v = append(v, r.db[id])
+	}
+	sortById(v)
+
+	return
+}
+
+// FindByBrandAndYearRange is a method that returns a list of vehicles that match the brand and a range of fabrication years
+func (r *RepositoryVehicleIndexed) FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	v = make([]internal.Vehicle, 0)
+
+	// walk the smaller of both candidate sets
+	ids := r.byBrand[brand]
+	years := r.byYear.rangeOf(startYear, endYear)
+	if len(years) < len(ids) {
+		for _, e := range years {
+			if value := r.db[e.id]; value.Brand == brand {
+				v = append(v, value)
+			}
+		}
+	} else {
+		for id := range ids {
+			if value := r.db[id]; value.FabricationYear >= startYear && value.FabricationYear <= endYear {
+				v = append(v, value)
+			}
+		}
+	}
+	sortById(v)
+
+	return
+}
+
+// FindByBrand is a method that returns a list of vehicles that match the brand
+func (r *RepositoryVehicleIndexed) FindByBrand(ctx context.Context, brand string) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	ids := r.byBrand[brand]
+	v = make([]internal.Vehicle, 0, len(ids))
+	for id := range ids {
+		v = append(v, r.db[id])
+	}
+	sortById(v)
+
+	return
+}
+
+// FindByWeightRange is a method that returns a list of vehicles that match the weight range
+func (r *RepositoryVehicleIndexed) FindByWeightRange(ctx context.Context, fromWeight float64, toWeight float64) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	entries := r.byWeight.rangeOf(fromWeight, toWeight)
+	v = make([]internal.Vehicle, 0, len(entries))
+	for _, e := range entries {
+		v = append(v, r.db[e.id])
+	}
+	sortById(v)
+
+	return
+}
+
+// FindByFilter is a method that returns a list of vehicles that match every set field of the filter
+// - candidates are taken from the most selective index available, then matched against the whole filter
+func (r *RepositoryVehicleIndexed) FindByFilter(ctx context.Context, filter internal.VehicleFilter) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	ids := r.match(filter)
+	v = make([]internal.Vehicle, 0, len(ids))
+	for _, id := range ids {
+		v = append(v, r.db[id])
+	}
+	sortById(v)
+
+	return
+}
+
+// Iterate is a method that returns an iteration over the vehicles that match every set field of the filter
+// - the ids that match are taken when it starts (as FindByFilter does), each vehicle is read when the iteration reaches it
+func (r *RepositoryVehicleIndexed) Iterate(ctx context.Context, filter internal.VehicleFilter) (it internal.VehicleIterator, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	ids := r.match(filter)
+	slices.Sort(ids)
+
+	it = newVehicleIteratorIds(ctx, ids, filter, r.get)
+	return
+}
+
+// FindPage is a method that returns the page of the vehicles that match every set field of the filter, sorted and paged as the query says
+// - the vehicles are in memory: the matches of the indexes are sorted and the page taken from them (see internal.PageVehicles)
+func (r *RepositoryVehicleIndexed) FindPage(ctx context.Context, filter internal.VehicleFilter, q internal.PageQuery) (v []internal.Vehicle, p internal.Page, err error) {
+	v, err = r.FindByFilter(ctx, filter)
+	if err != nil {
+		return
+	}
+	v, p, err = internal.PageVehicles(v, q)
+	return
+}
+
+// FindValues is a method that returns the distinct values of a text attribute of the vehicles, sorted
+func (r *RepositoryVehicleIndexed) FindValues(ctx context.Context, attribute string) (values []string, err error) {
+	if _, ok := internal.VehicleTextAttributes[attribute]; !ok {
+		err = fmt.Errorf("%w: unknown text attribute %s", internal.ErrRepositoryInvalidFind, attribute)
+		return
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	index := r.byText[attribute]
+	values = make([]string, 0, len(index))
+	for value := range index {
+		values = append(values, value)
+	}
+	slices.Sort(values)
+
+	return
+}
+
+// Save is a method that saves a new vehicle
+func (r *RepositoryVehicleIndexed) Save(ctx context.Context, v *internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// check duplicates
+	if _, ok := r.db[v.Id]; ok {
+		err = internal.ErrRepositoryVehicleDuplicated
+		return
+	}
+	if r.registrationTaken(v.Registration, v.Id) {
+		err = internal.ErrRepositoryRegistrationDuplicated
+		return
+	}
+
+	// save
+	r.insert(*v, false)
+
+	return
+}
+
+// Update is a method that replaces the attributes of an existing vehicle
+func (r *RepositoryVehicleIndexed) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// check existence and duplicates (only of a new registration: the datasets have shared ones)
+	old, ok := r.db[v.Id]
+	if !ok {
+		err = internal.ErrRepositoryVehicleNotFound
+		return
+	}
+	if v.Registration != old.Registration && r.registrationTaken(v.Registration, v.Id) {
+		err = internal.ErrRepositoryRegistrationDuplicated
+		return
+	}
+
+	// update
+	r.remove(old)
+	r.insert(*v, false)
+
+	return
+}
+
+// Patch is a method that updates only the given attributes of an existing vehicle
+func (r *RepositoryVehicleIndexed) Patch(ctx context.Context, id int, patch internal.VehicleAttributesPatch) (v internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// check existence
+	old, ok := r.db[id]
+	if !ok {
+		err = internal.ErrRepositoryVehicleNotFound
+		return
+	}
+
+	// apply patch and check duplicates (only of a new registration: the datasets have shared ones)
+	v = old
+	patch.Apply(&v.VehicleAttributes)
+	if v.Registration != old.Registration && r.registrationTaken(v.Registration, id) {
+		v = internal.Vehicle{}
+		err = internal.ErrRepositoryRegistrationDuplicated
+		return
+	}
+
+	// update
+	r.remove(old)
+	r.insert(v, false)
+
+	return
+}
+
+// Delete is a method that deletes a vehicle
+func (r *RepositoryVehicleIndexed) Delete(ctx context.Context, id int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// check existence
+	old, ok := r.db[id]
+	if !ok {
+		err = internal.ErrRepositoryVehicleNotFound
+		return
+	}
+
+	// delete
+	r.remove(old)
+
+	return
+}
+
+// insert adds a vehicle to db and every index
+// - the caller must hold the lock (or own r exclusively)
+// - bulk appends it to the sorted indexes without sorting them, the caller sorts them once every vehicle is inserted
+func (r *RepositoryVehicleIndexed) insert(v internal.Vehicle, bulk bool) {
+	r.db[v.Id] = v
+	addToSet(r.byBrand, v.Brand, v.Id)
+	addToSet(r.byColorAndYear, colorYear{color: v.Color, year: v.FabricationYear}, v.Id)
+	addToSet(r.byRegistration, v.Registration, v.Id)
+	for name, get := range internal.VehicleTextAttributes {
+		addToSet(r.byText[name], get(v), v.Id)
+	}
+	if bulk {
+		r.byYear.entries = append(r.byYear.entries, sortedEntry[int]{key: v.FabricationYear, id: v.Id})
+		r.byWeight.entries = append(r.byWeight.entries, sortedEntry[float64]{key: v.Weight, id: v.Id})
+		return
+	}
+	r.byYear.insert(v.FabricationYear, v.Id)
+	r.byWeight.insert(v.Weight, v.Id)
+}
+
+// remove deletes a vehicle from db and every index
+// - the caller must hold the lock
+func (r *RepositoryVehicleIndexed) remove(v internal.Vehicle) {
+	delete(r.db, v.Id)
+	removeFromSet(r.byBrand, v.Brand, v.Id)
+	removeFromSet(r.byColorAndYear, colorYear{color: v.Color, year: v.FabricationYear}, v.Id)
+	removeFromSet(r.byRegistration, v.Registration, v.Id)
+	for name, get := range internal.VehicleTextAttributes {
+		removeFromSet(r.byText[name], get(v), v.Id)
+	}
+	r.byYear.remove(v.FabricationYear, v.Id)
+	r.byWeight.remove(v.Weight, v.Id)
+}
+
+// get is a method that returns the vehicle with the id, if it exists
+func (r *RepositoryVehicleIndexed) get(id int) (v internal.Vehicle, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok = r.db[id]
+	return
+}
+
+// match is a method that returns the ids of the vehicles that match every set field of the filter, unsorted
+// - candidates are taken from the most selective index available, then matched against the whole filter
+// - the caller must hold the lock
+func (r *RepositoryVehicleIndexed) match(filter internal.VehicleFilter) (ids []int) {
+	ids = make([]int, 0)
+
+	// add is a function that adds the id to the result if its vehicle matches the filter
+	add := func(id int) {
+		if filter.Match(r.db[id]) {
+			ids = append(ids, id)
+		}
+	}
+
+	// candidates: ids (looked up in the vehicles) and hash indexes
+	best := len(r.db)
+	var set map[int]struct{}
+	hashed := false
+	if filter.Ids != nil {
+		set, hashed = make(map[int]struct{}, len(filter.Ids)), true
+		for _, id := range filter.Ids {
+			if _, ok := r.db[id]; ok {
+				set[id] = struct{}{}
+			}
+		}
+		best = len(set)
+	}
+	if brands := anyOf(filter.Brand, filter.Brands); brands != nil {
+		if b := unionOf(r.byBrand, brands); !hashed || len(b) < best {
+			set, hashed = b, true
+			best = len(set)
+		}
+	}
+	if y, colors := filter.FabricationYear, anyOf(filter.Color, filter.Colors); colors != nil && y.Min != nil && y.Max != nil && *y.Min == *y.Max {
+		keys := make([]colorYear, 0, len(colors))
+		for _, color := range colors {
+			keys = append(keys, colorYear{color: color, year: *y.Min})
+		}
+		if c := unionOf(r.byColorAndYear, keys); !hashed || len(c) < best {
+			set, hashed = c, true
+			best = len(set)
+		}
+	}
+
+	// candidates: sorted indexes (only if smaller than the hashed ones)
+	var years []sortedEntry[int]
+	var weights []sortedEntry[float64]
+	if y := filter.FabricationYear; y.IsSet() {
+		if e := r.byYear.rangeOfBounds(y.Min, y.Max); len(e) < best {
+			years, best = e, len(e)
+		}
+	}
+	if w := filter.Weight; w.IsSet() {
+		if e := r.byWeight.rangeOfBounds(w.Min, w.Max); len(e) < best {
+			years, weights, best = nil, e, len(e)
+		}
+	}
+
+	// match candidates
+	switch {
+	case weights != nil:
+		for _, e := range weights {
+			add(e.id)
+		}
+	case years != nil:
+		for _, e := range years {
+			add(e.id)
+		}
+	case hashed:
+		for id := range set {
+			add(id)
+		}
+	default:
+		for id := range r.db {
+			add(id)
+		}
+	}
+	return
+}
+
+// registrationTaken returns true if another vehicle than id already uses the registration
+// - the caller must hold the lock
+func (r *RepositoryVehicleIndexed) registrationTaken(registration string, id int) bool {
+	for other := range r.byRegistration[registration] {
+		if other != id {
+			return true
+		}
+	}
+	return false
+}
+
+// addToSet adds an id to the set stored under key
+func addToSet[K comparable](index map[K]map[int]struct{}, key K, id int) {
+	set, ok := index[key]
+	if !ok {
+		set = make(map[int]struct{})
+		index[key] = set
+	}
+	set[id] = struct{}{}
+}
+
+// removeFromSet removes an id from the set stored under key, dropping the set when it gets empty
+func removeFromSet[K comparable](index map[K]map[int]struct{}, key K, id int) {
+	set, ok := index[key]
+	if !ok {
+		return
+	}
+	delete(set, id)
+	if len(set) == 0 {
+		delete(index, key)
+	}
+}
+
+// unionOf returns the ids stored under any of the keys
+// - the set of a single key is the one of the index, it must not be modified
+func unionOf[K comparable](index map[K]map[int]struct{}, keys []K) map[int]struct{} {
+	if len(keys) == 1 {
+		return index[keys[0]]
+	}
+	set := make(map[int]struct{})
+	for _, key := range keys {
+		for id := range index[key] {
+			set[id] = struct{}{}
+		}
+	}
+	return set
+}
+
+// anyOf returns the values a text attribute of a filter may have: the exact value if it is set, else the set of values
+// - nil if the attribute is not filtered
+func anyOf(value *string, values []string) []string {
+	if value != nil {
+		return []string{*value}
+	}
+	return values
+}
+
+// sortedEntry is an entry of a sorted index
+type sortedEntry[K cmp.Ordered] struct {
+	key K
+	id  int
+}
+
+// compareSortedEntry orders entries by key and then by id
+func compareSortedEntry[K cmp.Ordered](a, b sortedEntry[K]) int {
+	if c := cmp.Compare(a.key, b.key); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.id, b.id)
+}
+
+// sortedIndex is a slice of entries sorted by key and id
+type sortedIndex[K cmp.Ordered] struct {
+	entries []sortedEntry[K]
+}
+
+// sort sorts the entries of the index
+func (s *sortedIndex[K]) sort() {
+	slices.SortFunc(s.entries, compareSortedEntry[K])
+}
+
+// insert adds an entry keeping the index sorted
+func (s *sortedIndex[K]) insert(key K, id int) {
+	e := sortedEntry[K]{key: key, id: id}
+	i, _ := slices.BinarySearchFunc(s.entries, e, compareSortedEntry[K])
+	s.entries = slices.Insert(s.entries, i, e)
+}
+
+// remove deletes an entry keeping the index sorted
+func (s *sortedIndex[K]) remove(key K, id int) {
+	e := sortedEntry[K]{key: key, id: id}
+	i, found := slices.BinarySearchFunc(s.entries, e, compareSortedEntry[K])
+	if found {
+		s.entries = slices.Delete(s.entries, i, i+1)
+	}
+}
+
+// rangeOfBounds returns the entries whose key is between the given bounds (both inclusive)
+// - nil bounds are open ended
+func (s *sortedIndex[K]) rangeOfBounds(from *K, to *K) []sortedEntry[K] {
+	if len(s.entries) == 0 {
+		return nil
+	}
+	lo, hi := s.entries[0].key, s.entries[len(s.entries)-1].key
+	if from != nil {
+		lo = *from
+	}
+	if to != nil {
+		hi = *to
+	}
+	return s.rangeOf(lo, hi)
+}
+
+// rangeOf returns the entries whose key is between from and to (both inclusive)
+// - the returned slice shares memory with the index, the caller must hold the lock while reading it
+func (s *sortedIndex[K]) rangeOf(from K, to K) []sortedEntry[K] {
+	if from > to {
+		return nil
+	}
+	lo, _ := slices.BinarySearchFunc(s.entries, from, func(e sortedEntry[K], k K) int {
+		// first entry with key >= from
+		if e.key < k {
+			return -1
+		}
+		return 1
+	})
+	hi, _ := slices.BinarySearchFunc(s.entries, to, func(e sortedEntry[K], k K) int {
+		// first entry with key > to
+		if e.key <= k {
+			return -1
+		}
+		return 1
+	})
+	return s.entries[lo:hi]
+}
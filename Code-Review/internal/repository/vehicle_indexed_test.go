@@ -0,0 +1,395 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newRandomVehicleMap returns a map of n pseudo-random vehicles
+func newRandomVehicleMap(n int) map[int]internal.Vehicle {
+	brands := []string{"Ford", "Chevrolet", "GMC", "Hummer", "Toyota", "Honda", "Mazda", "Nissan"}
+	colors := []string{"red", "blue", "green", "black", "white", "orange"}
+	rnd := rand.New(rand.NewSource(1))
+
+	db := make(map[int]internal.Vehicle, n)
+	for i := 1; i <= n; i++ {
+		db[i] = internal.Vehicle{
+			Id: i,
+			VehicleAttributes: internal.VehicleAttributes{
+				Brand:           brands[rnd.Intn(len(brands))],
+				Registration:    fmt.Sprintf("REG-%d", i),
+				Color:           colors[rnd.Intn(len(colors))],
+				FabricationYear: 1980 + rnd.Intn(45),
+				Weight:          float64(rnd.Intn(300000)) / 100,
+			},
+		}
+	}
+	return db
+}
+
+func TestRepositoryVehicleIndexed_MatchesMap(t *testing.T) {
+	// arrange
+	db := newRandomVehicleMap(2000)
+	rpMap := repository.NewRepositoryReadVehicleMap(db)
+	rpIdx := repository.NewRepositoryVehicleIndexed(db)
+
+	t.Run("FindByColorAndYear", func(t *testing.T) {
+		expected, _ := rpMap.FindByColorAndYear(context.Background(), "red", 2000)
+		vehicles, err := rpIdx.FindByColorAndYear(context.Background(), "red", 2000)
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+	})
+
+	t.Run("FindByBrandAndYearRange", func(t *testing.T) {
+		expected, _ := rpMap.FindByBrandAndYearRange(context.Background(), "Ford", 1990, 2000)
+		vehicles, err := rpIdx.FindByBrandAndYearRange(context.Background(), "Ford", 1990, 2000)
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+
+		// narrow year range walks the year index instead of the brand index
+		expected, _ = rpMap.FindByBrandAndYearRange(context.Background(), "Ford", 2000, 2000)
+		vehicles, err = rpIdx.FindByBrandAndYearRange(context.Background(), "Ford", 2000, 2000)
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+	})
+
+	t.Run("FindByBrand", func(t *testing.T) {
+		expected, _ := rpMap.FindByBrand(context.Background(), "GMC")
+		vehicles, err := rpIdx.FindByBrand(context.Background(), "GMC")
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+	})
+
+	t.Run("FindByWeightRange", func(t *testing.T) {
+		expected, _ := rpMap.FindByWeightRange(context.Background(), 500, 1250.5)
+		vehicles, err := rpIdx.FindByWeightRange(context.Background(), 500, 1250.5)
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+	})
+
+	t.Run("FindByFilter", func(t *testing.T) {
+		brand, color := "Ford", "red"
+		year, fromYear, toWeight := 2000, 1995, 900.0
+		for _, filter := range []internal.VehicleFilter{
+			{Brand: &brand},
+			{Brand: &brand, Weight: internal.Range[float64]{Max: &toWeight}},
+			{Color: &color, FabricationYear: internal.Range[int]{Min: &year, Max: &year}},
+			{FabricationYear: internal.Range[int]{Min: &fromYear}},
+			{Color: &color},
+			{Brands: []string{"Ford", "Toyota"}},
+			{Colors: []string{"red", "blue"}, FabricationYear: internal.Range[int]{Min: &year, Max: &year}},
+			{Brand: &brand, Colors: []string{"red", "black"}},
+			{Brands: []string{}},
+			{Ids: []int{3, 1, 99}},
+			{Ids: []int{1, 2, 3}, Brand: &brand},
+			{Ids: []int{}},
+		} {
+			expected, _ := rpMap.FindByFilter(context.Background(), filter)
+			vehicles, err := rpIdx.FindByFilter(context.Background(), filter)
+			require.NoError(t, err)
+			require.Equal(t, expected, vehicles)
+		}
+	})
+
+	t.Run("FindValues", func(t *testing.T) {
+		for name := range internal.VehicleTextAttributes {
+			expected, _ := rpMap.FindValues(context.Background(), name)
+			values, err := rpIdx.FindValues(context.Background(), name)
+			require.NoError(t, err)
+			require.Equal(t, expected, values)
+		}
+		_, err := rpIdx.FindValues(context.Background(), "registration")
+		require.ErrorIs(t, err, internal.ErrRepositoryInvalidFind)
+
+		// - the attribute is checked before ctx, as the map repository does
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, errMap := rpMap.FindValues(ctx, "registration")
+		_, errIdx := rpIdx.FindValues(ctx, "registration")
+		require.ErrorIs(t, errMap, internal.ErrRepositoryInvalidFind)
+		require.ErrorIs(t, errIdx, internal.ErrRepositoryInvalidFind)
+		_, errIdx = rpIdx.FindValues(ctx, "brand")
+		require.ErrorIs(t, errIdx, context.Canceled)
+	})
+
+	t.Run("FindByWeightRange - inverted range", func(t *testing.T) {
+		vehicles, err := rpIdx.FindByWeightRange(context.Background(), 2000, 1000)
+		require.NoError(t, err)
+		require.Len(t, vehicles, 0)
+	})
+}
+
+func TestRepositoryVehicleIndexed_Writes(t *testing.T) {
+	t.Run("indexes follow patch and delete", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryVehicleIndexed(VehicleMap)
+		brand := "Chevrolet"
+		weight := 3000.0
+		// act
+		_, err := rp.Patch(context.Background(), 1, internal.VehicleAttributesPatch{Brand: &brand, Weight: &weight})
+		// assert
+		require.NoError(t, err)
+		vehicles, _ := rp.FindByBrand(context.Background(), "Ford")
+		require.Len(t, vehicles, 0)
+		vehicles, _ = rp.FindByBrand(context.Background(), "Chevrolet")
+		require.Len(t, vehicles, 1)
+		vehicles, _ = rp.FindByWeightRange(context.Background(), 2999, 3001)
+		require.Len(t, vehicles, 1)
+		brands, _ := rp.FindValues(context.Background(), "brand")
+		require.Equal(t, []string{"Chevrolet"}, brands)
+
+		// act
+		err = rp.Delete(context.Background(), 1)
+		// assert
+		require.NoError(t, err)
+		vehicles, _ = rp.FindByWeightRange(context.Background(), 2999, 3001)
+		require.Len(t, vehicles, 0)
+		v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Registration: "ABC-123"}}
+		require.NoError(t, rp.Save(context.Background(), &v))
+	})
+
+	t.Run("error - duplicated registration", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryVehicleIndexed(VehicleMap)
+		v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Registration: "ABC-123"}}
+		// act
+		err := rp.Save(context.Background(), &v)
+		// assert
+		require.ErrorIs(t, err, internal.ErrRepositoryRegistrationDuplicated)
+	})
+
+	t.Run("concurrent reads and writes", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryVehicleIndexed(newRandomVehicleMap(500))
+		// act
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				v := internal.Vehicle{Id: 1000 + i, VehicleAttributes: internal.VehicleAttributes{
+					Brand: "Ford", Registration: fmt.Sprintf("NEW-%d", i), Weight: 10,
+				}}
+				_ = rp.Save(context.Background(), &v)
+			}(i)
+			go func() {
+				defer wg.Done()
+				_, _ = rp.FindByWeightRange(context.Background(), 0, 100)
+				_, _ = rp.FindByBrand(context.Background(), "Ford")
+			}()
+		}
+		wg.Wait()
+		// assert
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Len(t, vehicles, 508)
+	})
+}
+
+// benchmarks: indexed store against the map scan on a large fleet
+const benchmarkFleetSize = 200000
+
+func BenchmarkRepositoryVehicle_FindByBrand(b *testing.B) {
+	db := newRandomVehicleMap(benchmarkFleetSize)
+	b.Run("map", func(b *testing.B) {
+		rp := repository.NewRepositoryReadVehicleMap(db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByBrand(context.Background(), "Ford")
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		rp := repository.NewRepositoryVehicleIndexed(db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByBrand(context.Background(), "Ford")
+		}
+	})
+}
+
+func BenchmarkRepositoryVehicle_FindByColorAndYear(b *testing.B) {
+	db := newRandomVehicleMap(benchmarkFleetSize)
+	b.Run("map", func(b *testing.B) {
+		rp := repository.NewRepositoryReadVehicleMap(db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByColorAndYear(context.Background(), "red", 2000)
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		rp := repository.NewRepositoryVehicleIndexed(db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByColorAndYear(context.Background(), "red", 2000)
+		}
+	})
+}
+
+func BenchmarkRepositoryVehicle_FindByBrandAndYearRange(b *testing.B) {
+	db := newRandomVehicleMap(benchmarkFleetSize)
+	b.Run("map", func(b *testing.B) {
+		rp := repository.NewRepositoryReadVehicleMap(db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByBrandAndYearRange(context.Background(), "Ford", 2000, 2002)
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		rp := repository.NewRepositoryVehicleIndexed(db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByBrandAndYearRange(context.Background(), "Ford", 2000, 2002)
+		}
+	})
+}
+
+func BenchmarkRepositoryVehicle_FindByWeightRange(b *testing.B) {
+	db := newRandomVehicleMap(benchmarkFleetSize)
+	b.Run("map", func(b *testing.B) {
+		rp := repository.NewRepositoryReadVehicleMap(db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByWeightRange(context.Background(), 1000, 1010)
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		rp := repository.NewRepositoryVehicleIndexed(db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByWeightRange(context.Background(), 1000, 1010)
+		}
+	})
+}
+
+func TestRepositoryVehicle_ContextDone(t *testing.T) {
+	// arrange
+	db := newRandomVehicleMap(100)
+	repositories := map[string]internal.RepositoryVehicle{
+		"map":     repository.NewRepositoryReadVehicleMap(db),
+		"indexed": repository.NewRepositoryVehicleIndexed(db),
+		"sql":     newRepositoryVehicleSQL(t, db),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, rp := range repositories {
+		// act
+		vehicles, errFind := rp.FindByColorAndYear(ctx, "red", 2000)
+		errDelete := rp.Delete(ctx, 1)
+		// assert
+		require.ErrorIs(t, errFind, context.Canceled, name)
+		require.Nil(t, vehicles, name)
+		require.ErrorIs(t, errDelete, context.Canceled, name)
+		all, err := rp.FindAll(context.Background())
+		require.NoError(t, err, name)
+		require.Len(t, all, 100, name)
+	}
+}
+
+// collect returns the vehicles of an iteration, closing it
+func collect(t *testing.T, it internal.VehicleIterator) (v []internal.Vehicle, err error) {
+	t.Helper()
+	defer func() { require.NoError(t, it.Close()) }()
+
+	v = make([]internal.Vehicle, 0, it.Total())
+	for it.Next() {
+		v = append(v, it.Vehicle())
+	}
+	err = it.Err()
+	return
+}
+
+func TestRepositoryVehicle_Iterate(t *testing.T) {
+	// arrange
+	db := newRandomVehicleMap(200)
+	rpMap := repository.NewRepositoryReadVehicleMap(db)
+	repositories := map[string]internal.RepositoryVehicle{
+		"map":     rpMap,
+		"indexed": repository.NewRepositoryVehicleIndexed(db),
+		"sql":     newRepositoryVehicleSQL(t, db),
+	}
+
+	t.Run("same vehicles as FindByFilter", func(t *testing.T) {
+		brand, color := "Ford", "red"
+		year, fromYear, toWeight := 2000, 1995, 900.0
+		for name, rp := range repositories {
+			for _, filter := range []internal.VehicleFilter{
+				{},
+				{Brand: &brand, Weight: internal.Range[float64]{Max: &toWeight}},
+				{Color: &color, FabricationYear: internal.Range[int]{Min: &year, Max: &year}},
+				{FabricationYear: internal.Range[int]{Min: &fromYear}},
+			} {
+				// act
+				expected, _ := rpMap.FindByFilter(context.Background(), filter)
+				it, err := rp.Iterate(context.Background(), filter)
+				require.NoError(t, err, name)
+				total := it.Total()
+				vehicles, err := collect(t, it)
+				// assert
+				require.NoError(t, err, name)
+				require.Equal(t, expected, vehicles, name)
+				require.Equal(t, len(expected), total, name)
+			}
+		}
+	})
+
+	t.Run("a vehicle deleted or changed during the iteration is skipped", func(t *testing.T) {
+		for name, rp := range map[string]internal.RepositoryVehicle{
+			"map":     repository.NewRepositoryReadVehicleMap(newRandomVehicleMap(3)),
+			"indexed": repository.NewRepositoryVehicleIndexed(newRandomVehicleMap(3)),
+		} {
+			// arrange
+			brand := "Other"
+			for _, id := range []int{1, 2, 3} {
+				_, err := rp.Patch(context.Background(), id, internal.VehicleAttributesPatch{Brand: &brand})
+				require.NoError(t, err, name)
+			}
+			it, err := rp.Iterate(context.Background(), internal.VehicleFilter{Brand: &brand})
+			require.NoError(t, err, name)
+			// act
+			require.True(t, it.Next(), name)
+			require.NoError(t, rp.Delete(context.Background(), 2), name)
+			changed := "Changed"
+			_, err = rp.Patch(context.Background(), 3, internal.VehicleAttributesPatch{Brand: &changed})
+			require.NoError(t, err, name)
+			// assert
+			require.Equal(t, 1, it.Vehicle().Id, name)
+			require.False(t, it.Next(), name)
+			require.NoError(t, it.Err(), name)
+			require.Equal(t, 3, it.Total(), name)
+			require.NoError(t, it.Close(), name)
+		}
+	})
+
+	t.Run("error - ctx done during the iteration", func(t *testing.T) {
+		for name, rp := range repositories {
+			// arrange
+			ctx, cancel := context.WithCancel(context.Background())
+			it, err := rp.Iterate(ctx, internal.VehicleFilter{})
+			require.NoError(t, err, name)
+			require.True(t, it.Next(), name)
+			// act
+			cancel()
+			_, err = collect(t, it)
+			// assert
+			require.ErrorIs(t, err, context.Canceled, name)
+		}
+	})
+
+	t.Run("error - ctx done before it starts", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		for name, rp := range repositories {
+			// act
+			it, err := rp.Iterate(ctx, internal.VehicleFilter{})
+			// assert
+			require.ErrorIs(t, err, context.Canceled, name)
+			require.Nil(t, it, name)
+		}
+	})
+}
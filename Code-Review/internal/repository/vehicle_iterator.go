@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"app/internal"
+	"context"
+)
+
+// newVehicleIteratorIds is a function that returns a new instance of vehicleIteratorIds
+func newVehicleIteratorIds(ctx context.Context, ids []int, filter internal.VehicleFilter, get func(id int) (v internal.Vehicle, ok bool)) *vehicleIteratorIds {
+	return &vehicleIteratorIds{ctx: ctx, ids: ids, filter: filter, get: get}
+}
+
+// vehicleIteratorIds is a struct that implements internal.VehicleIterator over the ids of the vehicles of an in-memory repository
+// - only the ids are copied when the iteration starts, each vehicle is read with get (under the lock of the repository) when it is reached
+// - a vehicle deleted meanwhile, or that no longer matches the filter, is skipped
+type vehicleIteratorIds struct {
+	// ctx is the context of the iteration, checked on every step
+	ctx context.Context
+	// ids is the list of ids that matched the filter when the iteration started, sorted
+	ids []int
+	// filter is the filter the vehicles must still match
+	filter internal.VehicleFilter
+	// get is the function that reads a vehicle by id
+	get func(id int) (v internal.Vehicle, ok bool)
+	// next is the index of the next id
+	next int
+	// v is the current vehicle
+	v internal.Vehicle
+	// err is the error that stopped the iteration
+	err error
+}
+
+// Total is a method that returns the number of vehicles that matched the filter when the iteration started
+func (it *vehicleIteratorIds) Total() int {
+	return len(it.ids)
+}
+
+// Next is a method that advances to the next vehicle that still exists and matches the filter
+func (it *vehicleIteratorIds) Next() bool {
+	for it.err == nil && it.next < len(it.ids) {
+		if it.err = it.ctx.Err(); it.err != nil {
+			return false
+		}
+		id := it.ids[it.next]
+		it.next++
+		if v, ok := it.get(id); ok && it.filter.Match(v) {
+			it.v = v
+			return true
+		}
+	}
+	return false
+}
+
+// Vehicle is a method that returns the current vehicle
+func (it *vehicleIteratorIds) Vehicle() internal.Vehicle {
+	return it.v
+}
+
+// Err is a method that returns the error of ctx if it stopped the iteration
+func (it *vehicleIteratorIds) Err() error {
+	return it.err
+}
+
+// Close is a method that stops the iteration
+func (it *vehicleIteratorIds) Close() error {
+	it.next = len(it.ids)
+	return nil
+}
@@ -1,6 +1,13 @@
 package repository
 
-import "app/internal"
+import (
+	"app/internal"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
 
 // NewRepositoryReadVehicleMap is a function that returns a new instance of RepositoryReadVehicleMap
 func NewRepositoryReadVehicleMap(db map[int]internal.Vehicle) *RepositoryReadVehicleMap {
@@ -13,75 +20,322 @@ func NewRepositoryReadVehicleMap(db map[int]internal.Vehicle) *RepositoryReadVeh
 }
 
 // RepositoryReadVehicleMap is a struct that represents a vehicle repository
+// - calls fail with the error of ctx if it is done once the lock is acquired
 type RepositoryReadVehicleMap struct {
+	// mu is the mutex that guards db against concurrent reads and writes
+	mu sync.RWMutex
 	// db is a map of vehicles
 	db map[int]internal.Vehicle
 }
 
-// FindAll is a method that returns a map of all vehicles
-func (r *RepositoryReadVehicleMap) FindAll() (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
+// FindAll is a method that returns a list of all vehicles
+func (r *RepositoryReadVehicleMap) FindAll(ctx context.Context) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	v = make([]internal.Vehicle, 0)
 
 	// copy db
-	for key, value := range r.db {
-		v[key] = value
+	for _, value := range r.db {
+		v = append(v, value)
 	}
+	sortById(v)
 
 	return
 }
 
-// FindByColorAndYear is a method that returns a map of vehicles that match the color and fabrication year
-func (r *RepositoryReadVehicleMap) FindByColorAndYear(color string, fabricationYear int) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
+// FindByColorAndYear is a method that returns a list of vehicles that match the color and fabrication year
+func (r *RepositoryReadVehicleMap) FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	v = make([]internal.Vehicle, 0)
 
 	// filter db
-	for key, value := range r.db {
+	for _, value := range r.db {
 		if value.Color == color && value.FabricationYear == fabricationYear {
-			v[key] = value
+			v = append(v, value)
 		}
 	}
+	sortById(v)
 
 	return
 }
 
-// FindByBrandAndYearRange is a method that returns a map of vehicles that match the brand and a range of fabrication years
-func (r *RepositoryReadVehicleMap) FindByBrandAndYearRange(brand string, startYear int, endYear int) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
+// FindByBrandAndYearRange is a method that returns a list of vehicles that match the brand and a range of fabrication years
+func (r *RepositoryReadVehicleMap) FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	v = make([]internal.Vehicle, 0)
 
 	// filter db
-	for key, value := range r.db {
+	for _, value := range r.db {
 		if value.Brand == brand && value.FabricationYear >= startYear && value.FabricationYear <= endYear {
-			v[key] = value
+			v = append(v, value)
 		}
 	}
+	sortById(v)
 
 	return
 }
 
-// FindByBrand is a method that returns a map of vehicles that match the brand
-func (r *RepositoryReadVehicleMap) FindByBrand(brand string) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
+// FindByBrand is a method that returns a list of vehicles that match the brand
+func (r *RepositoryReadVehicleMap) FindByBrand(ctx context.Context, brand string) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	v = make([]internal.Vehicle, 0)
 
 	// filter db
-	for key, value := range r.db {
+	for _, value := range r.db {
 		if value.Brand == brand {
-			v[key] = value
+			v = append(v, value)
 		}
 	}
+	sortById(v)
 
 	return
 }
 
-// FindByWeightRange is a method that returns a map of vehicles that match the weight range
-func (r *RepositoryReadVehicleMap) FindByWeightRange(fromWeight float64, toWeight float64) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
+// FindByWeightRange is a method that returns a list of vehicles that match the weight range
+func (r *RepositoryReadVehicleMap) FindByWeightRange(ctx context.Context, fromWeight float64, toWeight float64) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	v = make([]internal.Vehicle, 0)
 
 	// filter db
-	for key, value := range r.db {
+	for _, value := range r.db {
 		if value.Weight >= fromWeight && value.Weight <= toWeight {
-			v[key] = value
+			v = append(v, value)
+		}
+	}
+	sortById(v)
+
+	return
+}
+
+// FindByFilter is a method that returns a list of vehicles that match every set field of the filter
+func (r *RepositoryReadVehicleMap) FindByFilter(ctx context.Context, filter internal.VehicleFilter) (v []internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	v = make([]internal.Vehicle, 0)
+
+	// filter db
+	for _, value := range r.db {
+		if filter.Match(value) {
+			v = append(v, value)
+		}
+	}
+	sortById(v)
+
+	return
+}
+
+// Iterate is a method that returns an iteration over the vehicles that match every set field of the filter
+// - the ids that match are taken when it starts, each vehicle is read when the iteration reaches it
+func (r *RepositoryReadVehicleMap) Iterate(ctx context.Context, filter internal.VehicleFilter) (it internal.VehicleIterator, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	ids := make([]int, 0)
+
+	// filter db
+	for key, value := range r.db {
+		if filter.Match(value) {
+			ids = append(ids, key)
+		}
+	}
+	slices.Sort(ids)
+
+	it = newVehicleIteratorIds(ctx, ids, filter, r.get)
+	return
+}
+
+// FindPage is a method that returns the page of the vehicles that match every set field of the filter, sorted and paged as the query says
+// - the vehicles are in memory: the matches are sorted and the page taken from them (see internal.PageVehicles)
+func (r *RepositoryReadVehicleMap) FindPage(ctx context.Context, filter internal.VehicleFilter, q internal.PageQuery) (v []internal.Vehicle, p internal.Page, err error) {
+	v, err = r.FindByFilter(ctx, filter)
+	if err != nil {
+		return
+	}
+	v, p, err = internal.PageVehicles(v, q)
+	return
+}
+
+// FindValues is a method that returns the distinct values of a text attribute of the vehicles, sorted
+func (r *RepositoryReadVehicleMap) FindValues(ctx context.Context, attribute string) (values []string, err error) {
+	get, ok := internal.VehicleTextAttributes[attribute]
+	if !ok {
+		err = fmt.Errorf("%w: unknown text attribute %s", internal.ErrRepositoryInvalidFind, attribute)
+		return
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// collect db
+	seen := make(map[string]struct{})
+	values = make([]string, 0)
+	for _, value := range r.db {
+		if _, ok := seen[get(value)]; !ok {
+			seen[get(value)] = struct{}{}
+			values = append(values, get(value))
 		}
 	}
+	slices.Sort(values)
 
 	return
-}
\ No newline at end of file
+}
+
+// Save is a method that saves a new vehicle
+func (r *RepositoryReadVehicleMap) Save(ctx context.Context, v *internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// check duplicates
+	if _, ok := r.db[v.Id]; ok {
+		err = internal.ErrRepositoryVehicleDuplicated
+		return
+	}
+	if r.registrationTaken(v.Registration, v.Id) {
+		err = internal.ErrRepositoryRegistrationDuplicated
+		return
+	}
+
+	// save
+	r.db[v.Id] = *v
+
+	return
+}
+
+// Update is a method that replaces the attributes of an existing vehicle
+func (r *RepositoryReadVehicleMap) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// check existence and duplicates (only of a new registration: the datasets have shared ones)
+	old, ok := r.db[v.Id]
+	if !ok {
+		err = internal.ErrRepositoryVehicleNotFound
+		return
+	}
+	if v.Registration != old.Registration && r.registrationTaken(v.Registration, v.Id) {
+		err = internal.ErrRepositoryRegistrationDuplicated
+		return
+	}
+
+	// update
+	r.db[v.Id] = *v
+
+	return
+}
+
+// Patch is a method that updates only the given attributes of an existing vehicle
+func (r *RepositoryReadVehicleMap) Patch(ctx context.Context, id int, patch internal.VehicleAttributesPatch) (v internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// check existence
+	v, ok := r.db[id]
+	if !ok {
+		err = internal.ErrRepositoryVehicleNotFound
+		return
+	}
+
+	// apply patch and check duplicates (only of a new registration: the datasets have shared ones)
+	registration := v.Registration
+	patch.Apply(&v.VehicleAttributes)
+	if v.Registration != registration && r.registrationTaken(v.Registration, v.Id) {
+		v = internal.Vehicle{}
+		err = internal.ErrRepositoryRegistrationDuplicated
+		return
+	}
+
+	// update
+	r.db[id] = v
+
+	return
+}
+
+// Delete is a method that deletes a vehicle
+func (r *RepositoryReadVehicleMap) Delete(ctx context.Context, id int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// check existence
+	if _, ok := r.db[id]; !ok {
+		err = internal.ErrRepositoryVehicleNotFound
+		return
+	}
+
+	// delete
+	delete(r.db, id)
+
+	return
+}
+
+// get is a method that returns the vehicle with the id, if it exists
+func (r *RepositoryReadVehicleMap) get(id int) (v internal.Vehicle, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok = r.db[id]
+	return
+}
+
+// sortById sorts a list of vehicles by id
+func sortById(v []internal.Vehicle) {
+	slices.SortFunc(v, func(a, b internal.Vehicle) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+}
+
+// registrationTaken returns true if another vehicle than id already uses the registration
+// - the caller must hold the lock
+func (r *RepositoryReadVehicleMap) registrationTaken(registration string, id int) bool {
+	for key, value := range r.db {
+		if key != id && value.Registration == registration {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"app/internal"
+	"context"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -13,34 +14,74 @@ func NewRepositoryMock() *Mock {
 
 type Mock struct {
 	mock.Mock
-	FuncFindAll                 func() (v map[int]internal.Vehicle, err error)
-	FuncFindByColorAndYear      func(color string, fabricationYear int) (v map[int]internal.Vehicle, err error)
-	FuncFindByBrandAndYearRange func(brand string, startYear int, endYear int) (v map[int]internal.Vehicle, err error)
-	FuncFindByBrand             func(brand string) (v map[int]internal.Vehicle, err error)
-	FuncFindByWeightRange       func(fromWeight float64, toWeight float64) (v map[int]internal.Vehicle, err error)
+	FuncFindAll                 func(ctx context.Context) (v []internal.Vehicle, err error)
+	FuncFindByColorAndYear      func(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error)
+	FuncFindByBrandAndYearRange func(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error)
+	FuncFindByBrand             func(ctx context.Context, brand string) (v []internal.Vehicle, err error)
+	FuncFindByWeightRange       func(ctx context.Context, fromWeight float64, toWeight float64) (v []internal.Vehicle, err error)
 }
 
-func (m *Mock) FindAll() (v map[int]internal.Vehicle, err error) {
-	args := m.Called()
-	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+func (m *Mock) FindAll(ctx context.Context) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
 }
 
-func (m *Mock) FindByColorAndYear(color string, fabricationYear int) (v map[int]internal.Vehicle, err error) {
-	args := m.Called(color, fabricationYear)
-	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+func (m *Mock) FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx, color, fabricationYear)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
 }
 
-func (m *Mock) FindByBrandAndYearRange(brand string, startYear int, endYear int) (v map[int]internal.Vehicle, err error) {
-	args := m.Called(brand, startYear, endYear)
-	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+func (m *Mock) FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx, brand, startYear, endYear)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
 }
 
-func (m *Mock) FindByBrand(brand string) (v map[int]internal.Vehicle, err error) {
-	args := m.Called(brand)
-	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+func (m *Mock) FindByBrand(ctx context.Context, brand string) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx, brand)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
 }
 
-func (m *Mock) FindByWeightRange(fromWeight float64, toWeight float64) (v map[int]internal.Vehicle, err error) {
-	args := m.Called(fromWeight, toWeight)
-	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+func (m *Mock) FindByWeightRange(ctx context.Context, fromWeight float64, toWeight float64) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx, fromWeight, toWeight)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
+}
+
+func (m *Mock) FindByFilter(ctx context.Context, filter internal.VehicleFilter) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
+}
+
+func (m *Mock) Iterate(ctx context.Context, filter internal.VehicleFilter) (it internal.VehicleIterator, err error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(internal.VehicleIterator), args.Error(1)
+}
+
+func (m *Mock) FindPage(ctx context.Context, filter internal.VehicleFilter, q internal.PageQuery) (v []internal.Vehicle, p internal.Page, err error) {
+	args := m.Called(ctx, filter, q)
+	return args.Get(0).([]internal.Vehicle), args.Get(1).(internal.Page), args.Error(2)
+}
+
+func (m *Mock) FindValues(ctx context.Context, attribute string) (values []string, err error) {
+	args := m.Called(ctx, attribute)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *Mock) Save(ctx context.Context, v *internal.Vehicle) (err error) {
+	args := m.Called(ctx, v)
+	return args.Error(0)
+}
+
+func (m *Mock) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	args := m.Called(ctx, v)
+	return args.Error(0)
+}
+
+func (m *Mock) Patch(ctx context.Context, id int, patch internal.VehicleAttributesPatch) (v internal.Vehicle, err error) {
+	args := m.Called(ctx, id, patch)
+	return args.Get(0).(internal.Vehicle), args.Error(1)
+}
+
+func (m *Mock) Delete(ctx context.Context, id int) (err error) {
+	args := m.Called(ctx, id)
+	return args.Error(0)
 }
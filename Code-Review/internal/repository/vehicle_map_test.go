@@ -3,6 +3,7 @@ package repository_test
 import (
 	"app/internal"
 	"app/internal/repository"
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -36,7 +37,7 @@ func TestRepositoryVehicle_FindAll(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryReadVehicleMap(VehicleMap)
 		// act
-		vehicles, err := rp.FindAll()
+		vehicles, err := rp.FindAll(context.Background())
 		// assert
 		require.NoError(t, err)
 		require.Len(t, vehicles, 1)
@@ -48,8 +49,8 @@ func TestRepositoryVehicle_FindByColorAndYear(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryReadVehicleMap(VehicleMap)
 		// act
-		rp.FindAll()
-		vehicles, err := rp.FindByColorAndYear("red", 2010)
+		rp.FindAll(context.Background())
+		vehicles, err := rp.FindByColorAndYear(context.Background(), "red", 2010)
 		// assert
 		require.NoError(t, err)
 		require.Len(t, vehicles, 1)
@@ -61,7 +62,7 @@ func TestRepositoryVehicle_FindByBrandAndYearRange(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryReadVehicleMap(VehicleMap)
 		// act
-		vehicles, err := rp.FindByBrandAndYearRange("Ford", 2010, 2015)
+		vehicles, err := rp.FindByBrandAndYearRange(context.Background(), "Ford", 2010, 2015)
 		// assert
 		require.NoError(t, err)
 		require.Len(t, vehicles, 1)
@@ -71,7 +72,7 @@ func TestRepositoryVehicle_FindByBrandAndYearRange(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryReadVehicleMap(VehicleMap)
 		// act
-		vehicles, _ := rp.FindByBrandAndYearRange("Ford", 2015, 2010)
+		vehicles, _ := rp.FindByBrandAndYearRange(context.Background(), "Ford", 2015, 2010)
 		// assert
 		require.Len(t, vehicles, 0)
 	})
@@ -82,7 +83,7 @@ func TestRepositoryVehicle_FindByBrand(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryReadVehicleMap(VehicleMap)
 		// act
-		vehicles, err := rp.FindByBrand("Ford")
+		vehicles, err := rp.FindByBrand(context.Background(), "Ford")
 		// assert
 		require.NoError(t, err)
 		require.Len(t, vehicles, 1)
@@ -94,7 +95,7 @@ func TestRepositoryVehicle_FindByWeightRange(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryReadVehicleMap(VehicleMap)
 		// act
-		vehicles, err := rp.FindByWeightRange(1000, 2000)
+		vehicles, err := rp.FindByWeightRange(context.Background(), 1000, 2000)
 		// assert
 		require.NoError(t, err)
 		require.Len(t, vehicles, 1)
@@ -104,8 +105,162 @@ func TestRepositoryVehicle_FindByWeightRange(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryReadVehicleMap(VehicleMap)
 		// act
-		vehicles, _ := rp.FindByWeightRange(2000, 1000)
+		vehicles, _ := rp.FindByWeightRange(context.Background(), 2000, 1000)
 		// assert
 		require.Len(t, vehicles, 0)
 	})
 }
+
+func TestRepositoryVehicle_FindByFilter(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(VehicleMap)
+		brand, fromYear := "Ford", 2005
+		// act
+		vehicles, err := rp.FindByFilter(context.Background(), internal.VehicleFilter{Brand: &brand, FabricationYear: internal.Range[int]{Min: &fromYear}})
+		// assert
+		require.NoError(t, err)
+		require.Len(t, vehicles, 1)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(VehicleMap)
+		maxSpeed := 100.0
+		// act
+		vehicles, err := rp.FindByFilter(context.Background(), internal.VehicleFilter{MaxSpeed: internal.Range[float64]{Max: &maxSpeed}})
+		// assert
+		require.NoError(t, err)
+		require.Len(t, vehicles, 0)
+	})
+}
+
+// newVehicleMap returns a fresh copy of VehicleMap so write tests do not share state
+func newVehicleMap() map[int]internal.Vehicle {
+	db := make(map[int]internal.Vehicle)
+	for key, value := range VehicleMap {
+		db[key] = value
+	}
+	return db
+}
+
+func TestRepositoryVehicle_Save(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(newVehicleMap())
+		v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Registration: "XYZ-789"}}
+		// act
+		err := rp.Save(context.Background(), &v)
+		// assert
+		require.NoError(t, err)
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Len(t, vehicles, 2)
+	})
+
+	t.Run("error - duplicated id", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(newVehicleMap())
+		v := internal.Vehicle{Id: 1, VehicleAttributes: internal.VehicleAttributes{Registration: "XYZ-789"}}
+		// act
+		err := rp.Save(context.Background(), &v)
+		// assert
+		require.ErrorIs(t, err, internal.ErrRepositoryVehicleDuplicated)
+	})
+
+	t.Run("error - duplicated registration", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(newVehicleMap())
+		v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Registration: "ABC-123"}}
+		// act
+		err := rp.Save(context.Background(), &v)
+		// assert
+		require.ErrorIs(t, err, internal.ErrRepositoryRegistrationDuplicated)
+	})
+}
+
+func TestRepositoryVehicle_Update(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(newVehicleMap())
+		v := internal.Vehicle{Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Chevrolet", Registration: "ABC-123"}}
+		// act
+		err := rp.Update(context.Background(), &v)
+		// assert
+		require.NoError(t, err)
+		vehicles, _ := rp.FindByBrand(context.Background(), "Chevrolet")
+		require.Len(t, vehicles, 1)
+	})
+
+	t.Run("error - not found", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(newVehicleMap())
+		v := internal.Vehicle{Id: 2}
+		// act
+		err := rp.Update(context.Background(), &v)
+		// assert
+		require.ErrorIs(t, err, internal.ErrRepositoryVehicleNotFound)
+	})
+}
+
+func TestRepositoryVehicle_Patch(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(newVehicleMap())
+		color := "blue"
+		// act
+		v, err := rp.Patch(context.Background(), 1, internal.VehicleAttributesPatch{Color: &color})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "blue", v.Color)
+		require.Equal(t, "Ford", v.Brand)
+	})
+
+	t.Run("success - a registration shared with another vehicle is kept", func(t *testing.T) {
+		// arrange
+		db := newVehicleMap()
+		db[2] = internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Registration: "ABC-123"}}
+		rp := repository.NewRepositoryReadVehicleMap(db)
+		color := "blue"
+		// act
+		v, err := rp.Patch(context.Background(), 2, internal.VehicleAttributesPatch{Color: &color})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "blue", v.Color)
+		require.Equal(t, "ABC-123", db[2].Registration)
+	})
+
+	t.Run("error - duplicated registration", func(t *testing.T) {
+		// arrange
+		db := newVehicleMap()
+		db[2] = internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Registration: "XYZ-789"}}
+		rp := repository.NewRepositoryReadVehicleMap(db)
+		registration := "ABC-123"
+		// act
+		_, err := rp.Patch(context.Background(), 2, internal.VehicleAttributesPatch{Registration: &registration})
+		// assert
+		require.ErrorIs(t, err, internal.ErrRepositoryRegistrationDuplicated)
+		require.Equal(t, "XYZ-789", db[2].Registration)
+	})
+}
+
+func TestRepositoryVehicle_Delete(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(newVehicleMap())
+		// act
+		err := rp.Delete(context.Background(), 1)
+		// assert
+		require.NoError(t, err)
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Len(t, vehicles, 0)
+	})
+
+	t.Run("error - not found", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryReadVehicleMap(newVehicleMap())
+		// act
+		err := rp.Delete(context.Background(), 2)
+		// assert
+		require.ErrorIs(t, err, internal.ErrRepositoryVehicleNotFound)
+	})
+}
@@ -0,0 +1,724 @@
+package repository
+
+import (
+	"app/internal"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VehicleSQLSchema is the list of statements that create the vehicles table, the indexes of the finders and the version of the vehicles
+// - the statements can be run again, existing objects are kept
+// - the SQL (and the ? placeholders of the queries) runs on SQLite and MariaDB
+var VehicleSQLSchema = []string{
+	`CREATE TABLE IF NOT EXISTS vehicles (
+		id               INTEGER          NOT NULL PRIMARY KEY,
+		brand            VARCHAR(255)     NOT NULL,
+		model            VARCHAR(255)     NOT NULL,
+		registration     VARCHAR(255)     NOT NULL,
+		color            VARCHAR(255)     NOT NULL,
+		fabrication_year INTEGER          NOT NULL,
+		capacity         INTEGER          NOT NULL,
+		max_speed        DOUBLE PRECISION NOT NULL,
+		fuel_type        VARCHAR(255)     NOT NULL,
+		transmission     VARCHAR(255)     NOT NULL,
+		weight           DOUBLE PRECISION NOT NULL,
+		height           DOUBLE PRECISION NOT NULL,
+		length           DOUBLE PRECISION NOT NULL,
+		width            DOUBLE PRECISION NOT NULL
+	)`,
+	// FindByColorAndYear
+	`CREATE INDEX IF NOT EXISTS idx_vehicles_color_year ON vehicles (color, fabrication_year)`,
+	// FindByBrand, FindByBrandAndYearRange
+	`CREATE INDEX IF NOT EXISTS idx_vehicles_brand_year ON vehicles (brand, fabrication_year)`,
+	// FindByWeightRange
+	`CREATE INDEX IF NOT EXISTS idx_vehicles_weight ON vehicles (weight)`,
+	// registration checks of the writes (not unique: the datasets have shared registrations)
+	`CREATE INDEX IF NOT EXISTS idx_vehicles_registration ON vehicles (registration)`,
+	// Version: a single row, bumped by every write in its transaction (modified is in unix nanoseconds)
+	`CREATE TABLE IF NOT EXISTS vehicles_version (
+		id       INTEGER NOT NULL PRIMARY KEY,
+		version  BIGINT  NOT NULL,
+		modified BIGINT  NOT NULL
+	)`,
+}
+
+// vehicleSQLColumns is the list of columns of a vehicle, in the order scanned by find
+const vehicleSQLColumns = "id, brand, model, registration, color, fabrication_year, capacity, max_speed, " +
+	"fuel_type, transmission, weight, height, length, width"
+
+// vehicleSQLSortColumns is the column of each sort field of internal.VehicleSortFields, with its value of a vehicle (for a cursor)
+var vehicleSQLSortColumns = map[string]struct {
+	column string
+	value  func(v internal.Vehicle) any
+}{
+	"id":           {"id", func(v internal.Vehicle) any { return v.Id }},
+	"brand":        {"brand", func(v internal.Vehicle) any { return v.Brand }},
+	"model":        {"model", func(v internal.Vehicle) any { return v.Model }},
+	"registration": {"registration", func(v internal.Vehicle) any { return v.Registration }},
+	"color":        {"color", func(v internal.Vehicle) any { return v.Color }},
+	"year":         {"fabrication_year", func(v internal.Vehicle) any { return v.FabricationYear }},
+	"capacity":     {"capacity", func(v internal.Vehicle) any { return v.Capacity }},
+	"max_speed":    {"max_speed", func(v internal.Vehicle) any { return v.MaxSpeed }},
+	"fuel_type":    {"fuel_type", func(v internal.Vehicle) any { return v.FuelType }},
+	"transmission": {"transmission", func(v internal.Vehicle) any { return v.Transmission }},
+	"weight":       {"weight", func(v internal.Vehicle) any { return v.Weight }},
+	"height":       {"height", func(v internal.Vehicle) any { return v.Height }},
+	"length":       {"length", func(v internal.Vehicle) any { return v.Length }},
+	"width":        {"width", func(v internal.Vehicle) any { return v.Width }},
+}
+
+// MigrateVehicleSQL is a function that creates the schema of the vehicles (see VehicleSQLSchema)
+// - the first version is taken from the clock, so versions are not reused by a database that is created again
+func MigrateVehicleSQL(db *sql.DB) (err error) {
+	for _, stmt := range VehicleSQLSchema {
+		if _, err = db.Exec(stmt); err != nil {
+			return
+		}
+	}
+
+	// version row (another process may insert it first)
+	var n int
+	if err = db.QueryRow("SELECT COUNT(*) FROM vehicles_version").Scan(&n); err != nil || n > 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	if _, err = db.Exec("INSERT INTO vehicles_version (id, version, modified) VALUES (1, ?, ?)", now, now); err != nil {
+		if db.QueryRow("SELECT COUNT(*) FROM vehicles_version").Scan(&n) == nil && n > 0 {
+			err = nil
+		}
+		return
+	}
+	return
+}
+
+// NewRepositoryVehicleSQL is a function that returns a new instance of RepositoryVehicleSQL
+// - db must already have the schema (see MigrateVehicleSQL)
+func NewRepositoryVehicleSQL(db *sql.DB) *RepositoryVehicleSQL {
+	return &RepositoryVehicleSQL{db: db}
+}
+
+// RepositoryVehicleSQL is a struct that represents a vehicle repository stored in a SQL database
+// - every write bumps the version of the vehicles in the database, so writes made by other processes are seen too
+type RepositoryVehicleSQL struct {
+	// db is the database
+	db *sql.DB
+	// mu is the mutex that serializes the writes of the repository, so they do not fail with a busy database between themselves
+	// - the checks of a write run in its transaction, so they also hold against the writes of other processes
+	mu sync.Mutex
+}
+
+// sqlQuerier is an interface that represents the queries shared by a database and a transaction
+type sqlQuerier interface {
+	// QueryContext is a method that runs a query that returns rows
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	// QueryRowContext is a method that runs a query that returns at most one row
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const (
+	// sqliteConstraintPrimaryKey is the extended result code of SQLite for a violated primary key
+	sqliteConstraintPrimaryKey = 1555
+	// sqliteConstraintUnique is the extended result code of SQLite for a violated unique index
+	sqliteConstraintUnique = 2067
+)
+
+// FindAll is a method that returns a list of all vehicles
+func (r *RepositoryVehicleSQL) FindAll(ctx context.Context) (v []internal.Vehicle, err error) {
+	v, err = find(ctx, r.db, "", nil)
+	return
+}
+
+// FindByColorAndYear is a method that returns a list of vehicles that match the color and fabrication year
+func (r *RepositoryVehicleSQL) FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error) {
+	v, err = find(ctx, r.db, "color = ? AND fabrication_year = ?", []any{color, fabricationYear})
+	return
+}
+
+// FindByBrandAndYearRange is a method that returns a list of vehicles that match the brand and a range of fabrication years
+func (r *RepositoryVehicleSQL) FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error) {
+	v, err = find(ctx, r.db, "brand = ? AND fabrication_year >= ? AND fabrication_year <= ?", []any{brand, startYear, endYear})
+	return
+}
+
+// FindByBrand is a method that returns a list of vehicles that match the brand
+func (r *RepositoryVehicleSQL) FindByBrand(ctx context.Context, brand string) (v []internal.Vehicle, err error) {
+	v, err = find(ctx, r.db, "brand = ?", []any{brand})
+	return
+}
+
+// FindByWeightRange is a method that returns a list of vehicles that match the weight range
+func (r *RepositoryVehicleSQL) FindByWeightRange(ctx context.Context, fromWeight float64, toWeight float64) (v []internal.Vehicle, err error) {
+	v, err = find(ctx, r.db, "weight >= ? AND weight <= ?", []any{fromWeight, toWeight})
+	return
+}
+
+// FindByFilter is a method that returns a list of vehicles that match every set field of the filter
+func (r *RepositoryVehicleSQL) FindByFilter(ctx context.Context, filter internal.VehicleFilter) (v []internal.Vehicle, err error) {
+	condition, args := filterCondition(filter)
+	v, err = find(ctx, r.db, condition, args)
+	return
+}
+
+// Iterate is a method that returns an iteration over the rows of the vehicles that match every set field of the filter
+// - the vehicles are counted first, then scanned as the iteration advances (writes made in between may change the count)
+func (r *RepositoryVehicleSQL) Iterate(ctx context.Context, filter internal.VehicleFilter) (it internal.VehicleIterator, err error) {
+	condition, args := filterCondition(filter)
+	where := ""
+	if condition != "" {
+		where = " WHERE " + condition
+	}
+
+	var total int
+	if err = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM vehicles"+where, args...).Scan(&total); err != nil {
+		return
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+vehicleSQLColumns+" FROM vehicles"+where+" ORDER BY id", args...)
+	if err != nil {
+		return
+	}
+
+	it = &vehicleIteratorSQL{ctx: ctx, rows: rows, total: total}
+	return
+}
+
+// FindPage is a method that returns the page of the vehicles that match every set field of the filter, sorted and paged as the query says
+// - the database sorts and limits the rows (ORDER BY the sort columns and the id), a cursor is a condition on the same columns (keyset)
+// - the vehicles are counted and the page read in a single transaction, one more row than the limit tells if there is a next page
+func (r *RepositoryVehicleSQL) FindPage(ctx context.Context, filter internal.VehicleFilter, q internal.PageQuery) (v []internal.Vehicle, p internal.Page, err error) {
+	// validate query
+	err = q.Validate()
+	if err != nil {
+		return
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	// total
+	condition, args := filterCondition(filter)
+	where := ""
+	if condition != "" {
+		where = " WHERE " + condition
+	}
+	if err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM vehicles"+where, args...).Scan(&p.Total); err != nil {
+		return
+	}
+
+	// page: after the cursor, or from the offset
+	order, after, afterArgs := pageOrder(q.Sort, q.After)
+	offset := q.Offset
+	if q.After != nil {
+		if where == "" {
+			where = " WHERE " + after
+		} else {
+			where += " AND " + after
+		}
+		args, offset = append(args, afterArgs...), 0
+	}
+	limit := math.MaxInt
+	if q.Limit > 0 {
+		limit = q.Limit + 1
+	}
+	v, err = queryVehicles(ctx, tx, "SELECT "+vehicleSQLColumns+" FROM vehicles"+where+" ORDER BY "+order+" LIMIT ? OFFSET ?",
+		append(args, limit, offset))
+	if err != nil {
+		return
+	}
+	if q.Limit > 0 && len(v) > q.Limit {
+		v = v[:q.Limit]
+		next := v[len(v)-1]
+		p.Next = &next
+	}
+	return
+}
+
+// FindValues is a method that returns the distinct values of a text attribute of the vehicles, sorted
+// - the names of the text attributes are the ones of their columns
+func (r *RepositoryVehicleSQL) FindValues(ctx context.Context, attribute string) (values []string, err error) {
+	if _, ok := internal.VehicleTextAttributes[attribute]; !ok {
+		err = fmt.Errorf("%w: unknown text attribute %s", internal.ErrRepositoryInvalidFind, attribute)
+		return
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT DISTINCT "+attribute+" FROM vehicles")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	values = make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			values = nil
+			return
+		}
+		values = append(values, value)
+	}
+	if err = rows.Err(); err != nil {
+		values = nil
+		return
+	}
+	slices.Sort(values)
+	return
+}
+
+// Version is a method that returns the current version of the vehicles and the time it was made
+func (r *RepositoryVehicleSQL) Version(ctx context.Context) (version uint64, modified time.Time, err error) {
+	var v, m int64
+	err = r.db.QueryRowContext(ctx, "SELECT version, modified FROM vehicles_version WHERE id = 1").Scan(&v, &m)
+	if err != nil {
+		return
+	}
+	version, modified = uint64(v), time.Unix(0, m)
+	return
+}
+
+// Save is a method that saves a new vehicle
+func (r *RepositoryVehicleSQL) Save(ctx context.Context, v *internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.write(ctx, func(tx *sql.Tx) (err error) {
+		// check duplicates
+		exists, err := vehicleExists(ctx, tx, v.Id)
+		if err != nil {
+			return
+		}
+		if exists {
+			err = internal.ErrRepositoryVehicleDuplicated
+			return
+		}
+		taken, err := registrationTaken(ctx, tx, v.Registration, v.Id)
+		if err != nil {
+			return
+		}
+		if taken {
+			err = internal.ErrRepositoryRegistrationDuplicated
+			return
+		}
+
+		// save
+		_, err = tx.ExecContext(ctx, "INSERT INTO vehicles ("+vehicleSQLColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			vehicleSQLValues(*v)...)
+		return
+	})
+	return
+}
+
+// Update is a method that replaces the attributes of an existing vehicle
+func (r *RepositoryVehicleSQL) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.write(ctx, func(tx *sql.Tx) error {
+		return update(ctx, tx, *v)
+	})
+	return
+}
+
+// Patch is a method that updates only the given attributes of an existing vehicle
+func (r *RepositoryVehicleSQL) Patch(ctx context.Context, id int, patch internal.VehicleAttributesPatch) (v internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.write(ctx, func(tx *sql.Tx) (err error) {
+		// check existence
+		vehicles, err := find(ctx, tx, "id = ?", []any{id})
+		if err != nil {
+			return
+		}
+		if len(vehicles) == 0 {
+			err = internal.ErrRepositoryVehicleNotFound
+			return
+		}
+
+		// apply patch and update
+		v = vehicles[0]
+		patch.Apply(&v.VehicleAttributes)
+		err = update(ctx, tx, v)
+		return
+	})
+	if err != nil {
+		v = internal.Vehicle{}
+		return
+	}
+	return
+}
+
+// Delete is a method that deletes a vehicle
+func (r *RepositoryVehicleSQL) Delete(ctx context.Context, id int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.write(ctx, func(tx *sql.Tx) (err error) {
+		result, err := tx.ExecContext(ctx, "DELETE FROM vehicles WHERE id = ?", id)
+		if err != nil {
+			return
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return
+		}
+		if n == 0 {
+			err = internal.ErrRepositoryVehicleNotFound
+			return
+		}
+		return
+	})
+	return
+}
+
+// Import is a method that saves the vehicles in a single transaction, replacing the ones with the same id
+// - registrations are not checked, so a dataset is imported as is
+func (r *RepositoryVehicleSQL) Import(ctx context.Context, v []internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.write(ctx, func(tx *sql.Tx) (err error) {
+		for _, vh := range v {
+			if _, err = tx.ExecContext(ctx, "DELETE FROM vehicles WHERE id = ?", vh.Id); err != nil {
+				return
+			}
+			_, err = tx.ExecContext(ctx, "INSERT INTO vehicles ("+vehicleSQLColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				vehicleSQLValues(vh)...)
+			if err != nil {
+				return
+			}
+		}
+		return
+	})
+	return
+}
+
+// write is a method that runs the checks and statements of a write and bumps the version of the vehicles in a single transaction
+// - the transaction is rolled back if fn fails
+// - a violated constraint of the vehicles (a write of another process in between) is returned as the error of the repository
+func (r *RepositoryVehicleSQL) write(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			err = constraintError(err)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE vehicles_version SET version = version + 1, modified = ? WHERE id = 1", time.Now().UnixNano())
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// find is a function that returns the vehicles that match the condition, ordered by id
+// - an empty condition returns all vehicles
+func find(ctx context.Context, q sqlQuerier, condition string, args []any) (v []internal.Vehicle, err error) {
+	query := "SELECT " + vehicleSQLColumns + " FROM vehicles"
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	query += " ORDER BY id"
+
+	v, err = queryVehicles(ctx, q, query, args)
+	return
+}
+
+// queryVehicles is a function that returns the vehicles of a query of vehicleSQLColumns, in the order of its rows
+func queryVehicles(ctx context.Context, q sqlQuerier, query string, args []any) (v []internal.Vehicle, err error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	v = make([]internal.Vehicle, 0)
+	for rows.Next() {
+		var vh internal.Vehicle
+		vh, err = scanVehicle(rows)
+		if err != nil {
+			v = nil
+			return
+		}
+		v = append(v, vh)
+	}
+	if err = rows.Err(); err != nil {
+		v = nil
+		return
+	}
+	return
+}
+
+// update is a function that replaces the attributes of an existing vehicle in the transaction of a write
+func update(ctx context.Context, tx *sql.Tx, v internal.Vehicle) (err error) {
+	// check existence and duplicates (only of a new registration: the datasets have shared ones)
+	registration, exists, err := vehicleRegistration(ctx, tx, v.Id)
+	if err != nil {
+		return
+	}
+	if !exists {
+		err = internal.ErrRepositoryVehicleNotFound
+		return
+	}
+	if v.Registration != registration {
+		var taken bool
+		taken, err = registrationTaken(ctx, tx, v.Registration, v.Id)
+		if err != nil {
+			return
+		}
+		if taken {
+			err = internal.ErrRepositoryRegistrationDuplicated
+			return
+		}
+	}
+
+	// update
+	values := vehicleSQLValues(v)
+	_, err = tx.ExecContext(ctx, `UPDATE vehicles SET brand = ?, model = ?, registration = ?, color = ?, fabrication_year = ?,
+		capacity = ?, max_speed = ?, fuel_type = ?, transmission = ?, weight = ?, height = ?, length = ?, width = ?
+		WHERE id = ?`, append(values[1:], v.Id)...)
+	return
+}
+
+// vehicleExists is a function that returns true if a vehicle with the id exists
+func vehicleExists(ctx context.Context, tx *sql.Tx, id int) (ok bool, err error) {
+	err = tx.QueryRowContext(ctx, "SELECT 1 FROM vehicles WHERE id = ?", id).Scan(new(int))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+		return
+	}
+	ok = err == nil
+	return
+}
+
+// vehicleRegistration is a function that returns the registration of the vehicle with the id, if it exists
+func vehicleRegistration(ctx context.Context, tx *sql.Tx, id int) (registration string, ok bool, err error) {
+	err = tx.QueryRowContext(ctx, "SELECT registration FROM vehicles WHERE id = ?", id).Scan(&registration)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+		return
+	}
+	ok = err == nil
+	return
+}
+
+// registrationTaken is a function that returns true if another vehicle than id already uses the registration
+func registrationTaken(ctx context.Context, tx *sql.Tx, registration string, id int) (taken bool, err error) {
+	err = tx.QueryRowContext(ctx, "SELECT 1 FROM vehicles WHERE registration = ? AND id <> ? LIMIT 1", registration, id).Scan(new(int))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+		return
+	}
+	taken = err == nil
+	return
+}
+
+// constraintError is a function that returns the error of the repository for a violated constraint of SQLite
+// - the id is the primary key, the registration has a unique index only on databases created with one (the datasets share registrations)
+// - other errors are returned as is
+func constraintError(err error) error {
+	var sqliteErr interface{ Code() int }
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	switch {
+	case sqliteErr.Code() == sqliteConstraintPrimaryKey:
+		return internal.ErrRepositoryVehicleDuplicated
+	case sqliteErr.Code() == sqliteConstraintUnique && strings.Contains(err.Error(), "vehicles.registration"):
+		return internal.ErrRepositoryRegistrationDuplicated
+	}
+	return err
+}
+
+// scanVehicle is a function that scans a row of vehicleSQLColumns
+func scanVehicle(rows *sql.Rows) (vh internal.Vehicle, err error) {
+	err = rows.Scan(
+		&vh.Id, &vh.Brand, &vh.Model, &vh.Registration, &vh.Color, &vh.FabricationYear, &vh.Capacity, &vh.MaxSpeed,
+		&vh.FuelType, &vh.Transmission, &vh.Weight, &vh.Height, &vh.Length, &vh.Width,
+	)
+	return
+}
+
+// filterCondition is a function that returns the condition of the set fields of a filter (empty if none is set)
+func filterCondition(filter internal.VehicleFilter) (condition string, args []any) {
+	var conditions []string
+
+	// ids (an empty set matches nothing)
+	switch {
+	case filter.Ids == nil:
+	case len(filter.Ids) == 0:
+		conditions = append(conditions, "1 = 0")
+	default:
+		conditions = append(conditions, "id IN (?"+strings.Repeat(", ?", len(filter.Ids)-1)+")")
+		for _, id := range filter.Ids {
+			args = append(args, id)
+		}
+	}
+
+	// exact matches and sets of values (an empty set matches nothing)
+	for _, field := range []struct {
+		column string
+		value  *string
+		values []string
+	}{
+		{"brand", filter.Brand, filter.Brands},
+		{"model", filter.Model, filter.Models},
+		{"color", filter.Color, filter.Colors},
+		{"fuel_type", filter.FuelType, filter.FuelTypes},
+		{"transmission", filter.Transmission, filter.Transmissions},
+	} {
+		if field.value != nil {
+			conditions = append(conditions, field.column+" = ?")
+			args = append(args, *field.value)
+		}
+		switch {
+		case field.values == nil:
+		case len(field.values) == 0:
+			conditions = append(conditions, "1 = 0")
+		default:
+			conditions = append(conditions, field.column+" IN (?"+strings.Repeat(", ?", len(field.values)-1)+")")
+			for _, value := range field.values {
+				args = append(args, value)
+			}
+		}
+	}
+
+	// ranges
+	conditions, args = rangeCondition(conditions, args, "fabrication_year", filter.FabricationYear)
+	conditions, args = rangeCondition(conditions, args, "capacity", filter.Capacity)
+	conditions, args = rangeCondition(conditions, args, "max_speed", filter.MaxSpeed)
+	conditions, args = rangeCondition(conditions, args, "weight", filter.Weight)
+	conditions, args = rangeCondition(conditions, args, "height", filter.Height)
+	conditions, args = rangeCondition(conditions, args, "length", filter.Length)
+	conditions, args = rangeCondition(conditions, args, "width", filter.Width)
+
+	condition = strings.Join(conditions, " AND ")
+	return
+}
+
+// vehicleSQLValues is a function that returns the values of a vehicle, in the order of vehicleSQLColumns
+func vehicleSQLValues(v internal.Vehicle) []any {
+	return []any{
+		v.Id, v.Brand, v.Model, v.Registration, v.Color, v.FabricationYear, v.Capacity, v.MaxSpeed,
+		v.FuelType, v.Transmission, v.Weight, v.Height, v.Length, v.Width,
+	}
+}
+
+// pageOrder is a function that returns the ORDER BY of the sort fields, then the id, and the condition of the rows after the cursor
+// - the condition compares the keys in order: k1 > ? OR (k1 = ? AND k2 > ?) OR ..., with < for the descending ones
+func pageOrder(fields []internal.SortField, after *internal.Vehicle) (order string, condition string, args []any) {
+	fields = append(slices.Clip(fields), internal.SortField{Name: "id"})
+
+	orders := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f.Desc {
+			orders = append(orders, vehicleSQLSortColumns[f.Name].column+" DESC")
+			continue
+		}
+		orders = append(orders, vehicleSQLSortColumns[f.Name].column)
+	}
+	order = strings.Join(orders, ", ")
+	if after == nil {
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for i, f := range fields {
+		op := " > ?"
+		if f.Desc {
+			op = " < ?"
+		}
+		equals := make([]string, 0, i+1)
+		for _, prev := range fields[:i] {
+			equals = append(equals, vehicleSQLSortColumns[prev.Name].column+" = ?")
+			args = append(args, vehicleSQLSortColumns[prev.Name].value(*after))
+		}
+		equals = append(equals, vehicleSQLSortColumns[f.Name].column+op)
+		args = append(args, vehicleSQLSortColumns[f.Name].value(*after))
+		keys = append(keys, "("+strings.Join(equals, " AND ")+")")
+	}
+	condition = "(" + strings.Join(keys, " OR ") + ")"
+	return
+}
+
+// rangeCondition is a function that appends the conditions of the set bounds of a range
+func rangeCondition[T int | float64](conditions []string, args []any, column string, rg internal.Range[T]) ([]string, []any) {
+	if rg.Min != nil {
+		conditions = append(conditions, column+" >= ?")
+		args = append(args, *rg.Min)
+	}
+	if rg.Max != nil {
+		conditions = append(conditions, column+" <= ?")
+		args = append(args, *rg.Max)
+	}
+	return conditions, args
+}
+
+// vehicleIteratorSQL is a struct that implements internal.VehicleIterator over the rows of a query of vehicleSQLColumns
+// - ctx is checked on every step (database/sql closes the rows once it is done, but not in step with the iteration)
+type vehicleIteratorSQL struct {
+	// ctx is the context of the query
+	ctx context.Context
+	// rows is the result of the query
+	rows *sql.Rows
+	// total is the number of rows counted before the query
+	total int
+	// v is the current vehicle
+	v internal.Vehicle
+	// err is the error that stopped the iteration
+	err error
+}
+
+// Total is a method that returns the number of rows counted before the query
+func (it *vehicleIteratorSQL) Total() int {
+	return it.total
+}
+
+// Next is a method that scans the next row
+func (it *vehicleIteratorSQL) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil || !it.rows.Next() {
+		return false
+	}
+	if it.v, it.err = scanVehicle(it.rows); it.err != nil {
+		return false
+	}
+	return true
+}
+
+// Vehicle is a method that returns the current vehicle
+func (it *vehicleIteratorSQL) Vehicle() internal.Vehicle {
+	return it.v
+}
+
+// Err is a method that returns the error of the scan or of the rows that stopped the iteration
+func (it *vehicleIteratorSQL) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+// Close is a method that closes the rows
+func (it *vehicleIteratorSQL) Close() error {
+	return it.rows.Close()
+}
@@ -0,0 +1,231 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"context"
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// newRepositoryVehicleSQL returns a repository on a new SQLite database with the given vehicles
+func newRepositoryVehicleSQL(t *testing.T, db map[int]internal.Vehicle) *repository.RepositoryVehicleSQL {
+	sqlDB, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "vehicles.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, repository.MigrateVehicleSQL(sqlDB))
+
+	rp := repository.NewRepositoryVehicleSQL(sqlDB)
+	v := make([]internal.Vehicle, 0, len(db))
+	for _, vh := range db {
+		v = append(v, vh)
+	}
+	require.NoError(t, rp.Import(context.Background(), v))
+	return rp
+}
+
+func TestRepositoryVehicleSQL_MatchesMap(t *testing.T) {
+	// arrange
+	db := newRandomVehicleMap(500)
+	rpMap := repository.NewRepositoryReadVehicleMap(db)
+	rpSQL := newRepositoryVehicleSQL(t, db)
+
+	t.Run("FindAll", func(t *testing.T) {
+		expected, _ := rpMap.FindAll(context.Background())
+		vehicles, err := rpSQL.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+	})
+
+	t.Run("FindByColorAndYear", func(t *testing.T) {
+		expected, _ := rpMap.FindByColorAndYear(context.Background(), "red", 2000)
+		vehicles, err := rpSQL.FindByColorAndYear(context.Background(), "red", 2000)
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+	})
+
+	t.Run("FindByBrandAndYearRange", func(t *testing.T) {
+		expected, _ := rpMap.FindByBrandAndYearRange(context.Background(), "Ford", 1990, 2000)
+		vehicles, err := rpSQL.FindByBrandAndYearRange(context.Background(), "Ford", 1990, 2000)
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+	})
+
+	t.Run("FindByBrand", func(t *testing.T) {
+		expected, _ := rpMap.FindByBrand(context.Background(), "GMC")
+		vehicles, err := rpSQL.FindByBrand(context.Background(), "GMC")
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+	})
+
+	t.Run("FindByWeightRange", func(t *testing.T) {
+		expected, _ := rpMap.FindByWeightRange(context.Background(), 500, 1250.5)
+		vehicles, err := rpSQL.FindByWeightRange(context.Background(), 500, 1250.5)
+		require.NoError(t, err)
+		require.Equal(t, expected, vehicles)
+
+		// open-ended range
+		vehicles, err = rpSQL.FindByWeightRange(context.Background(), math.Inf(-1), math.Inf(1))
+		require.NoError(t, err)
+		require.Len(t, vehicles, len(db))
+	})
+
+	t.Run("FindByFilter", func(t *testing.T) {
+		brand, color := "Ford", "red"
+		year, fromYear, toWeight := 2000, 1995, 900.0
+		for _, filter := range []internal.VehicleFilter{
+			{},
+			{Brand: &brand},
+			{Brand: &brand, Weight: internal.Range[float64]{Max: &toWeight}},
+			{Color: &color, FabricationYear: internal.Range[int]{Min: &year, Max: &year}},
+			{FabricationYear: internal.Range[int]{Min: &fromYear}},
+			{Brands: []string{"Ford", "Toyota"}},
+			{Colors: []string{"red", "blue"}, FabricationYear: internal.Range[int]{Min: &year, Max: &year}},
+			{Brand: &brand, Colors: []string{"red", "black"}},
+			{Brands: []string{}},
+			{Ids: []int{3, 1, 99}},
+			{Ids: []int{1, 2, 3}, Brand: &brand},
+			{Ids: []int{}},
+		} {
+			expected, _ := rpMap.FindByFilter(context.Background(), filter)
+			vehicles, err := rpSQL.FindByFilter(context.Background(), filter)
+			require.NoError(t, err)
+			require.Equal(t, expected, vehicles)
+		}
+	})
+
+	t.Run("FindPage", func(t *testing.T) {
+		brand := "Ford"
+		byBrandAndYear := []internal.SortField{{Name: "brand", Desc: true}, {Name: "year"}}
+		bySpeed := []internal.SortField{{Name: "max_speed", Desc: true}, {Name: "color"}}
+		_, first, _ := rpMap.FindPage(context.Background(), internal.VehicleFilter{}, internal.PageQuery{Sort: bySpeed, Limit: 20})
+		for _, c := range []struct {
+			filter internal.VehicleFilter
+			query  internal.PageQuery
+		}{
+			{internal.VehicleFilter{}, internal.PageQuery{}},
+			{internal.VehicleFilter{}, internal.PageQuery{Limit: 10}},
+			{internal.VehicleFilter{}, internal.PageQuery{Sort: byBrandAndYear, Limit: 25, Offset: 30}},
+			{internal.VehicleFilter{}, internal.PageQuery{Sort: byBrandAndYear, Offset: 490}},
+			{internal.VehicleFilter{}, internal.PageQuery{Sort: bySpeed, Limit: 20, After: first.Next}},
+			{internal.VehicleFilter{Brand: &brand}, internal.PageQuery{Sort: []internal.SortField{{Name: "weight"}}, Limit: 5, After: first.Next}},
+			{internal.VehicleFilter{Brands: []string{}}, internal.PageQuery{Limit: 5}},
+		} {
+			expected, expectedPage, _ := rpMap.FindPage(context.Background(), c.filter, c.query)
+			vehicles, p, err := rpSQL.FindPage(context.Background(), c.filter, c.query)
+			require.NoError(t, err)
+			require.Equal(t, expected, vehicles)
+			require.Equal(t, expectedPage, p)
+		}
+		_, _, err := rpSQL.FindPage(context.Background(), internal.VehicleFilter{}, internal.PageQuery{Sort: []internal.SortField{{Name: "price"}}})
+		require.ErrorIs(t, err, internal.ErrPageInvalid)
+	})
+
+	t.Run("FindValues", func(t *testing.T) {
+		for name := range internal.VehicleTextAttributes {
+			expected, _ := rpMap.FindValues(context.Background(), name)
+			values, err := rpSQL.FindValues(context.Background(), name)
+			require.NoError(t, err)
+			require.Equal(t, expected, values)
+		}
+		_, err := rpSQL.FindValues(context.Background(), "registration")
+		require.ErrorIs(t, err, internal.ErrRepositoryInvalidFind)
+	})
+}
+
+func TestRepositoryVehicleSQL_Writes(t *testing.T) {
+	t.Run("save, patch and delete", func(t *testing.T) {
+		// arrange
+		rp := newRepositoryVehicleSQL(t, VehicleMap)
+		v := internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", Registration: "XYZ-789", Weight: 1.5}}
+		brand := "Chevrolet"
+		// act
+		require.NoError(t, rp.Save(context.Background(), &v))
+		patched, err := rp.Patch(context.Background(), 2, internal.VehicleAttributesPatch{Brand: &brand})
+		require.NoError(t, err)
+		require.NoError(t, rp.Delete(context.Background(), 1))
+		// assert
+		v.Brand = "Chevrolet"
+		require.Equal(t, v, patched)
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Equal(t, []internal.Vehicle{v}, vehicles)
+	})
+
+	t.Run("patch of a vehicle with a shared registration", func(t *testing.T) {
+		// arrange
+		rp := newRepositoryVehicleSQL(t, VehicleMap)
+		require.NoError(t, rp.Import(context.Background(), []internal.Vehicle{{Id: 2, VehicleAttributes: internal.VehicleAttributes{Registration: "ABC-123"}}}))
+		color := "blue"
+		// act
+		patched, err := rp.Patch(context.Background(), 2, internal.VehicleAttributesPatch{Color: &color})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "blue", patched.Color)
+		require.Equal(t, "ABC-123", patched.Registration)
+	})
+
+	t.Run("writes of any repository on the database make a new version", func(t *testing.T) {
+		// arrange
+		sqlDB, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "vehicles.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { sqlDB.Close() })
+		require.NoError(t, repository.MigrateVehicleSQL(sqlDB))
+		rp, other := repository.NewRepositoryVehicleSQL(sqlDB), repository.NewRepositoryVehicleSQL(sqlDB)
+		first, _, err := rp.Version(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, repository.MigrateVehicleSQL(sqlDB))
+		migrated, _, _ := rp.Version(context.Background())
+		require.Equal(t, first, migrated, "a migration keeps the version")
+		// act
+		errSave := other.Save(context.Background(), &internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Registration: "XYZ-789"}})
+		afterSave, modified, _ := rp.Version(context.Background())
+		errDelete := other.Delete(context.Background(), 3)
+		afterDelete, _, _ := rp.Version(context.Background())
+		// assert
+		require.NoError(t, errSave)
+		require.ErrorIs(t, errDelete, internal.ErrRepositoryVehicleNotFound)
+		require.Equal(t, first+1, afterSave)
+		require.Equal(t, afterSave, afterDelete, "a failed write keeps the version")
+		require.WithinDuration(t, time.Now(), modified, time.Minute)
+	})
+
+	t.Run("a violated constraint is returned as the error of the repository", func(t *testing.T) {
+		// arrange
+		sqlDB, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "vehicles.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { sqlDB.Close() })
+		require.NoError(t, repository.MigrateVehicleSQL(sqlDB))
+		_, err = sqlDB.Exec("CREATE UNIQUE INDEX vehicles_registration_unique ON vehicles (registration)")
+		require.NoError(t, err)
+		rp := repository.NewRepositoryVehicleSQL(sqlDB)
+		// act
+		err = rp.Import(context.Background(), []internal.Vehicle{
+			{Id: 1, VehicleAttributes: internal.VehicleAttributes{Registration: "ABC-123"}},
+			{Id: 2, VehicleAttributes: internal.VehicleAttributes{Registration: "ABC-123"}},
+		})
+		// assert
+		require.ErrorIs(t, err, internal.ErrRepositoryRegistrationDuplicated)
+		vehicles, _ := rp.FindAll(context.Background())
+		require.Empty(t, vehicles, "the import is rolled back")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		// arrange
+		rp := newRepositoryVehicleSQL(t, VehicleMap)
+		registration := "ABC-123"
+		// act and assert
+		require.ErrorIs(t, rp.Save(context.Background(), &internal.Vehicle{Id: 1}), internal.ErrRepositoryVehicleDuplicated)
+		require.ErrorIs(t, rp.Save(context.Background(), &internal.Vehicle{Id: 2, VehicleAttributes: internal.VehicleAttributes{Registration: "ABC-123"}}),
+			internal.ErrRepositoryRegistrationDuplicated)
+		require.ErrorIs(t, rp.Update(context.Background(), &internal.Vehicle{Id: 2}), internal.ErrRepositoryVehicleNotFound)
+		_, err := rp.Patch(context.Background(), 2, internal.VehicleAttributesPatch{Registration: &registration})
+		require.ErrorIs(t, err, internal.ErrRepositoryVehicleNotFound)
+		require.ErrorIs(t, rp.Delete(context.Background(), 2), internal.ErrRepositoryVehicleNotFound)
+	})
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"app/internal"
+	"context"
+	"sync"
+	"time"
+)
+
+// NewRepositoryVehicleVersioned is a function that returns a new instance of RepositoryVehicleVersioned
+// - the first version is taken from the clock, so versions are not reused by a restart
+func NewRepositoryVehicleVersioned(rp internal.RepositoryVehicle) *RepositoryVehicleVersioned {
+	now := time.Now()
+	return &RepositoryVehicleVersioned{rp: rp, version: uint64(now.UnixNano()), modified: now}
+}
+
+// RepositoryVehicleVersioned is a struct that represents a vehicle repository that counts the versions of its vehicles
+// - every write that succeeds bumps the version, Bump does it for changes made around the repository (e.g. a reload)
+// - writes made by others (e.g. other processes on the same database) are not seen: a SQL database versions itself (see RepositoryVehicleSQL.Version)
+type RepositoryVehicleVersioned struct {
+	// rp is the wrapped repository
+	rp internal.RepositoryVehicle
+	// mu is the mutex that protects the version
+	mu sync.RWMutex
+	// version is the current version
+	version uint64
+	// modified is the time of the current version
+	modified time.Time
+}
+
+// Version is a method that returns the current version of the vehicles and the time it was made
+func (r *RepositoryVehicleVersioned) Version(ctx context.Context) (version uint64, modified time.Time, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version, r.modified, nil
+}
+
+// Bump is a method that makes a new version of the vehicles
+func (r *RepositoryVehicleVersioned) Bump() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.modified = time.Now()
+}
+
+// bumpOnSuccess is a method that makes a new version of the vehicles if a write did not fail
+func (r *RepositoryVehicleVersioned) bumpOnSuccess(err error) {
+	if err == nil {
+		r.Bump()
+	}
+}
+
+// FindAll is a method that returns a list of all vehicles
+func (r *RepositoryVehicleVersioned) FindAll(ctx context.Context) (v []internal.Vehicle, err error) {
+	return r.rp.FindAll(ctx)
+}
+
+// FindByColorAndYear is a method that returns a list of vehicles that match the color and fabrication year
+func (r *RepositoryVehicleVersioned) FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error) {
+	return r.rp.FindByColorAndYear(ctx, color, fabricationYear)
+}
+
+// FindByBrandAndYearRange is a method that returns a list of vehicles that match the brand and a range of fabrication years
+func (r *RepositoryVehicleVersioned) FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error) {
+	return r.rp.FindByBrandAndYearRange(ctx, brand, startYear, endYear)
+}
+
+// FindByBrand is a method that returns a list of vehicles that match the brand
+func (r *RepositoryVehicleVersioned) FindByBrand(ctx context.Context, brand string) (v []internal.Vehicle, err error) {
+	return r.rp.FindByBrand(ctx, brand)
+}
+
+// FindByWeightRange is a method that returns a list of vehicles that match the weight range
+func (r *RepositoryVehicleVersioned) FindByWeightRange(ctx context.Context, fromWeight float64, toWeight float64) (v []internal.Vehicle, err error) {
+	return r.rp.FindByWeightRange(ctx, fromWeight, toWeight)
+}
+
+// FindByFilter is a method that returns a list of vehicles that match every set field of the filter
+func (r *RepositoryVehicleVersioned) FindByFilter(ctx context.Context, filter internal.VehicleFilter) (v []internal.Vehicle, err error) {
+	return r.rp.FindByFilter(ctx, filter)
+}
+
+// Iterate is a method that returns an iteration over the vehicles that match every set field of the filter
+func (r *RepositoryVehicleVersioned) Iterate(ctx context.Context, filter internal.VehicleFilter) (it internal.VehicleIterator, err error) {
+	return r.rp.Iterate(ctx, filter)
+}
+
+// FindPage is a method that returns the page of the vehicles that match every set field of the filter, sorted and paged as the query says
+func (r *RepositoryVehicleVersioned) FindPage(ctx context.Context, filter internal.VehicleFilter, q internal.PageQuery) (v []internal.Vehicle, p internal.Page, err error) {
+	return r.rp.FindPage(ctx, filter, q)
+}
+
+// FindValues is a method that returns the distinct values of a text attribute of the vehicles, sorted
+func (r *RepositoryVehicleVersioned) FindValues(ctx context.Context, attribute string) (values []string, err error) {
+	return r.rp.FindValues(ctx, attribute)
+}
+
+// Save is a method that saves a new vehicle
+func (r *RepositoryVehicleVersioned) Save(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = r.rp.Save(ctx, v)
+	r.bumpOnSuccess(err)
+	return
+}
+
+// Update is a method that replaces the attributes of an existing vehicle
+func (r *RepositoryVehicleVersioned) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = r.rp.Update(ctx, v)
+	r.bumpOnSuccess(err)
+	return
+}
+
+// Patch is a method that updates only the given attributes of an existing vehicle
+func (r *RepositoryVehicleVersioned) Patch(ctx context.Context, id int, patch internal.VehicleAttributesPatch) (v internal.Vehicle, err error) {
+	v, err = r.rp.Patch(ctx, id, patch)
+	r.bumpOnSuccess(err)
+	return
+}
+
+// Delete is a method that deletes a vehicle
+func (r *RepositoryVehicleVersioned) Delete(ctx context.Context, id int) (err error) {
+	err = r.rp.Delete(ctx, id)
+	r.bumpOnSuccess(err)
+	return
+}
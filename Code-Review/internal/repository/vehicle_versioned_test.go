@@ -0,0 +1,53 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryVehicleVersioned(t *testing.T) {
+	// arrange
+	rp := repository.NewRepositoryVehicleVersioned(repository.NewRepositoryVehicleIndexed(newRandomVehicleMap(10)))
+	ctx := context.Background()
+	first, _, _ := rp.Version(ctx)
+
+	t.Run("case - reads keep the version", func(t *testing.T) {
+		// act
+		_, err := rp.FindAll(ctx)
+		version, _, _ := rp.Version(ctx)
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, first, version)
+	})
+
+	t.Run("case - writes that succeed and bumps make a new version", func(t *testing.T) {
+		// act
+		errSave := rp.Save(ctx, &internal.Vehicle{Id: 11, VehicleAttributes: internal.VehicleAttributes{Registration: "REG-11"}})
+		afterSave, _, _ := rp.Version(ctx)
+		errDelete := rp.Delete(ctx, 11)
+		afterDelete, _, _ := rp.Version(ctx)
+		rp.Bump()
+		afterBump, _, _ := rp.Version(ctx)
+		// assert
+		require.NoError(t, errSave)
+		require.NoError(t, errDelete)
+		require.Equal(t, first+1, afterSave)
+		require.Equal(t, first+2, afterDelete)
+		require.Equal(t, first+3, afterBump)
+	})
+
+	t.Run("case error, failed writes keep the version", func(t *testing.T) {
+		// arrange
+		before, _, _ := rp.Version(ctx)
+		// act
+		err := rp.Delete(ctx, 11)
+		version, _, _ := rp.Version(ctx)
+		// assert
+		require.ErrorIs(t, err, internal.ErrRepositoryVehicleNotFound)
+		require.Equal(t, before, version)
+	})
+}
@@ -1,107 +1,605 @@
 package service
 
-import "app/internal"
+import (
+	"app/internal"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
 
 // ServiceVehicleDefault is a struct that represents the default service for vehicles
 type ServiceVehicleDefault struct {
 	// rp is the repository that will be used by the service
-	rp internal.RepositoryReadVehicle
+	rp internal.RepositoryVehicle
+	// vd is the validator of the writes, the one the records of the datasets are checked with on load
+	vd *internal.VehicleValidator
+	// matrix is the matrix of the vehicles, used to find similar ones
+	matrix derived[*internal.VehicleMatrix]
 }
 
 // NewServiceVehicleDefault is a function that returns a new instance of ServiceVehicleDefault
-func NewServiceVehicleDefault(rp internal.RepositoryReadVehicle) *ServiceVehicleDefault {
-	return &ServiceVehicleDefault{rp: rp}
+func NewServiceVehicleDefault(rp internal.RepositoryVehicle) *ServiceVehicleDefault {
+	return &ServiceVehicleDefault{
+		rp:     rp,
+		vd:     internal.NewVehicleValidatorDefault(),
+		matrix: derived[*internal.VehicleMatrix]{build: internal.NewVehicleMatrix},
+	}
 }
 
-// FindByColorAndYear is a method that returns a map of vehicles that match the color and fabrication year
-func (s *ServiceVehicleDefault) FindByColorAndYear(color string, fabricationYear int) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.FindByColorAndYear(color, fabricationYear)
-	//code added for better control error
+// FindByColorAndYear is a method that returns a list of vehicles that match the color and fabrication year
+// - the color is matched as the text match mode of ctx says (see internal.TextMatchFromContext)
+func (s *ServiceVehicleDefault) FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error) {
+	colors, err := s.resolveText(ctx, textQuery{attribute: "color", value: color})
 	if err != nil {
 		return
 	}
+
+	for _, c := range colors[0] {
+		var found []internal.Vehicle
+		found, err = s.rp.FindByColorAndYear(ctx, c, fabricationYear)
+		//code added for better control error
+		if err != nil {
+			return
+		}
+		v = append(v, found...)
+	}
 	if len(v) == 0 {
 		err = internal.ErrServiceNoVehicles
 		return
 	}
+	if len(colors[0]) > 1 {
+		sortVehiclesById(v)
+	}
 	return
 }
 
-// FindByBrandAndYearRange is a method that returns a map of vehicles that match the brand and a range of fabrication years
-func (s *ServiceVehicleDefault) FindByBrandAndYearRange(brand string, startYear int, endYear int) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.FindByBrandAndYearRange(brand, startYear, endYear)
-	//code added for better control error
+// FindByBrandAndYearRange is a method that returns a list of vehicles that match the brand and a range of fabrication years
+// - the brand is matched as the text match mode of ctx says (see internal.TextMatchFromContext)
+func (s *ServiceVehicleDefault) FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error) {
+	brands, err := s.resolveText(ctx, textQuery{attribute: "brand", value: brand})
 	if err != nil {
 		return
 	}
+
+	for _, b := range brands[0] {
+		var found []internal.Vehicle
+		found, err = s.rp.FindByBrandAndYearRange(ctx, b, startYear, endYear)
+		//code added for better control error
+		if err != nil {
+			return
+		}
+		v = append(v, found...)
+	}
 	if len(v) == 0 {
 		err = internal.ErrServiceNoVehicles
 		return
 	}
+	if len(brands[0]) > 1 {
+		sortVehiclesById(v)
+	}
 	return
 }
 
 // AverageMaxSpeedByBrand is a method that returns the average speed of the vehicles by brand
-func (s *ServiceVehicleDefault) AverageMaxSpeedByBrand(brand string) (a float64, err error) {
-	// get vehicles by brand
-	v, err := s.rp.FindByBrand(brand)
+func (s *ServiceVehicleDefault) AverageMaxSpeedByBrand(ctx context.Context, brand string) (a float64, err error) {
+	a, err = s.averageByBrand(ctx, brand, "max_speed")
+	return
+}
+
+// AverageCapacityByBrand is a method that returns the average capacity of the vehicles by brand
+func (s *ServiceVehicleDefault) AverageCapacityByBrand(ctx context.Context, brand string) (a float64, err error) {
+	a, err = s.averageByBrand(ctx, brand, "capacity")
+	return
+}
+
+// averageByBrand is a method that returns the average of a metric of the vehicles by brand
+func (s *ServiceVehicleDefault) averageByBrand(ctx context.Context, brand string, metric string) (a float64, err error) {
+	groups, err := s.Stats(
+		ctx,
+		internal.VehicleFilter{Brand: &brand},
+		internal.StatsQuery{Metric: metric, Aggregates: []string{"avg"}},
+	)
 	if err != nil {
 		return
 	}
 
-	// check if there are vehicles
+	a = groups[0].Values[0]
+	return
+}
+
+// SearchByWeightRange
+func (s *ServiceVehicleDefault) SearchByWeightRange(ctx context.Context, query internal.SearchQuery, ok bool) (v []internal.Vehicle, err error) {
+	// check if query is set
+	if !ok {
+		v, err = s.rp.FindAll(ctx)
+		return
+	}
+
+	v, err = s.rp.FindByWeightRange(ctx, query.FromWeight, query.ToWeight)
+	if err != nil {
+		return
+	}
 	if len(v) == 0 {
 		err = internal.ErrServiceNoVehicles
 		return
 	}
+	return
+}
 
-	var totalSpeed float64
-	for _, vehicle := range v {
-		totalSpeed += vehicle.MaxSpeed
+// Search is a method that returns a list of vehicles that match every set field of the filter
+func (s *ServiceVehicleDefault) Search(ctx context.Context, filter internal.VehicleFilter) (v []internal.Vehicle, err error) {
+	// check if filter is set
+	if filter.IsEmpty() {
+		v, err = s.rp.FindAll(ctx)
+		return
+	}
+
+	// validate filter
+	err = filter.Validate()
+	if err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrServiceInvalidSearch, err)
+		return
 	}
 
-	a = totalSpeed / float64(len(v))
+	// text fields: the values of the vehicles they match
+	filter, err = s.resolveFilter(ctx, filter)
+	if err != nil {
+		return
+	}
+	v, err = s.rp.FindByFilter(ctx, filter)
+	if err != nil {
+		return
+	}
+	if len(v) == 0 {
+		err = internal.ErrServiceNoVehicles
+		return
+	}
 	return
 }
 
-// AverageCapacityByBrand is a method that returns the average capacity of the vehicles by brand
-func (s *ServiceVehicleDefault) AverageCapacityByBrand(brand string) (a int, err error) {
-	// get vehicles by brand
-	v, err := s.rp.FindByBrand(brand)
+// Stream is a method that returns an iteration over the vehicles that match every set field of the filter
+// - same rules as Search: the filter is validated, its text fields resolved, and a set filter that matches no vehicle is an error
+func (s *ServiceVehicleDefault) Stream(ctx context.Context, filter internal.VehicleFilter) (it internal.VehicleIterator, err error) {
+	// check if filter is set
+	if filter.IsEmpty() {
+		it, err = s.rp.Iterate(ctx, filter)
+		return
+	}
+
+	// validate filter
+	err = filter.Validate()
+	if err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrServiceInvalidSearch, err)
+		return
+	}
+
+	// text fields: the values of the vehicles they match
+	filter, err = s.resolveFilter(ctx, filter)
 	if err != nil {
 		return
 	}
+	it, err = s.rp.Iterate(ctx, filter)
+	if err != nil {
+		return
+	}
+	if it.Total() == 0 {
+		_ = it.Close()
+		it, err = nil, internal.ErrServiceNoVehicles
+		return
+	}
+	return
+}
+
+// Page is a method that returns the page of the vehicles that match every set field of the filter, sorted and paged as the query says
+// - same rules as Search: the filter is validated, its text fields resolved, and a set filter that matches no vehicle is an error
+// - the repository sorts and pages the vehicles, only the ones of the page are read
+func (s *ServiceVehicleDefault) Page(ctx context.Context, filter internal.VehicleFilter, q internal.PageQuery) (v []internal.Vehicle, p internal.Page, err error) {
+	// check if filter is set
+	if filter.IsEmpty() {
+		v, p, err = s.rp.FindPage(ctx, filter, q)
+		return
+	}
+
+	// validate filter
+	err = filter.Validate()
+	if err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrServiceInvalidSearch, err)
+		return
+	}
 
-	// check if there are vehicles
+	// text fields: the values of the vehicles they match
+	filter, err = s.resolveFilter(ctx, filter)
+	if err != nil {
+		return
+	}
+	v, p, err = s.rp.FindPage(ctx, filter, q)
+	if err != nil {
+		return
+	}
+	if p.Total == 0 {
+		v, p, err = nil, internal.Page{}, internal.ErrServiceNoVehicles
+		return
+	}
+	return
+}
+
+// Stats is a method that returns the aggregates of a metric per group of the vehicles that match the filter
+func (s *ServiceVehicleDefault) Stats(ctx context.Context, filter internal.VehicleFilter, query internal.StatsQuery) (groups []internal.StatsGroup, err error) {
+	// validate query
+	err = query.Validate()
+	if err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrServiceInvalidStats, err)
+		return
+	}
+
+	// vehicles: same rules as Search, but an empty result is always an error (there is nothing to aggregate)
+	v, err := s.Search(ctx, filter)
+	if err != nil {
+		return
+	}
 	if len(v) == 0 {
 		err = internal.ErrServiceNoVehicles
 		return
 	}
 
-	var totalCapacity int
-	for _, vehicle := range v {
-		totalCapacity += vehicle.Capacity
+	groups, err = internal.ComputeVehicleStats(v, query)
+	return
+}
+
+// Compare is a method that returns the vehicles of the ids aligned attribute by attribute
+func (s *ServiceVehicleDefault) Compare(ctx context.Context, ids []int) (c internal.VehicleComparison, err error) {
+	// validate ids
+	err = internal.ValidateCompareIds(ids)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrServiceInvalidCompare, err)
+		return
 	}
 
-	a = totalCapacity / len(v)
+	// vehicles: only the ones of the ids are read, in the order of the ids, every missing one is reported
+	found, err := s.rp.FindByFilter(ctx, internal.VehicleFilter{Ids: ids})
+	if err != nil {
+		return
+	}
+	byId := make(map[int]internal.Vehicle, len(found))
+	for _, v := range found {
+		byId[v.Id] = v
+	}
+	v := make([]internal.Vehicle, 0, len(ids))
+	var missing []int
+	for _, id := range ids {
+		vh, ok := byId[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		v = append(v, vh)
+	}
+	if len(missing) > 0 {
+		err = &internal.VehiclesNotFoundError{Ids: missing}
+		return
+	}
+
+	c = internal.NewVehicleComparison(v)
 	return
 }
 
-// SearchByWeightRange
-func (s *ServiceVehicleDefault) SearchByWeightRange(query internal.SearchQuery, ok bool) (v map[int]internal.Vehicle, err error) {
-	// check if query is set
-	if !ok {
-		v, err = s.rp.FindAll()
+// Similar is a method that returns the vehicles most like the vehicle of the id, nearest first
+func (s *ServiceVehicleDefault) Similar(ctx context.Context, id int, query internal.SimilarQuery) (matches []internal.SimilarVehicle, err error) {
+	// validate query
+	err = query.Validate()
+	if err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrServiceInvalidSimilar, err)
 		return
 	}
 
-	v, err = s.rp.FindByWeightRange(query.FromWeight, query.ToWeight)
+	m, err := s.matrix.get(ctx, s.rp)
 	if err != nil {
 		return
 	}
-	if len(v) == 0 {
+	matches, ok := m.Similar(id, query)
+	if !ok {
+		err = internal.ErrServiceVehicleNotFound
+		return
+	}
+	if len(matches) == 0 {
 		err = internal.ErrServiceNoVehicles
 		return
 	}
 	return
 }
+
+// textQuery is a struct that represents the value of a text attribute asked by a query
+type textQuery struct {
+	// attribute is the name of the attribute of internal.VehicleTextAttributes
+	attribute string
+	// value is the value asked
+	value string
+}
+
+// resolveText is a method that returns the values of the vehicles each text query matches, in the order of the queries
+// - the values are matched as the text match mode of ctx says (see internal.TextMatchFromContext)
+// - the values of the vehicles are the current ones of the repository, found per attribute of the queries
+// - a query that matches no value is an error: internal.NoVehiclesError if the vehicles have values like it,
+// internal.ErrServiceNoVehicles otherwise
+func (s *ServiceVehicleDefault) resolveText(ctx context.Context, queries ...textQuery) (values [][]string, err error) {
+	known := make(map[string][]string, len(queries))
+	for _, q := range queries {
+		if _, ok := known[q.attribute]; ok {
+			continue
+		}
+		known[q.attribute], err = s.rp.FindValues(ctx, q.attribute)
+		if err != nil {
+			return
+		}
+	}
+	vc := internal.NewVehicleVocabulary(known)
+
+	m := internal.TextMatchFromContext(ctx)
+	values = make([][]string, len(queries))
+	var unknown bool
+	var suggestions []internal.TextSuggestion
+	for i, q := range queries {
+		values[i] = vc.Resolve(q.attribute, q.value, m)
+		if len(values[i]) > 0 {
+			continue
+		}
+		unknown = true
+		if sg := vc.Suggest(q.attribute, q.value); len(sg) > 0 {
+			suggestions = append(suggestions, internal.TextSuggestion{Attribute: q.attribute, Value: q.value, Suggestions: sg})
+		}
+	}
+	switch {
+	case len(suggestions) > 0:
+		values, err = nil, &internal.NoVehiclesError{Suggestions: suggestions}
+	case unknown:
+		values, err = nil, internal.ErrServiceNoVehicles
+	}
+	return
+}
+
+// resolveFilter is a method that returns the filter with the values of the vehicles its text fields match
+// - a text field that matches a single value stays an exact match, one that matches several is replaced by the set of them,
+// so the repository finds the vehicles in a single pass
+func (s *ServiceVehicleDefault) resolveFilter(ctx context.Context, filter internal.VehicleFilter) (resolved internal.VehicleFilter, err error) {
+	fields := []struct {
+		attribute string
+		value     func(f *internal.VehicleFilter) **string
+		values    func(f *internal.VehicleFilter) *[]string
+	}{
+		{"brand", func(f *internal.VehicleFilter) **string { return &f.Brand }, func(f *internal.VehicleFilter) *[]string { return &f.Brands }},
+		{"model", func(f *internal.VehicleFilter) **string { return &f.Model }, func(f *internal.VehicleFilter) *[]string { return &f.Models }},
+		{"color", func(f *internal.VehicleFilter) **string { return &f.Color }, func(f *internal.VehicleFilter) *[]string { return &f.Colors }},
+		{"fuel_type", func(f *internal.VehicleFilter) **string { return &f.FuelType }, func(f *internal.VehicleFilter) *[]string { return &f.FuelTypes }},
+		{"transmission", func(f *internal.VehicleFilter) **string { return &f.Transmission }, func(f *internal.VehicleFilter) *[]string { return &f.Transmissions }},
+	}
+
+	var queries []textQuery
+	var set []int
+	for i, field := range fields {
+		if value := *field.value(&filter); value != nil {
+			queries = append(queries, textQuery{attribute: field.attribute, value: *value})
+			set = append(set, i)
+		}
+	}
+	resolved = filter
+	if len(queries) == 0 {
+		return
+	}
+
+	values, err := s.resolveText(ctx, queries...)
+	if err != nil {
+		resolved = internal.VehicleFilter{}
+		return
+	}
+	for i, field := range set {
+		if len(values[i]) == 1 {
+			*fields[field].value(&resolved) = &values[i][0]
+			continue
+		}
+		*fields[field].value(&resolved) = nil
+		*fields[field].values(&resolved) = values[i]
+	}
+	return
+}
+
+// sortVehiclesById sorts the vehicles found by several queries of the repository by id
+func sortVehiclesById(v []internal.Vehicle) {
+	slices.SortFunc(v, func(a, b internal.Vehicle) int { return cmp.Compare(a.Id, b.Id) })
+}
+
+// derived is a struct that represents a value built from all the vehicles of the repository
+// - a versioned repository gets the value built once per version, any other one once per call
+type derived[T any] struct {
+	// build is the function that builds the value
+	build func(v []internal.Vehicle) T
+	// mu is the mutex that protects the value
+	mu sync.Mutex
+	// value is the value of the vehicles of version
+	value T
+	// version is the version of the vehicles of the value
+	version uint64
+	// built is true once the value has been built
+	built bool
+}
+
+// get is a method that returns the value of the vehicles of the repository
+func (d *derived[T]) get(ctx context.Context, rp internal.RepositoryVehicle) (value T, err error) {
+	var v []internal.Vehicle
+	rpVersioned, versioned := rp.(internal.RepositoryVersionedVehicle)
+	if !versioned {
+		v, err = rp.FindAll(ctx)
+		if err != nil {
+			return
+		}
+		value = d.build(v)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// - the version is taken before the vehicles: a write meanwhile makes the next call build it again
+	version, _, err := rpVersioned.Version(ctx)
+	if err != nil {
+		return
+	}
+	if !d.built || d.version != version {
+		v, err = rp.FindAll(ctx)
+		if err != nil {
+			return
+		}
+		d.value, d.version, d.built = d.build(v), version, true
+	}
+	value = d.value
+	return
+}
+
+// Save is a method that saves a new vehicle
+func (s *ServiceVehicleDefault) Save(ctx context.Context, v *internal.Vehicle) (err error) {
+	// validate vehicle
+	if v.Id <= 0 {
+		err = fmt.Errorf("%w: id must be positive", internal.ErrServiceInvalidVehicle)
+		return
+	}
+	err = validateVehicleAttributes(v.VehicleAttributes)
+	if err != nil {
+		return
+	}
+	err = s.validate(*v)
+	if err != nil {
+		return
+	}
+
+	err = s.rp.Save(ctx, v)
+	if err != nil {
+		err = serviceWriteError(err)
+		return
+	}
+	return
+}
+
+// Update is a method that replaces the attributes of an existing vehicle
+func (s *ServiceVehicleDefault) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	// validate vehicle
+	err = validateVehicleAttributes(v.VehicleAttributes)
+	if err != nil {
+		return
+	}
+	err = s.validate(*v)
+	if err != nil {
+		return
+	}
+
+	err = s.rp.Update(ctx, v)
+	if err != nil {
+		err = serviceWriteError(err)
+		return
+	}
+	return
+}
+
+// Patch is a method that updates only the given attributes of an existing vehicle
+func (s *ServiceVehicleDefault) Patch(ctx context.Context, id int, patch internal.VehicleAttributesPatch) (v internal.Vehicle, err error) {
+	// validate patch: required attributes can not be cleared
+	switch {
+	case patch.Brand != nil && *patch.Brand == "":
+		err = fmt.Errorf("%w: brand is required", internal.ErrServiceInvalidVehicle)
+		return
+	case patch.Model != nil && *patch.Model == "":
+		err = fmt.Errorf("%w: model is required", internal.ErrServiceInvalidVehicle)
+		return
+	case patch.Registration != nil && *patch.Registration == "":
+		err = fmt.Errorf("%w: registration is required", internal.ErrServiceInvalidVehicle)
+		return
+	}
+	// - the validated attributes are checked one by one, so the ones of the patch are checked on their own
+	if fields := patchedFields(patch); len(fields) > 0 {
+		patched := internal.Vehicle{Id: id}
+		patch.Apply(&patched.VehicleAttributes)
+		err = s.validate(patched, fields...)
+		if err != nil {
+			return
+		}
+	}
+
+	v, err = s.rp.Patch(ctx, id, patch)
+	if err != nil {
+		err = serviceWriteError(err)
+		return
+	}
+	return
+}
+
+// Delete is a method that deletes a vehicle
+func (s *ServiceVehicleDefault) Delete(ctx context.Context, id int) (err error) {
+	err = s.rp.Delete(ctx, id)
+	if err != nil {
+		err = serviceWriteError(err)
+		return
+	}
+	return
+}
+
+// validateVehicleAttributes checks that the required attributes of a vehicle are set
+func validateVehicleAttributes(a internal.VehicleAttributes) (err error) {
+	switch {
+	case a.Brand == "":
+		err = fmt.Errorf("%w: brand is required", internal.ErrServiceInvalidVehicle)
+	case a.Model == "":
+		err = fmt.Errorf("%w: model is required", internal.ErrServiceInvalidVehicle)
+	case a.Registration == "":
+		err = fmt.Errorf("%w: registration is required", internal.ErrServiceInvalidVehicle)
+	}
+	return
+}
+
+// validate is a method that checks a vehicle with the validator of the service
+// - only the problems of the given fields are checked (every field if none is given)
+// - errors fail the write with an *internal.ValidationReportError (wrapped by internal.ErrServiceInvalidVehicle), warnings do not
+func (s *ServiceVehicleDefault) validate(v internal.Vehicle, fields ...string) (err error) {
+	_, report := s.vd.Validate([]internal.Vehicle{v})
+	var problems []internal.ValidationProblem
+	for _, p := range report.Problems {
+		if p.Severity == internal.ValidationError && (len(fields) == 0 || slices.Contains(fields, p.Field)) {
+			problems = append(problems, p)
+		}
+	}
+	if len(problems) == 0 {
+		return
+	}
+	report.Skipped, report.Problems = 1, problems
+	err = fmt.Errorf("%w: %w", internal.ErrServiceInvalidVehicle, &internal.ValidationReportError{Report: report})
+	return
+}
+
+// patchedFields returns the names of the fields of the validator set by a patch
+func patchedFields(p internal.VehicleAttributesPatch) (fields []string) {
+	for field, set := range map[string]bool{
+		"registration": p.Registration != nil,
+		"year":         p.FabricationYear != nil,
+		"weight":       p.Weight != nil,
+		"height":       p.Height != nil,
+		"length":       p.Length != nil,
+		"width":        p.Width != nil,
+		"fuel_type":    p.FuelType != nil,
+		"transmission": p.Transmission != nil,
+	} {
+		if set {
+			fields = append(fields, field)
+		}
+	}
+	return
+}
+
+// serviceWriteError translates a repository write error into a service error
+func serviceWriteError(err error) error {
+	switch {
+	case errors.Is(err, internal.ErrRepositoryVehicleNotFound):
+		return fmt.Errorf("%w: %w", internal.ErrServiceVehicleNotFound, err)
+	case errors.Is(err, internal.ErrRepositoryVehicleDuplicated), errors.Is(err, internal.ErrRepositoryRegistrationDuplicated):
+		return fmt.Errorf("%w: %w", internal.ErrServiceVehicleConflict, err)
+	}
+	return err
+}
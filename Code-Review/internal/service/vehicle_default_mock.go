@@ -2,6 +2,7 @@ package service
 
 import (
 	"app/internal"
+	"context"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -13,51 +14,113 @@ func NewServiceVehicleDefaultMock() *Mock {
 
 type Mock struct {
 	mock.Mock
-	FuncFindByColorAndYear      func(color string, fabricationYear int) (v map[int]internal.Vehicle, err error)
-	FuncFindByBrandAndYearRange func(brand string, startYear int, endYear int) (v map[int]internal.Vehicle, err error)
-	FuncAverageMaxSpeedByBrand  func(brand string) (a float64, err error)
-	FuncAverageCapacityByBrand  func(brand string) (a int, err error)
-	FuncSearchByWeightRange     func(startWeight int, endWeight int) (v map[int]internal.Vehicle, err error)
+	FuncFindByColorAndYear      func(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error)
+	FuncFindByBrandAndYearRange func(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error)
+	FuncAverageMaxSpeedByBrand  func(ctx context.Context, brand string) (a float64, err error)
+	FuncAverageCapacityByBrand  func(ctx context.Context, brand string) (a float64, err error)
+	FuncSearchByWeightRange     func(ctx context.Context, startWeight int, endWeight int) (v []internal.Vehicle, err error)
 }
 
-// FindByColorAndYear is a method that returns a map of vehicles that match the color and fabrication year
-func (m *Mock) FindByColorAndYear(color string, fabricationYear int) (v map[int]internal.Vehicle, err error) {
-	args := m.Called(color, fabricationYear)
+// FindByColorAndYear is a method that returns a list of vehicles that match the color and fabrication year
+func (m *Mock) FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx, color, fabricationYear)
 	if m.FuncFindByColorAndYear != nil {
-		return m.FuncFindByColorAndYear(color, fabricationYear)
+		return m.FuncFindByColorAndYear(ctx, color, fabricationYear)
 	}
-	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
 }
 
-// FindByBrandAndYearRange is a method that returns a map of vehicles that match the brand and a range of fabrication years
-func (m *Mock) FindByBrandAndYearRange(brand string, startYear int, endYear int) (v map[int]internal.Vehicle, err error) {
-	args := m.Called(brand, startYear, endYear)
+// FindByBrandAndYearRange is a method that returns a list of vehicles that match the brand and a range of fabrication years
+func (m *Mock) FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx, brand, startYear, endYear)
 	if m.FuncFindByBrandAndYearRange != nil {
-		return m.FuncFindByBrandAndYearRange(brand, startYear, endYear)
+		return m.FuncFindByBrandAndYearRange(ctx, brand, startYear, endYear)
 	}
-	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
 }
 
 // AverageMaxSpeedByBrand is a method that returns the average speed of the vehicles by brand
-func (m *Mock) AverageMaxSpeedByBrand(brand string) (a float64, err error) {
-	args := m.Called(brand)
+func (m *Mock) AverageMaxSpeedByBrand(ctx context.Context, brand string) (a float64, err error) {
+	args := m.Called(ctx, brand)
 	if m.FuncAverageMaxSpeedByBrand != nil {
-		return m.FuncAverageMaxSpeedByBrand(brand)
+		return m.FuncAverageMaxSpeedByBrand(ctx, brand)
 	}
 	return args.Get(0).(float64), args.Error(1)
 }
 
 // AverageCapacityByBrand is a method that returns the average capacity of the vehicles by brand
-func (m *Mock) AverageCapacityByBrand(brand string) (a int, err error) {
-	args := m.Called(brand)
+func (m *Mock) AverageCapacityByBrand(ctx context.Context, brand string) (a float64, err error) {
+	args := m.Called(ctx, brand)
 	if m.FuncAverageCapacityByBrand != nil {
-		return m.FuncAverageCapacityByBrand(brand)
+		return m.FuncAverageCapacityByBrand(ctx, brand)
 	}
-	return args.Get(0).(int), args.Error(1)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+// FindByWeightRange is a method that returns a list of vehicles that match the weight range
+func (m *Mock) SearchByWeightRange(ctx context.Context, query internal.SearchQuery, ok bool) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx, query, ok)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
+}
+
+// Search is a method that returns a list of vehicles that match every set field of the filter
+func (m *Mock) Search(ctx context.Context, filter internal.VehicleFilter) (v []internal.Vehicle, err error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]internal.Vehicle), args.Error(1)
+}
+
+// Stream is a method that returns an iteration over the vehicles that match every set field of the filter
+// - the iteration may be nil (e.g. with an error)
+func (m *Mock) Stream(ctx context.Context, filter internal.VehicleFilter) (it internal.VehicleIterator, err error) {
+	args := m.Called(ctx, filter)
+	it, _ = args.Get(0).(internal.VehicleIterator)
+	return it, args.Error(1)
+}
+
+// Page is a method that returns the page of the vehicles that match every set field of the filter, sorted and paged as the query says
+func (m *Mock) Page(ctx context.Context, filter internal.VehicleFilter, q internal.PageQuery) (v []internal.Vehicle, p internal.Page, err error) {
+	args := m.Called(ctx, filter, q)
+	return args.Get(0).([]internal.Vehicle), args.Get(1).(internal.Page), args.Error(2)
+}
+
+// Stats is a method that returns the aggregates of a metric per group of the vehicles that match the filter
+func (m *Mock) Stats(ctx context.Context, filter internal.VehicleFilter, query internal.StatsQuery) (groups []internal.StatsGroup, err error) {
+	args := m.Called(ctx, filter, query)
+	return args.Get(0).([]internal.StatsGroup), args.Error(1)
+}
+
+// Compare is a method that returns the vehicles of the ids aligned attribute by attribute
+func (m *Mock) Compare(ctx context.Context, ids []int) (c internal.VehicleComparison, err error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).(internal.VehicleComparison), args.Error(1)
+}
+
+// Similar is a method that returns the vehicles most like the vehicle of the id, nearest first
+func (m *Mock) Similar(ctx context.Context, id int, query internal.SimilarQuery) (matches []internal.SimilarVehicle, err error) {
+	args := m.Called(ctx, id, query)
+	return args.Get(0).([]internal.SimilarVehicle), args.Error(1)
+}
+
+// Save is a method that saves a new vehicle
+func (m *Mock) Save(ctx context.Context, v *internal.Vehicle) (err error) {
+	args := m.Called(ctx, v)
+	return args.Error(0)
+}
+
+// Update is a method that replaces the attributes of an existing vehicle
+func (m *Mock) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	args := m.Called(ctx, v)
+	return args.Error(0)
+}
+
+// Patch is a method that updates only the given attributes of an existing vehicle
+func (m *Mock) Patch(ctx context.Context, id int, patch internal.VehicleAttributesPatch) (v internal.Vehicle, err error) {
+	args := m.Called(ctx, id, patch)
+	return args.Get(0).(internal.Vehicle), args.Error(1)
 }
 
-// FindByWeightRange is a method that returns a map of vehicles that match the weight range
-func (m *Mock) SearchByWeightRange(query internal.SearchQuery, ok bool) (v map[int]internal.Vehicle, err error) {
-	args := m.Called(query, ok)
-	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+// Delete is a method that deletes a vehicle
+func (m *Mock) Delete(ctx context.Context, id int) (err error) {
+	args := m.Called(ctx, id)
+	return args.Error(0)
 }
@@ -4,14 +4,17 @@ import (
 	"app/internal"
 	"app/internal/repository"
 	"app/internal/service"
+	"context"
+	"math"
 	"testing"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
-// VehicleMap is a map of vehicles
-var VehicleMap = map[int]internal.Vehicle{
-	1: {
+// Vehicles is a list of vehicles
+var Vehicles = []internal.Vehicle{
+	{
 		Id: 1,
 		VehicleAttributes: internal.VehicleAttributes{
 			Brand:           "Ford",
@@ -37,11 +40,12 @@ func TestServiceVehicleDefault_FindByColorAndYear(t *testing.T) {
 	t.Run("success, vehicles found", func(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindByColorAndYear", "red", 2010).Return(VehicleMap, nil)
+		rp.On("FindValues", mock.Anything, "color").Return([]string{"red"}, nil)
+		rp.On("FindByColorAndYear", mock.Anything, "red", 2010).Return(Vehicles, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		vehicles, err := sv.FindByColorAndYear("red", 2010)
+		vehicles, err := sv.FindByColorAndYear(context.Background(), "red", 2010)
 		// assert
 		require.NoError(t, err)
 		require.Len(t, vehicles, 1)
@@ -53,9 +57,10 @@ func TestServiceVehicleDefault_FindByColorAndYear(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryMock()
 		sv := service.NewServiceVehicleDefault(rp)
-		rp.On("FindByColorAndYear", "red", 2010).Return(map[int]internal.Vehicle{}, nil)
+		rp.On("FindValues", mock.Anything, "color").Return([]string{"red"}, nil)
+		rp.On("FindByColorAndYear", mock.Anything, "red", 2010).Return([]internal.Vehicle{}, nil)
 		// act
-		v, err := sv.FindByColorAndYear("red", 2010)
+		v, err := sv.FindByColorAndYear(context.Background(), "red", 2010)
 		// assert
 		require.Error(t, err)
 		require.Len(t, v, 0)
@@ -68,11 +73,12 @@ func TestServiceVehicleDefault_FindByBrandAndYearRange(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		// arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindByBrandAndYearRange", "Ford", 2010, 2015).Return(VehicleMap, nil)
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("FindByBrandAndYearRange", mock.Anything, "Ford", 2010, 2015).Return(Vehicles, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		vehicles, err := sv.FindByBrandAndYearRange("Ford", 2010, 2015)
+		vehicles, err := sv.FindByBrandAndYearRange(context.Background(), "Ford", 2010, 2015)
 		// assert
 		require.NoError(t, err)
 		require.Len(t, vehicles, 1)
@@ -82,11 +88,12 @@ func TestServiceVehicleDefault_FindByBrandAndYearRange(t *testing.T) {
 	t.Run("error - no vehicles", func(t *testing.T) {
 		//arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindByBrandAndYearRange", "Ford", 2010, 2015).Return(map[int]internal.Vehicle{}, nil)
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("FindByBrandAndYearRange", mock.Anything, "Ford", 2010, 2015).Return([]internal.Vehicle{}, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		vehicles, err := sv.FindByBrandAndYearRange("Ford", 2010, 2015)
+		vehicles, err := sv.FindByBrandAndYearRange(context.Background(), "Ford", 2010, 2015)
 		// assert
 		require.Error(t, err)
 		require.Len(t, vehicles, 0)
@@ -98,11 +105,13 @@ func TestServiceVehicleDefault_AverageMaxSpeedByBrand(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		//arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindByBrand", "Ford").Return(VehicleMap, nil)
+		brand := "Ford"
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("FindByFilter", mock.Anything, internal.VehicleFilter{Brand: &brand}).Return(Vehicles, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		average, err := sv.AverageMaxSpeedByBrand("Ford")
+		average, err := sv.AverageMaxSpeedByBrand(context.Background(), "Ford")
 		// assert
 		require.NoError(t, err)
 		require.Equal(t, 180.0, average)
@@ -113,11 +122,13 @@ func TestServiceVehicleDefault_AverageMaxSpeedByBrand(t *testing.T) {
 	t.Run("error - no vehicles", func(t *testing.T) {
 		//arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindByBrand", "Ford").Return(map[int]internal.Vehicle{}, nil)
+		brand := "Ford"
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("FindByFilter", mock.Anything, internal.VehicleFilter{Brand: &brand}).Return([]internal.Vehicle{}, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		average, err := sv.AverageMaxSpeedByBrand("Ford")
+		average, err := sv.AverageMaxSpeedByBrand(context.Background(), "Ford")
 		// assert
 		require.Error(t, err)
 		require.Equal(t, 0.0, average)
@@ -131,44 +142,425 @@ func TestServiceVehicleDefault_AverageCapacityByBrand(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		//arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindByBrand", "Ford").Return(VehicleMap, nil)
+		brand := "Ford"
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("FindByFilter", mock.Anything, internal.VehicleFilter{Brand: &brand}).Return(Vehicles, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		average, err := sv.AverageCapacityByBrand("Ford")
+		average, err := sv.AverageCapacityByBrand(context.Background(), "Ford")
 		// assert
 		require.NoError(t, err)
-		require.Equal(t, 5, average)
+		require.Equal(t, 5.0, average)
 		rp.AssertExpectations(t)
 
 	})
 
+	t.Run("success - average is not truncated", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		brand := "Ford"
+		vehicles := []internal.Vehicle{
+			{Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Capacity: 5}},
+			{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Capacity: 2}},
+		}
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("FindByFilter", mock.Anything, internal.VehicleFilter{Brand: &brand}).Return(vehicles, nil)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		average, err := sv.AverageCapacityByBrand(context.Background(), "Ford")
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 3.5, average)
+		rp.AssertExpectations(t)
+	})
+
 	t.Run("error - no vehicles", func(t *testing.T) {
 		//arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindByBrand", "Ford").Return(map[int]internal.Vehicle{}, nil)
+		brand := "Ford"
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("FindByFilter", mock.Anything, internal.VehicleFilter{Brand: &brand}).Return([]internal.Vehicle{}, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		average, err := sv.AverageCapacityByBrand("Ford")
+		average, err := sv.AverageCapacityByBrand(context.Background(), "Ford")
 		// assert
 		require.Error(t, err)
-		require.Equal(t, 0, average)
+		require.Equal(t, 0.0, average)
 		require.EqualError(t, err, "service: no vehicles")
 		rp.AssertExpectations(t)
 
 	})
 }
 
+func TestServiceVehicleDefault_Stats(t *testing.T) {
+	vehicles := []internal.Vehicle{
+		{Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", FuelType: "gas", MaxSpeed: 100}},
+		{Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", FuelType: "gas", MaxSpeed: 200}},
+		{Id: 3, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", FuelType: "gas", MaxSpeed: 400}},
+		{Id: 4, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", FuelType: "diesel", MaxSpeed: 150}},
+		{Id: 5, VehicleAttributes: internal.VehicleAttributes{Brand: "Audi", FuelType: "gas", MaxSpeed: 250}},
+	}
+
+	t.Run("success - grouped aggregates ordered by group", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindAll", mock.Anything, mock.Anything).Return(vehicles, nil)
+		sv := service.NewServiceVehicleDefault(rp)
+		query := internal.StatsQuery{
+			GroupBy:    []string{"brand", "fuel_type"},
+			Metric:     "max_speed",
+			Aggregates: []string{"count", "sum", "avg", "min", "max", "p50", "p95"},
+		}
+		// act
+		groups, err := sv.Stats(context.Background(), internal.VehicleFilter{}, query)
+		// assert
+		require.NoError(t, err)
+		expectedGroups := []internal.StatsGroup{
+			{Key: []string{"Audi", "gas"}, Values: []float64{1, 250, 250, 250, 250, 250, 250}},
+			{Key: []string{"Ford", "diesel"}, Values: []float64{1, 150, 150, 150, 150, 150, 150}},
+			{Key: []string{"Ford", "gas"}, Values: []float64{3, 700, 700.0 / 3, 100, 400, 200, 380}},
+		}
+		require.Equal(t, expectedGroups, groups)
+		rp.AssertExpectations(t)
+	})
+
+	t.Run("success - no group_by is a single group", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindAll", mock.Anything, mock.Anything).Return(vehicles, nil)
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		groups, err := sv.Stats(context.Background(), internal.VehicleFilter{}, internal.StatsQuery{Metric: "max_speed", Aggregates: []string{"count", "p25"}})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, []internal.StatsGroup{{Key: []string{}, Values: []float64{5, 150}}}, groups)
+	})
+
+	t.Run("error - invalid query", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		groups, err := sv.Stats(context.Background(), internal.VehicleFilter{}, internal.StatsQuery{Metric: "max_speed", Aggregates: []string{"p101"}})
+		// assert
+		require.Nil(t, groups)
+		require.ErrorIs(t, err, internal.ErrServiceInvalidStats)
+		require.ErrorIs(t, err, internal.ErrStatsInvalid)
+		rp.AssertNotCalled(t, "FindAll")
+	})
+
+	t.Run("error - no vehicles", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindAll", mock.Anything, mock.Anything).Return([]internal.Vehicle{}, nil)
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		groups, err := sv.Stats(context.Background(), internal.VehicleFilter{}, internal.StatsQuery{Metric: "weight", Aggregates: []string{"avg"}})
+		// assert
+		require.Nil(t, groups)
+		require.ErrorIs(t, err, internal.ErrServiceNoVehicles)
+	})
+}
+
+func TestServiceVehicleDefault_Compare(t *testing.T) {
+	vehicles := []internal.Vehicle{
+		{Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", FabricationYear: 2010, MaxSpeed: 180, Weight: 1000}},
+		{Id: 7, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", FabricationYear: 2015, MaxSpeed: 200, Weight: 1200}},
+		{Id: 42, VehicleAttributes: internal.VehicleAttributes{Brand: "GMC", FabricationYear: 2015, MaxSpeed: 150, Weight: 900}},
+	}
+
+	t.Run("success - aligned in the order of the ids, ranked", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindByFilter", mock.Anything, internal.VehicleFilter{Ids: []int{42, 1, 7}}).Return(vehicles, nil)
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		c, err := sv.Compare(context.Background(), []int{42, 1, 7})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, []internal.Vehicle{vehicles[2], vehicles[0], vehicles[1]}, c.Vehicles)
+		require.Equal(t, internal.ComparedText{Name: "brand", Values: []string{"GMC", "Ford", "Ford"}, Differ: true}, c.Texts[0])
+		require.Equal(t, internal.ComparedText{Name: "model", Values: []string{"", "", ""}, Differ: false}, c.Texts[1])
+		expectedNumbers := map[string]internal.ComparedNumber{
+			"year": {Name: "year", Values: []float64{2015, 2010, 2015}, Differ: true, Best: internal.CompareBestMax,
+				Ranks: []int{1, 3, 1}, Deltas: []float64{0, 5, 0}},
+			"max_speed": {Name: "max_speed", Values: []float64{150, 180, 200}, Differ: true, Best: internal.CompareBestMax,
+				Ranks: []int{3, 2, 1}, Deltas: []float64{50, 20, 0}},
+			"weight": {Name: "weight", Values: []float64{900, 1000, 1200}, Differ: true, Best: internal.CompareBestMin,
+				Ranks: []int{1, 2, 3}, Deltas: []float64{0, 100, 300}},
+			"capacity": {Name: "capacity", Values: []float64{0, 0, 0}, Differ: false, Best: internal.CompareBestMax,
+				Ranks: []int{1, 1, 1}, Deltas: []float64{0, 0, 0}},
+		}
+		for _, n := range c.Numbers {
+			if expected, ok := expectedNumbers[n.Name]; ok {
+				require.Equal(t, expected, n, n.Name)
+			}
+		}
+		require.Len(t, c.Numbers, len(internal.VehicleCompareNumbers))
+	})
+
+	t.Run("error - invalid ids", func(t *testing.T) {
+		for name, c := range map[string]struct {
+			ids    []int
+			reason string
+		}{
+			"one id":       {[]int{1}, "expected between 2 and 10 ids, got 1"},
+			"too many ids": {[]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, "expected between 2 and 10 ids, got 11"},
+			"repeated id":  {[]int{1, 7, 1}, "id 1 is repeated"},
+			"negative id":  {[]int{1, -7}, "id -7 is not positive"},
+		} {
+			// arrange
+			rp := repository.NewRepositoryMock()
+			sv := service.NewServiceVehicleDefault(rp)
+			// act
+			_, err := sv.Compare(context.Background(), c.ids)
+			// assert
+			require.ErrorIs(t, err, internal.ErrServiceInvalidCompare, name)
+			require.ErrorIs(t, err, internal.ErrCompareInvalid, name)
+			var compareErr *internal.CompareError
+			require.ErrorAs(t, err, &compareErr, name)
+			require.Equal(t, c.reason, compareErr.Reason, name)
+			rp.AssertNotCalled(t, "FindByFilter")
+		}
+	})
+
+	t.Run("error - missing ids are listed", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindByFilter", mock.Anything, internal.VehicleFilter{Ids: []int{3, 1, 99}}).Return(vehicles[:1], nil)
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		_, err := sv.Compare(context.Background(), []int{3, 1, 99})
+		// assert
+		var notFoundErr *internal.VehiclesNotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		require.Equal(t, []int{3, 99}, notFoundErr.Ids)
+		require.ErrorIs(t, err, internal.ErrServiceVehicleNotFound)
+	})
+}
+
+func TestServiceVehicleDefault_Similar(t *testing.T) {
+	// only max_speed and weight differ, normalised: max_speed 0, 0.5, 1, 1 and weight 0, 1, 0, 1
+	vehicles := []internal.Vehicle{
+		{Id: 1, VehicleAttributes: internal.VehicleAttributes{FuelType: "gas", MaxSpeed: 100, Weight: 1000}},
+		{Id: 2, VehicleAttributes: internal.VehicleAttributes{FuelType: "gas", MaxSpeed: 150, Weight: 2000}},
+		{Id: 3, VehicleAttributes: internal.VehicleAttributes{FuelType: "diesel", MaxSpeed: 200, Weight: 1000}},
+		{Id: 4, VehicleAttributes: internal.VehicleAttributes{FuelType: "gas", MaxSpeed: 200, Weight: 2000}},
+	}
+	// distances is a function that returns the id and the distance of each match
+	distances := func(matches []internal.SimilarVehicle) map[int]float64 {
+		d := make(map[int]float64, len(matches))
+		for _, m := range matches {
+			d[m.Id] = m.Distance
+		}
+		return d
+	}
+
+	t.Run("success - nearest first, weighted", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindAll", mock.Anything).Return(vehicles, nil)
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		even, errEven := sv.Similar(context.Background(), 1, internal.SimilarQuery{K: 3})
+		speed, errSpeed := sv.Similar(context.Background(), 1, internal.SimilarQuery{K: 2, Weights: map[string]float64{"weight": 0}})
+		// assert
+		require.NoError(t, errEven)
+		require.Equal(t, []int{3, 2, 4}, []int{even[0].Id, even[1].Id, even[2].Id})
+		require.InDeltaMapValues(t, map[int]float64{2: 0.4226, 3: 0.3780, 4: 0.5345}, distances(even), 1e-4)
+		require.NoError(t, errSpeed)
+		require.Len(t, speed, 2)
+		require.Equal(t, 2, speed[0].Id)
+		require.InDelta(t, 0.5/math.Sqrt(6), speed[0].Distance, 1e-9)
+	})
+
+	t.Run("success - same fuel type", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindAll", mock.Anything).Return(vehicles, nil)
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		matches, err := sv.Similar(context.Background(), 1, internal.SimilarQuery{K: 5, Same: []string{"fuel_type"}})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, []int{2, 4}, []int{matches[0].Id, matches[1].Id})
+	})
+
+	t.Run("success - the matrix of a versioned repository is built once per version", func(t *testing.T) {
+		// arrange
+		rpMock := repository.NewRepositoryMock()
+		rpMock.On("FindAll", mock.Anything).Return(vehicles, nil)
+		rp := repository.NewRepositoryVehicleVersioned(rpMock)
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		_, errFirst := sv.Similar(context.Background(), 1, internal.SimilarQuery{K: 1})
+		_, errSecond := sv.Similar(context.Background(), 2, internal.SimilarQuery{K: 1})
+		rp.Bump()
+		_, errThird := sv.Similar(context.Background(), 3, internal.SimilarQuery{K: 1})
+		// assert
+		require.NoError(t, errFirst)
+		require.NoError(t, errSecond)
+		require.NoError(t, errThird)
+		rpMock.AssertNumberOfCalls(t, "FindAll", 2)
+	})
+
+	t.Run("error - invalid query", func(t *testing.T) {
+		for name, query := range map[string]internal.SimilarQuery{
+			"k":              {K: 0},
+			"unknown weight": {K: 1, Weights: map[string]float64{"brand": 1}},
+			"negative":       {K: 1, Weights: map[string]float64{"weight": -1}},
+			"every weight 0": {K: 1, Weights: map[string]float64{"year": 0, "capacity": 0, "max_speed": 0, "weight": 0, "height": 0, "length": 0, "width": 0}},
+			"unknown same":   {K: 1, Same: []string{"color"}},
+		} {
+			// arrange
+			rp := repository.NewRepositoryMock()
+			sv := service.NewServiceVehicleDefault(rp)
+			// act
+			_, err := sv.Similar(context.Background(), 1, query)
+			// assert
+			require.ErrorIs(t, err, internal.ErrServiceInvalidSimilar, name)
+			require.ErrorIs(t, err, internal.ErrSimilarInvalid, name)
+			rp.AssertNotCalled(t, "FindAll")
+		}
+	})
+
+	t.Run("error - vehicle not found", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindAll", mock.Anything).Return(vehicles, nil)
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		_, err := sv.Similar(context.Background(), 99, internal.SimilarQuery{K: 1})
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceVehicleNotFound)
+	})
+
+	t.Run("error - no similar vehicles", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindAll", mock.Anything).Return(vehicles, nil)
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		_, err := sv.Similar(context.Background(), 3, internal.SimilarQuery{K: 1, Same: []string{"fuel_type"}})
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceNoVehicles)
+	})
+}
+
+func TestServiceVehicleDefault_TextMatch(t *testing.T) {
+	db := map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Chevrolet", Color: "red", FabricationYear: 2010, FuelType: "gas"}},
+		2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "CHEVROLET ", Color: "Red", FabricationYear: 2010, FuelType: "diesel"}},
+		3: {Id: 3, VehicleAttributes: internal.VehicleAttributes{Brand: "Citroën", Color: "blue", FabricationYear: 2012, FuelType: "gas"}},
+		4: {Id: 4, VehicleAttributes: internal.VehicleAttributes{Brand: "Chrysler", Color: "red", FabricationYear: 2011, FuelType: "gas"}},
+	}
+
+	t.Run("success - case, spaces and accents are normalized", func(t *testing.T) {
+		cases := []struct {
+			brand string
+			ids   []int
+		}{
+			{"chevrolet", []int{1, 2}},
+			{"  Chevrolet", []int{1, 2}},
+			{"citroen", []int{3}},
+			{"CITROËN", []int{3}},
+			{"Citroe\u0308n", []int{3}},
+		}
+		for _, c := range cases {
+			// arrange
+			sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+			// act
+			v, err := sv.FindByBrandAndYearRange(context.Background(), c.brand, 2000, 2020)
+			// assert
+			require.NoError(t, err, c.brand)
+			ids := []int{}
+			for _, vh := range v {
+				ids = append(ids, vh.Id)
+			}
+			require.Equal(t, c.ids, ids, c.brand)
+		}
+	})
+
+	t.Run("success - every finder normalizes", func(t *testing.T) {
+		// arrange
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+		brand, fuelType := "chevrolet", "GAS"
+		// act
+		byColor, errByColor := sv.FindByColorAndYear(context.Background(), "RED", 2010)
+		searched, errSearched := sv.Search(context.Background(), internal.VehicleFilter{Brand: &brand, FuelType: &fuelType})
+		// assert
+		require.NoError(t, errByColor)
+		require.Equal(t, []int{1, 2}, []int{byColor[0].Id, byColor[1].Id})
+		require.NoError(t, errSearched)
+		require.Len(t, searched, 1)
+		require.Equal(t, 1, searched[0].Id)
+	})
+
+	t.Run("success - fuzzy match allows typos", func(t *testing.T) {
+		// arrange
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+		ctx := internal.NewTextMatchContext(context.Background(), internal.TextMatchFuzzy)
+		// act
+		v, err := sv.FindByBrandAndYearRange(ctx, "chevrolte", 2000, 2020)
+		// assert
+		require.NoError(t, err)
+		require.Len(t, v, 2)
+	})
+
+	t.Run("success - the values are the current ones of the repository", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryVehicleIndexed(db)
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleVersioned(rp))
+		_, err := sv.FindByBrandAndYearRange(context.Background(), "chevrolet", 2000, 2020)
+		require.NoError(t, err)
+		// - a write the versioned repository does not see, as the ones of other processes on a database
+		v := internal.Vehicle{Id: 5, VehicleAttributes: internal.VehicleAttributes{Brand: "Škoda", Registration: "SK-5", FabricationYear: 2015}}
+		require.NoError(t, rp.Save(context.Background(), &v))
+		// act
+		found, err := sv.FindByBrandAndYearRange(context.Background(), "skoda", 2000, 2020)
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, []internal.Vehicle{v}, found)
+	})
+
+	t.Run("error - no vehicles, with suggestions", func(t *testing.T) {
+		// arrange
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+		// act
+		_, err := sv.FindByBrandAndYearRange(context.Background(), "chevrolte", 2000, 2020)
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceNoVehicles)
+		var noVehicles *internal.NoVehiclesError
+		require.ErrorAs(t, err, &noVehicles)
+		expectedSuggestions := []internal.TextSuggestion{
+			{Attribute: "brand", Value: "chevrolte", Suggestions: []string{"CHEVROLET"}},
+		}
+		require.Equal(t, expectedSuggestions, noVehicles.Suggestions)
+		require.EqualError(t, err, "service: no vehicles, did you mean brand CHEVROLET")
+	})
+
+	t.Run("error - no vehicles, nothing alike", func(t *testing.T) {
+		// arrange
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+		// act
+		_, err := sv.FindByColorAndYear(context.Background(), "purple", 2010)
+		// assert
+		require.EqualError(t, err, "service: no vehicles")
+	})
+}
+
 func TestServiceVehicleDefault_SearchByWeightRange(t *testing.T) {
 	t.Run("case - query !ok then find all", func(t *testing.T) {
 		//arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindAll").Return(VehicleMap, nil)
+		rp.On("FindAll", mock.Anything, mock.Anything).Return(Vehicles, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		vehicles, err := sv.SearchByWeightRange(internal.SearchQuery{}, false)
+		vehicles, err := sv.SearchByWeightRange(context.Background(), internal.SearchQuery{}, false)
 		// assert
 		require.NoError(t, err)
 		require.Len(t, vehicles, 1)
@@ -179,11 +571,11 @@ func TestServiceVehicleDefault_SearchByWeightRange(t *testing.T) {
 	t.Run("case - query ok then find by weight range", func(t *testing.T) {
 		//arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindByWeightRange", 1000.0, 2000.0).Return(VehicleMap, nil)
+		rp.On("FindByWeightRange", mock.Anything, 1000.0, 2000.0).Return(Vehicles, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		vehicles, err := sv.SearchByWeightRange(internal.SearchQuery{
+		vehicles, err := sv.SearchByWeightRange(context.Background(), internal.SearchQuery{
 			FromWeight: 1000.0,
 			ToWeight:   2000.0,
 		}, true)
@@ -197,11 +589,11 @@ func TestServiceVehicleDefault_SearchByWeightRange(t *testing.T) {
 	t.Run("case - error - no vehicles", func(t *testing.T) {
 		//arrange
 		rp := repository.NewRepositoryMock()
-		rp.On("FindByWeightRange", 1000.0, 2000.0).Return(map[int]internal.Vehicle{}, nil)
+		rp.On("FindByWeightRange", mock.Anything, 1000.0, 2000.0).Return([]internal.Vehicle{}, nil)
 
 		sv := service.NewServiceVehicleDefault(rp)
 		// act
-		vehicles, err := sv.SearchByWeightRange(internal.SearchQuery{
+		vehicles, err := sv.SearchByWeightRange(context.Background(), internal.SearchQuery{
 			FromWeight: 1000.0,
 			ToWeight:   2000.0,
 		}, true)
@@ -214,3 +606,371 @@ func TestServiceVehicleDefault_SearchByWeightRange(t *testing.T) {
 	})
 
 }
+
+func TestServiceVehicleDefault_Search(t *testing.T) {
+	t.Run("case - empty filter then find all", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("FindAll", mock.Anything, mock.Anything).Return(Vehicles, nil)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		vehicles, err := sv.Search(context.Background(), internal.VehicleFilter{})
+		// assert
+		require.NoError(t, err)
+		require.Len(t, vehicles, 1)
+		rp.AssertExpectations(t)
+	})
+
+	t.Run("case - filter then find by filter", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		brand := "Ford"
+		filter := internal.VehicleFilter{Brand: &brand}
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("FindByFilter", mock.Anything, filter).Return(Vehicles, nil)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		vehicles, err := sv.Search(context.Background(), filter)
+		// assert
+		require.NoError(t, err)
+		require.Len(t, vehicles, 1)
+		rp.AssertExpectations(t)
+	})
+
+	t.Run("case - a text field that matches several values is found in a single pass", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		brand, color := "chevrolet", "red"
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"CHEVROLET ", "Chevrolet", "Ford"}, nil)
+		rp.On("FindValues", mock.Anything, "color").Return([]string{"red"}, nil)
+		rp.On("FindByFilter", mock.Anything, internal.VehicleFilter{Brands: []string{"CHEVROLET ", "Chevrolet"}, Color: &color}).Return(Vehicles, nil)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		vehicles, err := sv.Search(context.Background(), internal.VehicleFilter{Brand: &brand, Color: &color})
+		// assert
+		require.NoError(t, err)
+		require.Len(t, vehicles, 1)
+		rp.AssertNumberOfCalls(t, "FindByFilter", 1)
+	})
+
+	t.Run("case - error - min above max", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		min, max := 2000.0, 1000.0
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		_, err := sv.Search(context.Background(), internal.VehicleFilter{Weight: internal.Range[float64]{Min: &min, Max: &max}})
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceInvalidSearch)
+		var fieldErr *internal.FilterFieldError
+		require.ErrorAs(t, err, &fieldErr)
+		require.Equal(t, "weight", fieldErr.Field)
+		rp.AssertNotCalled(t, "FindByFilter")
+	})
+
+	t.Run("case - error - no vehicles", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		brand := "Fiat"
+		filter := internal.VehicleFilter{Brand: &brand}
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Fiat"}, nil)
+		rp.On("FindByFilter", mock.Anything, filter).Return([]internal.Vehicle{}, nil)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		_, err := sv.Search(context.Background(), filter)
+		// assert
+		require.EqualError(t, err, "service: no vehicles")
+		rp.AssertExpectations(t)
+	})
+}
+
+func TestServiceVehicleDefault_Stream(t *testing.T) {
+	db := map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Chevrolet", Color: "red"}},
+		2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "CHEVROLET ", Color: "red"}},
+		3: {Id: 3, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Color: "blue"}},
+	}
+
+	// ids returns the ids of the vehicles of an iteration, closing it
+	ids := func(it internal.VehicleIterator) (ids []int) {
+		defer it.Close()
+		for it.Next() {
+			ids = append(ids, it.Vehicle().Id)
+		}
+		return
+	}
+
+	t.Run("case - empty filter then iterate all", func(t *testing.T) {
+		//arrange
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+		// act
+		it, err := sv.Stream(context.Background(), internal.VehicleFilter{})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 3, it.Total())
+		require.Equal(t, []int{1, 2, 3}, ids(it))
+	})
+
+	t.Run("case - filter then iterate the repository with the resolved values", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		brand, resolved := "ford", "Ford"
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("Iterate", mock.Anything, internal.VehicleFilter{Brand: &resolved}).Return(internal.NewVehicleIteratorSlice(Vehicles), nil)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		it, err := sv.Stream(context.Background(), internal.VehicleFilter{Brand: &brand})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, []int{1}, ids(it))
+		rp.AssertExpectations(t)
+	})
+
+	t.Run("case - several resolved values are iterated in a single pass", func(t *testing.T) {
+		//arrange
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+		brand := "chevrolet"
+		// act
+		it, err := sv.Stream(context.Background(), internal.VehicleFilter{Brand: &brand})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 2, it.Total())
+		require.Equal(t, []int{1, 2}, ids(it))
+	})
+
+	t.Run("case - error - min above max", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		min, max := 2000.0, 1000.0
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		it, err := sv.Stream(context.Background(), internal.VehicleFilter{Weight: internal.Range[float64]{Min: &min, Max: &max}})
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceInvalidSearch)
+		require.Nil(t, it)
+		rp.AssertNotCalled(t, "Iterate")
+	})
+
+	t.Run("case - error - no vehicles", func(t *testing.T) {
+		//arrange
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+		brand, color := "Ford", "red"
+		// act
+		it, err := sv.Stream(context.Background(), internal.VehicleFilter{Brand: &brand, Color: &color})
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceNoVehicles)
+		require.Nil(t, it)
+	})
+}
+
+func TestServiceVehicleDefault_Page(t *testing.T) {
+	db := map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Chevrolet", Color: "red"}},
+		2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "CHEVROLET ", Color: "red"}},
+		3: {Id: 3, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Color: "blue"}},
+	}
+
+	t.Run("case - empty filter then the page of all", func(t *testing.T) {
+		//arrange
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+		// act
+		v, p, err := sv.Page(context.Background(), internal.VehicleFilter{}, internal.PageQuery{Sort: []internal.SortField{{Name: "id", Desc: true}}, Limit: 2})
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, []internal.Vehicle{db[3], db[2]}, v)
+		require.Equal(t, internal.Page{Total: 3, Next: &v[1]}, p)
+	})
+
+	t.Run("case - filter then the page of the repository with the resolved values", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		brand, resolved := "ford", "Ford"
+		pq := internal.PageQuery{Limit: 1}
+		rp.On("FindValues", mock.Anything, "brand").Return([]string{"Ford"}, nil)
+		rp.On("FindPage", mock.Anything, internal.VehicleFilter{Brand: &resolved}, pq).Return(Vehicles, internal.Page{Total: 1}, nil)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		v, p, err := sv.Page(context.Background(), internal.VehicleFilter{Brand: &brand}, pq)
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, Vehicles, v)
+		require.Equal(t, internal.Page{Total: 1}, p)
+		rp.AssertExpectations(t)
+	})
+
+	t.Run("case - error - min above max", func(t *testing.T) {
+		//arrange
+		rp := repository.NewRepositoryMock()
+		min, max := 2000.0, 1000.0
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		_, _, err := sv.Page(context.Background(), internal.VehicleFilter{Weight: internal.Range[float64]{Min: &min, Max: &max}}, internal.PageQuery{})
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceInvalidSearch)
+		rp.AssertNotCalled(t, "FindPage")
+	})
+
+	t.Run("case - error - no vehicles", func(t *testing.T) {
+		//arrange
+		sv := service.NewServiceVehicleDefault(repository.NewRepositoryVehicleIndexed(db))
+		brand, color := "Ford", "red"
+		// act
+		v, _, err := sv.Page(context.Background(), internal.VehicleFilter{Brand: &brand, Color: &color}, internal.PageQuery{})
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceNoVehicles)
+		require.Nil(t, v)
+	})
+}
+
+func TestServiceVehicleDefault_Save(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		v := Vehicles[0]
+		rp.On("Save", mock.Anything, &v).Return(nil)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		err := sv.Save(context.Background(), &v)
+		// assert
+		require.NoError(t, err)
+		rp.AssertExpectations(t)
+	})
+
+	t.Run("error - invalid vehicle", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		v := internal.Vehicle{Id: 2}
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		err := sv.Save(context.Background(), &v)
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceInvalidVehicle)
+		rp.AssertNotCalled(t, "Save")
+	})
+
+	t.Run("error - attributes that break the rules of the vehicles", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		v := Vehicles[0]
+		v.Weight, v.FuelType = -1, "steam"
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		err := sv.Save(context.Background(), &v)
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceInvalidVehicle)
+		var reportErr *internal.ValidationReportError
+		require.ErrorAs(t, err, &reportErr)
+		expectedProblems := []internal.ValidationProblem{
+			{Index: 0, Id: 1, Field: "weight", Message: "-1 is not positive", Severity: internal.ValidationError},
+			{Index: 0, Id: 1, Field: "fuel_type", Message: `"steam" is unknown`, Severity: internal.ValidationError},
+		}
+		require.Equal(t, expectedProblems, reportErr.Report.Problems)
+		rp.AssertNotCalled(t, "Save")
+	})
+
+	t.Run("error - duplicated", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		v := Vehicles[0]
+		rp.On("Save", mock.Anything, &v).Return(internal.ErrRepositoryVehicleDuplicated)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		err := sv.Save(context.Background(), &v)
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceVehicleConflict)
+		rp.AssertExpectations(t)
+	})
+}
+
+func TestServiceVehicleDefault_Update(t *testing.T) {
+	t.Run("error - not found", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		v := Vehicles[0]
+		rp.On("Update", mock.Anything, &v).Return(internal.ErrRepositoryVehicleNotFound)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		err := sv.Update(context.Background(), &v)
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceVehicleNotFound)
+		rp.AssertExpectations(t)
+	})
+}
+
+func TestServiceVehicleDefault_Patch(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		color := "red"
+		patch := internal.VehicleAttributesPatch{Color: &color}
+		rp.On("Patch", mock.Anything, 1, patch).Return(Vehicles[0], nil)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		v, err := sv.Patch(context.Background(), 1, patch)
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, Vehicles[0], v)
+		rp.AssertExpectations(t)
+	})
+
+	t.Run("error - clearing a required attribute", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		registration := ""
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		_, err := sv.Patch(context.Background(), 1, internal.VehicleAttributesPatch{Registration: &registration})
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceInvalidVehicle)
+		rp.AssertNotCalled(t, "Patch")
+	})
+
+	t.Run("error - patched attributes that break the rules of the vehicles", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		year, transmission := 1800, "cvt"
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		_, err := sv.Patch(context.Background(), 1, internal.VehicleAttributesPatch{FabricationYear: &year, Transmission: &transmission})
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceInvalidVehicle)
+		var reportErr *internal.ValidationReportError
+		require.ErrorAs(t, err, &reportErr)
+		require.Len(t, reportErr.Report.Problems, 2, "only the patched attributes are checked")
+		require.Equal(t, "year", reportErr.Report.Problems[0].Field)
+		require.Equal(t, "transmission", reportErr.Report.Problems[1].Field)
+		rp.AssertNotCalled(t, "Patch")
+	})
+}
+
+func TestServiceVehicleDefault_Delete(t *testing.T) {
+	t.Run("error - not found", func(t *testing.T) {
+		// arrange
+		rp := repository.NewRepositoryMock()
+		rp.On("Delete", mock.Anything, 2).Return(internal.ErrRepositoryVehicleNotFound)
+
+		sv := service.NewServiceVehicleDefault(rp)
+		// act
+		err := sv.Delete(context.Background(), 2)
+		// assert
+		require.ErrorIs(t, err, internal.ErrServiceVehicleNotFound)
+		rp.AssertExpectations(t)
+	})
+}
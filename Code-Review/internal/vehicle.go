@@ -2,11 +2,11 @@ package internal
 
 // Dimensions is a struct that represents a dimension in 3d
 type Dimensions struct {
-	// Height is the height of the dimension
+	// Height is the height of the dimension, in cm (see UnitsCanonical)
 	Height float64
-	// Length is the length of the dimension
+	// Length is the length of the dimension, in cm
 	Length float64
-	// Width is the width of the dimension
+	// Width is the width of the dimension, in cm
 	Width float64
 }
 
@@ -24,13 +24,13 @@ type VehicleAttributes struct {
 	FabricationYear int
 	// Capacity is the capacity of people of the vehicle
 	Capacity int
-	// MaxSpeed is the maximum speed of the vehicle
+	// MaxSpeed is the maximum speed of the vehicle, in km/h (see UnitsCanonical)
 	MaxSpeed float64
 	// FuelType is the fuel type of the vehicle
 	FuelType string
 	// Transmission is the transmission of the vehicle
 	Transmission string
-	// Weight is the weight of the vehicle
+	// Weight is the weight of the vehicle, in kg (see UnitsCanonical)
 	Weight float64
 	// Dimensions is the dimensions of the vehicle
 	Dimensions
@@ -44,3 +44,77 @@ type Vehicle struct {
 	// VehicleAttribue is the attributes of a vehicle
 	VehicleAttributes
 }
+
+// VehicleAttributesPatch is a struct that represents a partial update of the attributes of a vehicle
+// - nil fields are left untouched
+type VehicleAttributesPatch struct {
+	// Brand is the brand of the vehicle
+	Brand *string
+	// Model is the model of the vehicle
+	Model *string
+	// Registration is the registration of the vehicle
+	Registration *string
+	// Color is the color of the vehicle
+	Color *string
+	// FabricationYear is the fabrication year of the vehicle
+	FabricationYear *int
+	// Capacity is the capacity of people of the vehicle
+	Capacity *int
+	// MaxSpeed is the maximum speed of the vehicle
+	MaxSpeed *float64
+	// FuelType is the fuel type of the vehicle
+	FuelType *string
+	// Transmission is the transmission of the vehicle
+	Transmission *string
+	// Weight is the weight of the vehicle
+	Weight *float64
+	// Height is the height of the vehicle
+	Height *float64
+	// Length is the length of the vehicle
+	Length *float64
+	// Width is the width of the vehicle
+	Width *float64
+}
+
+// Apply is a method that applies the patch over the given attributes
+func (p VehicleAttributesPatch) Apply(a *VehicleAttributes) {
+	if p.Brand != nil {
+		a.Brand = *p.Brand
+	}
+	if p.Model != nil {
+		a.Model = *p.Model
+	}
+	if p.Registration != nil {
+		a.Registration = *p.Registration
+	}
+	if p.Color != nil {
+		a.Color = *p.Color
+	}
+	if p.FabricationYear != nil {
+		a.FabricationYear = *p.FabricationYear
+	}
+	if p.Capacity != nil {
+		a.Capacity = *p.Capacity
+	}
+	if p.MaxSpeed != nil {
+		a.MaxSpeed = *p.MaxSpeed
+	}
+	if p.FuelType != nil {
+		a.FuelType = *p.FuelType
+	}
+	if p.Transmission != nil {
+		a.Transmission = *p.Transmission
+	}
+	if p.Weight != nil {
+		a.Weight = *p.Weight
+	}
+	if p.Height != nil {
+		a.Height = *p.Height
+	}
+	if p.Length != nil {
+		a.Length = *p.Length
+	}
+	if p.Width != nil {
+		a.Width = *p.Width
+	}
+}
@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrCompareInvalid is an error that represents an invalid comparison
+	ErrCompareInvalid = errors.New("compare: invalid ids")
+)
+
+const (
+	// CompareMinIds is the minimum number of vehicles of a comparison
+	CompareMinIds = 2
+	// CompareMaxIds is the maximum number of vehicles of a comparison
+	CompareMaxIds = 10
+)
+
+// CompareBest is the value of a numeric attribute that ranks first: the highest or the lowest
+type CompareBest string
+
+const (
+	// CompareBestMax ranks the highest value first
+	CompareBestMax CompareBest = "max"
+	// CompareBestMin ranks the lowest value first
+	CompareBestMin CompareBest = "min"
+)
+
+// VehicleCompareTexts is the list of text attributes of a comparison, in order
+var VehicleCompareTexts = []string{"brand", "model", "registration", "color", "fuel_type", "transmission"}
+
+// vehicleCompareTexts is the value of each text attribute of a comparison, by name
+var vehicleCompareTexts = map[string]func(v Vehicle) string{
+	"brand":        func(v Vehicle) string { return v.Brand },
+	"model":        func(v Vehicle) string { return v.Model },
+	"registration": func(v Vehicle) string { return v.Registration },
+	"color":        func(v Vehicle) string { return v.Color },
+	"fuel_type":    func(v Vehicle) string { return v.FuelType },
+	"transmission": func(v Vehicle) string { return v.Transmission },
+}
+
+// VehicleCompareNumbers is the list of numeric attributes of a comparison (see VehicleStatsMetrics), in order
+var VehicleCompareNumbers = []string{"year", "capacity", "max_speed", "weight", "height", "length", "width"}
+
+// VehicleCompareBest is the best value of each numeric attribute of a comparison, by name
+// - newer, roomier and faster is better; lighter and smaller is better
+var VehicleCompareBest = map[string]CompareBest{
+	"year":      CompareBestMax,
+	"capacity":  CompareBestMax,
+	"max_speed": CompareBestMax,
+	"weight":    CompareBestMin,
+	"height":    CompareBestMin,
+	"length":    CompareBestMin,
+	"width":     CompareBestMin,
+}
+
+// CompareError is an error that represents ids that can not be compared
+type CompareError struct {
+	// Reason is why the ids can not be compared
+	Reason string
+}
+
+// Error returns the message of the error
+func (e *CompareError) Error() string {
+	return ErrCompareInvalid.Error() + ": " + e.Reason
+}
+
+// Unwrap returns ErrCompareInvalid so callers can check the error with errors.Is
+func (e *CompareError) Unwrap() error {
+	return ErrCompareInvalid
+}
+
+// ValidateCompareIds returns a *CompareError if the ids can not be compared
+// - between CompareMinIds and CompareMaxIds ids, positive and without repetitions
+func ValidateCompareIds(ids []int) (err error) {
+	if len(ids) < CompareMinIds || len(ids) > CompareMaxIds {
+		return &CompareError{Reason: fmt.Sprintf("expected between %d and %d ids, got %d", CompareMinIds, CompareMaxIds, len(ids))}
+	}
+	for i, id := range ids {
+		if id <= 0 {
+			return &CompareError{Reason: fmt.Sprintf("id %d is not positive", id)}
+		}
+		if slices.Contains(ids[:i], id) {
+			return &CompareError{Reason: fmt.Sprintf("id %d is repeated", id)}
+		}
+	}
+	return
+}
+
+// VehiclesNotFoundError is an error that represents some vehicles that were not found
+type VehiclesNotFoundError struct {
+	// Ids is the list of ids that were not found, in the order they were asked
+	Ids []int
+}
+
+// Error returns the message of the error
+func (e *VehiclesNotFoundError) Error() string {
+	ids := make([]string, len(e.Ids))
+	for i, id := range e.Ids {
+		ids[i] = strconv.Itoa(id)
+	}
+	return "vehicles not found: " + strings.Join(ids, ", ")
+}
+
+// Unwrap returns ErrServiceVehicleNotFound so callers can check the error with errors.Is
+func (e *VehiclesNotFoundError) Unwrap() error {
+	return ErrServiceVehicleNotFound
+}
+
+// VehicleComparison is a struct that represents some vehicles aligned attribute by attribute
+type VehicleComparison struct {
+	// Vehicles is the list of compared vehicles, in the order they were asked
+	Vehicles []Vehicle
+	// Texts is the comparison of each attribute of VehicleCompareTexts, in the same order
+	Texts []ComparedText
+	// Numbers is the comparison of each attribute of VehicleCompareNumbers, in the same order
+	Numbers []ComparedNumber
+}
+
+// ComparedText is a struct that represents a text attribute of the compared vehicles
+type ComparedText struct {
+	// Name is the name of the attribute
+	Name string
+	// Values is the value of each vehicle, in the same order
+	Values []string
+	// Differ is true if any two vehicles have different values
+	Differ bool
+}
+
+// ComparedNumber is a struct that represents a numeric attribute of the compared vehicles
+type ComparedNumber struct {
+	// Name is the name of the attribute
+	Name string
+	// Values is the value of each vehicle, in the same order
+	Values []float64
+	// Differ is true if any two vehicles have different values
+	Differ bool
+	// Best is the value that ranks first
+	Best CompareBest
+	// Ranks is the rank of each vehicle, from 1 (equal values share the rank, the next one is skipped: 1, 1, 3)
+	Ranks []int
+	// Deltas is the distance of each vehicle to the best value (0 for the best)
+	Deltas []float64
+}
+
+// NewVehicleComparison is a function that aligns the attributes of the vehicles, ranking the numeric ones
+func NewVehicleComparison(v []Vehicle) (c VehicleComparison) {
+	c.Vehicles = v
+	c.Texts = make([]ComparedText, 0, len(VehicleCompareTexts))
+	for _, name := range VehicleCompareTexts {
+		ct := ComparedText{Name: name, Values: make([]string, len(v))}
+		for i, vh := range v {
+			ct.Values[i] = vehicleCompareTexts[name](vh)
+			ct.Differ = ct.Differ || ct.Values[i] != ct.Values[0]
+		}
+		c.Texts = append(c.Texts, ct)
+	}
+
+	c.Numbers = make([]ComparedNumber, 0, len(VehicleCompareNumbers))
+	for _, name := range VehicleCompareNumbers {
+		cn := ComparedNumber{Name: name, Values: make([]float64, len(v)), Best: VehicleCompareBest[name]}
+		for i, vh := range v {
+			cn.Values[i] = VehicleStatsMetrics[name](vh)
+			cn.Differ = cn.Differ || cn.Values[i] != cn.Values[0]
+		}
+		cn.Ranks, cn.Deltas = compareRanks(cn.Values, cn.Best)
+		c.Numbers = append(c.Numbers, cn)
+	}
+	return
+}
+
+// compareRanks is a function that returns the rank of each value and its distance to the best one
+func compareRanks(values []float64, best CompareBest) (ranks []int, deltas []float64) {
+	// better returns true if a ranks before b
+	better := func(a, b float64) bool { return a > b }
+	if best == CompareBestMin {
+		better = func(a, b float64) bool { return a < b }
+	}
+
+	top := values[0]
+	for _, value := range values[1:] {
+		if better(value, top) {
+			top = value
+		}
+	}
+
+	ranks, deltas = make([]int, len(values)), make([]float64, len(values))
+	for i, value := range values {
+		ranks[i] = 1
+		for _, other := range values {
+			if better(other, value) {
+				ranks[i]++
+			}
+		}
+		deltas[i] = math.Abs(value - top)
+	}
+	return
+}
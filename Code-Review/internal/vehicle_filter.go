@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	// ErrFilterInvalid is an error that represents an invalid filter
+	ErrFilterInvalid = errors.New("filter: invalid")
+)
+
+// FilterFieldError is an error that represents an invalid field of a filter
+type FilterFieldError struct {
+	// Field is the name of the invalid field
+	Field string
+	// Message is the reason why the field is invalid
+	Message string
+}
+
+// Error returns the message of the error
+func (e *FilterFieldError) Error() string {
+	return fmt.Sprintf("filter: invalid %s: %s", e.Field, e.Message)
+}
+
+// Unwrap returns ErrFilterInvalid so callers can check the error with errors.Is
+func (e *FilterFieldError) Unwrap() error {
+	return ErrFilterInvalid
+}
+
+// Range is a struct that represents an open-ended range of values
+// - nil bounds are not checked
+type Range[T int | float64] struct {
+	// Min is the minimum value (inclusive)
+	Min *T
+	// Max is the maximum value (inclusive)
+	Max *T
+}
+
+// IsSet returns true if at least one of the bounds is set
+func (r Range[T]) IsSet() bool {
+	return r.Min != nil || r.Max != nil
+}
+
+// Contains returns true if the value is within the range
+func (r Range[T]) Contains(value T) bool {
+	if r.Min != nil && value < *r.Min {
+		return false
+	}
+	if r.Max != nil && value > *r.Max {
+		return false
+	}
+	return true
+}
+
+// validate returns a FilterFieldError if the minimum is above the maximum
+func (r Range[T]) validate(field string) (err error) {
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		err = &FilterFieldError{Field: field, Message: "min is greater than max"}
+	}
+	return
+}
+
+// VehicleFilter is a struct that represents a composable filter over the attributes of a vehicle
+// - all set fields must match (and), unset fields are not filtered
+// - the ids and a text attribute can also be matched against a set of values (any of them), nil sets are not filtered and empty ones match nothing
+type VehicleFilter struct {
+	// Ids is the set of ids the vehicle has one of
+	Ids []int
+	// Brand is the exact brand of the vehicle
+	Brand *string
+	// Model is the exact model of the vehicle
+	Model *string
+	// Color is the exact color of the vehicle
+	Color *string
+	// FuelType is the exact fuel type of the vehicle
+	FuelType *string
+	// Transmission is the exact transmission of the vehicle
+	Transmission *string
+	// Brands is the set of brands the vehicle has one of
+	Brands []string
+	// Models is the set of models the vehicle has one of
+	Models []string
+	// Colors is the set of colors the vehicle has one of
+	Colors []string
+	// FuelTypes is the set of fuel types the vehicle has one of
+	FuelTypes []string
+	// Transmissions is the set of transmissions the vehicle has one of
+	Transmissions []string
+	// FabricationYear is the range of fabrication years of the vehicle
+	FabricationYear Range[int]
+	// Capacity is the range of capacity of people of the vehicle
+	Capacity Range[int]
+	// MaxSpeed is the range of maximum speed of the vehicle
+	MaxSpeed Range[float64]
+	// Weight is the range of weight of the vehicle
+	Weight Range[float64]
+	// Height is the range of height of the vehicle
+	Height Range[float64]
+	// Length is the range of length of the vehicle
+	Length Range[float64]
+	// Width is the range of width of the vehicle
+	Width Range[float64]
+}
+
+// IsEmpty returns true if no field of the filter is set
+func (f VehicleFilter) IsEmpty() bool {
+	return f.Ids == nil && f.Brand == nil && f.Model == nil && f.Color == nil && f.FuelType == nil && f.Transmission == nil &&
+		f.Brands == nil && f.Models == nil && f.Colors == nil && f.FuelTypes == nil && f.Transmissions == nil &&
+		!f.FabricationYear.IsSet() && !f.Capacity.IsSet() && !f.MaxSpeed.IsSet() &&
+		!f.Weight.IsSet() && !f.Height.IsSet() && !f.Length.IsSet() && !f.Width.IsSet()
+}
+
+// Validate returns a FilterFieldError naming the first invalid field of the filter
+func (f VehicleFilter) Validate() (err error) {
+	for _, check := range []func() error{
+		func() error { return f.FabricationYear.validate("year") },
+		func() error { return f.Capacity.validate("capacity") },
+		func() error { return f.MaxSpeed.validate("max_speed") },
+		func() error { return f.Weight.validate("weight") },
+		func() error { return f.Height.validate("height") },
+		func() error { return f.Length.validate("length") },
+		func() error { return f.Width.validate("width") },
+	} {
+		if err = check(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Match returns true if the vehicle matches every set field of the filter
+func (f VehicleFilter) Match(v Vehicle) bool {
+	switch {
+	case f.Ids != nil && !slices.Contains(f.Ids, v.Id):
+		return false
+	case f.Brand != nil && v.Brand != *f.Brand:
+		return false
+	case f.Model != nil && v.Model != *f.Model:
+		return false
+	case f.Color != nil && v.Color != *f.Color:
+		return false
+	case f.FuelType != nil && v.FuelType != *f.FuelType:
+		return false
+	case f.Transmission != nil && v.Transmission != *f.Transmission:
+		return false
+	case f.Brands != nil && !slices.Contains(f.Brands, v.Brand):
+		return false
+	case f.Models != nil && !slices.Contains(f.Models, v.Model):
+		return false
+	case f.Colors != nil && !slices.Contains(f.Colors, v.Color):
+		return false
+	case f.FuelTypes != nil && !slices.Contains(f.FuelTypes, v.FuelType):
+		return false
+	case f.Transmissions != nil && !slices.Contains(f.Transmissions, v.Transmission):
+		return false
+	}
+	return f.FabricationYear.Contains(v.FabricationYear) &&
+		f.Capacity.Contains(v.Capacity) &&
+		f.MaxSpeed.Contains(v.MaxSpeed) &&
+		f.Weight.Contains(v.Weight) &&
+		f.Height.Contains(v.Height) &&
+		f.Length.Contains(v.Length) &&
+		f.Width.Contains(v.Width)
+}
@@ -0,0 +1,63 @@
+package internal
+
+// VehicleIterator is an interface that represents an iteration over vehicles, read one at a time
+// - Next must be called before the first Vehicle, Close must be called once the iteration is done (even if it stops early)
+type VehicleIterator interface {
+	// Total is a method that returns the number of vehicles of the iteration, counted when it started
+	Total() int
+
+	// Next is a method that advances to the next vehicle, it returns false once there are no more or the iteration failed
+	Next() bool
+
+	// Vehicle is a method that returns the current vehicle
+	Vehicle() Vehicle
+
+	// Err is a method that returns the error that stopped the iteration, if any
+	Err() error
+
+	// Close is a method that releases the resources of the iteration
+	Close() error
+}
+
+// NewVehicleIteratorSlice is a function that returns an iteration over a list of vehicles already in memory
+func NewVehicleIteratorSlice(v []Vehicle) *VehicleIteratorSlice {
+	return &VehicleIteratorSlice{v: v, i: -1}
+}
+
+// VehicleIteratorSlice is a struct that implements VehicleIterator over a list of vehicles
+type VehicleIteratorSlice struct {
+	// v is the list of vehicles
+	v []Vehicle
+	// i is the index of the current vehicle
+	i int
+}
+
+// Total is a method that returns the number of vehicles of the list
+func (it *VehicleIteratorSlice) Total() int {
+	return len(it.v)
+}
+
+// Next is a method that advances to the next vehicle of the list
+func (it *VehicleIteratorSlice) Next() bool {
+	if it.i+1 >= len(it.v) {
+		it.i = len(it.v)
+		return false
+	}
+	it.i++
+	return true
+}
+
+// Vehicle is a method that returns the current vehicle
+func (it *VehicleIteratorSlice) Vehicle() Vehicle {
+	return it.v[it.i]
+}
+
+// Err is a method that returns nil, a list does not fail
+func (it *VehicleIteratorSlice) Err() error {
+	return nil
+}
+
+// Close is a method that does nothing, a list has no resources
+func (it *VehicleIteratorSlice) Close() error {
+	return nil
+}
@@ -1,7 +1,49 @@
 package internal
 
+import "errors"
+
+var (
+	// ErrReloadInvalid is an error that represents a source of vehicles that could not be loaded on reload
+	ErrReloadInvalid = errors.New("reload: invalid vehicles")
+)
+
 // LoaderVehicle is an interface that represents the loader for vehicles
 type LoaderVehicle interface {
 	// Load is a method that loads the vehicles
 	Load() (v map[int]Vehicle, err error)
-}
\ No newline at end of file
+}
+
+// LoaderRecordsVehicle is an interface that represents a loader that returns every record as found in the source
+// - unlike Load, duplicated ids are kept, so the records can be validated
+type LoaderRecordsVehicle interface {
+	// LoadRecords is a method that loads the records in source order
+	LoadRecords() (v []Vehicle, err error)
+}
+
+// StorerVehicle is an interface that represents the storer for vehicles (the inverse of LoaderVehicle)
+type StorerVehicle interface {
+	// Store is a method that stores the vehicles, replacing the previous ones
+	Store(v map[int]Vehicle) (err error)
+}
+
+// StorerRecordsVehicle is an interface that represents the storer for records (the inverse of LoaderRecordsVehicle)
+// - unlike Store, duplicated ids are kept
+type StorerRecordsVehicle interface {
+	// StoreRecords is a method that stores the records in the given order, replacing the previous ones
+	StoreRecords(v []Vehicle) (err error)
+}
+
+// LoaderStorerVehicle is an interface that represents a loader that can also store the vehicles back
+type LoaderStorerVehicle interface {
+	LoaderVehicle
+	LoaderRecordsVehicle
+	StorerVehicle
+	StorerRecordsVehicle
+}
+
+// ReloaderVehicle is an interface that represents the reload of the vehicles from their source while serving them
+type ReloaderVehicle interface {
+	// Reload is a method that replaces the vehicles with the ones of the source
+	// - an invalid source returns an error wrapping ErrReloadInvalid and the current vehicles are kept
+	Reload() (report ValidationReport, err error)
+}
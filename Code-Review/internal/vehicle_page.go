@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+var (
+	// ErrPageInvalid is an error that represents an invalid page query
+	ErrPageInvalid = errors.New("page: invalid")
+)
+
+// VehicleSortFields is a map of the attributes a list of vehicles can be sorted by, with their comparison function
+var VehicleSortFields = map[string]func(a, b Vehicle) int{
+	"id":           func(a, b Vehicle) int { return cmp.Compare(a.Id, b.Id) },
+	"brand":        func(a, b Vehicle) int { return cmp.Compare(a.Brand, b.Brand) },
+	"model":        func(a, b Vehicle) int { return cmp.Compare(a.Model, b.Model) },
+	"registration": func(a, b Vehicle) int { return cmp.Compare(a.Registration, b.Registration) },
+	"color":        func(a, b Vehicle) int { return cmp.Compare(a.Color, b.Color) },
+	"year":         func(a, b Vehicle) int { return cmp.Compare(a.FabricationYear, b.FabricationYear) },
+	"capacity":     func(a, b Vehicle) int { return cmp.Compare(a.Capacity, b.Capacity) },
+	"max_speed":    func(a, b Vehicle) int { return cmp.Compare(a.MaxSpeed, b.MaxSpeed) },
+	"fuel_type":    func(a, b Vehicle) int { return cmp.Compare(a.FuelType, b.FuelType) },
+	"transmission": func(a, b Vehicle) int { return cmp.Compare(a.Transmission, b.Transmission) },
+	"weight":       func(a, b Vehicle) int { return cmp.Compare(a.Weight, b.Weight) },
+	"height":       func(a, b Vehicle) int { return cmp.Compare(a.Height, b.Height) },
+	"length":       func(a, b Vehicle) int { return cmp.Compare(a.Length, b.Length) },
+	"width":        func(a, b Vehicle) int { return cmp.Compare(a.Width, b.Width) },
+}
+
+// SortField is a struct that represents an attribute to sort a list of vehicles by
+type SortField struct {
+	// Name is the name of the attribute, one of VehicleSortFields
+	Name string
+	// Desc is true if the order is descending
+	Desc bool
+}
+
+// PageQuery is a struct that represents the sort and paging of a list of vehicles
+type PageQuery struct {
+	// Sort is the list of attributes to sort by, the id is always used as the last tie-breaker
+	Sort []SortField
+	// Limit is the maximum number of vehicles in the page (0 means no limit)
+	Limit int
+	// Offset is the number of vehicles to skip
+	Offset int
+	// After is the last vehicle of the previous page (cursor), if set the offset is ignored
+	// - only the attributes of the sort and the id are compared, the others may be unset
+	After *Vehicle
+}
+
+// Page is a struct that represents the metadata of a page of vehicles
+type Page struct {
+	// Total is the number of vehicles before paging
+	Total int
+	// Next is the last vehicle of the page if there are more vehicles after it
+	Next *Vehicle
+}
+
+// Validate returns an error that wraps ErrPageInvalid if the limit or the offset is negative or a sort field is unknown
+func (q PageQuery) Validate() (err error) {
+	if q.Limit < 0 {
+		err = fmt.Errorf("%w: limit must not be negative", ErrPageInvalid)
+		return
+	}
+	if q.Offset < 0 {
+		err = fmt.Errorf("%w: offset must not be negative", ErrPageInvalid)
+		return
+	}
+	for _, f := range q.Sort {
+		if _, ok := VehicleSortFields[f.Name]; !ok {
+			err = fmt.Errorf("%w: unknown sort field %s", ErrPageInvalid, f.Name)
+			return
+		}
+	}
+	return
+}
+
+// CompareVehicles returns a comparison function for the given sort fields
+// - the id is always used as the last tie-breaker so the order is deterministic
+func CompareVehicles(fields []SortField) (compare func(a, b Vehicle) int, err error) {
+	compares := make([]func(a, b Vehicle) int, 0, len(fields)+1)
+	for _, f := range fields {
+		c, ok := VehicleSortFields[f.Name]
+		if !ok {
+			err = fmt.Errorf("%w: unknown sort field %s", ErrPageInvalid, f.Name)
+			return
+		}
+		if f.Desc {
+			asc := c
+			c = func(a, b Vehicle) int { return -asc(a, b) }
+		}
+		compares = append(compares, c)
+	}
+	compares = append(compares, VehicleSortFields["id"])
+
+	compare = func(a, b Vehicle) int {
+		for _, c := range compares {
+			if r := c(a, b); r != 0 {
+				return r
+			}
+		}
+		return 0
+	}
+	return
+}
+
+// PageVehicles sorts the vehicles in place and returns the requested page
+func PageVehicles(v []Vehicle, q PageQuery) (page []Vehicle, p Page, err error) {
+	// validate query
+	err = q.Validate()
+	if err != nil {
+		return
+	}
+
+	// sort
+	compare, err := CompareVehicles(q.Sort)
+	if err != nil {
+		return
+	}
+	slices.SortStableFunc(v, compare)
+	p.Total = len(v)
+
+	// start: first vehicle after the cursor, or the offset
+	start := min(q.Offset, len(v))
+	if q.After != nil {
+		start = sort.Search(len(v), func(i int) bool { return compare(v[i], *q.After) > 0 })
+	}
+
+	// end
+	end := len(v)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+
+	page = v[start:end]
+	if end < len(v) && end > start {
+		next := v[end-1]
+		p.Next = &next
+	}
+	return
+}
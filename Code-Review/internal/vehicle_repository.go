@@ -1,27 +1,85 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 var (
 	// ErrRepositoryInvalidFind is an error that represents an invalid find
 	ErrRepositoryInvalidFind = errors.New("repository: invalid find")
+	// ErrRepositoryVehicleNotFound is an error that represents a vehicle not found
+	ErrRepositoryVehicleNotFound = errors.New("repository: vehicle not found")
+	// ErrRepositoryVehicleDuplicated is an error that represents a vehicle with an id that already exists
+	ErrRepositoryVehicleDuplicated = errors.New("repository: vehicle duplicated")
+	// ErrRepositoryRegistrationDuplicated is an error that represents a vehicle with a registration that already exists
+	ErrRepositoryRegistrationDuplicated = errors.New("repository: registration duplicated")
 )
 
 // RepositoryReadVehicle is an interface that represents a vehicle repository
 // - method: static. All searchs are strong typed, not hybrid or dynamic
+// - ctx: once it is done, the methods stop and return its error (context.Canceled or context.DeadlineExceeded)
 type RepositoryReadVehicle interface {
-	// FindAll is a method that returns a map of all vehicles
-	FindAll() (v map[int]Vehicle, err error)
+	// FindAll is a method that returns a list of all vehicles, ordered by id
+	FindAll(ctx context.Context) (v []Vehicle, err error)
+
+	// FindByColorAndYear is a method that returns a list of vehicles that match the color and fabrication year, ordered by id
+	FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []Vehicle, err error)
+
+	// FindByBrandAndYearRange is a method that returns a list of vehicles that match the brand and a range of fabrication years, ordered by id
+	FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []Vehicle, err error)
+
+	// FindByBrand is a method that returns a list of vehicles that match the brand, ordered by id
+	FindByBrand(ctx context.Context, brand string) (v []Vehicle, err error)
+
+	// FindByWeightRange is a method that returns a list of vehicles that match the weight range, ordered by id
+	FindByWeightRange(ctx context.Context, fromWeight float64, toWeight float64) (v []Vehicle, err error)
+
+	// FindByFilter is a method that returns a list of vehicles that match every set field of the filter, ordered by id
+	FindByFilter(ctx context.Context, filter VehicleFilter) (v []Vehicle, err error)
+
+	// Iterate is a method that returns an iteration over the vehicles that match every set field of the filter, ordered by id
+	// - the vehicles are read as the iteration advances, not copied up front: one may be updated or deleted meanwhile
+	// (a vehicle that no longer matches is skipped, so Total is an upper bound)
+	// - ctx is checked as the iteration advances, its error stops it (see VehicleIterator.Err)
+	Iterate(ctx context.Context, filter VehicleFilter) (it VehicleIterator, err error)
+
+	// FindPage is a method that returns the page of the vehicles that match every set field of the filter, sorted and paged as the query says
+	// - the repository sorts, skips and limits the vehicles itself, so only the vehicles of the page are returned (see PageVehicles)
+	// - an invalid query is an error that wraps ErrPageInvalid
+	FindPage(ctx context.Context, filter VehicleFilter, q PageQuery) (v []Vehicle, p Page, err error)
+
+	// FindValues is a method that returns the distinct values of a text attribute of the vehicles (see VehicleTextAttributes), sorted
+	// - an unknown attribute is an error that wraps ErrRepositoryInvalidFind
+	FindValues(ctx context.Context, attribute string) (values []string, err error)
+}
+
+// RepositoryWriteVehicle is an interface that represents a vehicle repository for writes
+// - ctx: a write is not done if ctx is done before it starts, but it may complete once started
+type RepositoryWriteVehicle interface {
+	// Save is a method that saves a new vehicle
+	Save(ctx context.Context, v *Vehicle) (err error)
+
+	// Update is a method that replaces the attributes of an existing vehicle
+	Update(ctx context.Context, v *Vehicle) (err error)
 
-	// FindByColorAndYear is a method that returns a map of vehicles that match the color and fabrication year
-	FindByColorAndYear(color string, fabricationYear int) (v map[int]Vehicle, err error)
+	// Patch is a method that updates only the given attributes of an existing vehicle
+	Patch(ctx context.Context, id int, patch VehicleAttributesPatch) (v Vehicle, err error)
 
-	// FindByBrandAndYearRange is a method that returns a map of vehicles that match the brand and a range of fabrication years
-	FindByBrandAndYearRange(brand string, startYear int, endYear int) (v map[int]Vehicle, err error)
+	// Delete is a method that deletes a vehicle
+	Delete(ctx context.Context, id int) (err error)
+}
 
-	// FindByBrand is a method that returns a map of vehicles that match the brand
-	FindByBrand(brand string) (v map[int]Vehicle, err error)
+// RepositoryVersionedVehicle is an interface that represents a repository that counts the versions of its vehicles
+// - the version changes every time the vehicles do, so what is derived from them can be kept until it changes
+type RepositoryVersionedVehicle interface {
+	// Version is a method that returns the current version of the vehicles and the time it was made
+	Version(ctx context.Context) (version uint64, modified time.Time, err error)
+}
 
-	// FindByWeightRange is a method that returns a map of vehicles that match the weight range
-	FindByWeightRange(fromWeight float64, toWeight float64) (v map[int]Vehicle, err error)
-}
\ No newline at end of file
+// RepositoryVehicle is an interface that represents a vehicle repository for reads and writes
+type RepositoryVehicle interface {
+	RepositoryReadVehicle
+	RepositoryWriteVehicle
+}
@@ -1,14 +1,29 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
 	// ErrServiceInvalidFind is an error that represents an invalid find
 	ErrServiceInvalidFind = errors.New("service: invalid find")
 	// ErrServiceInvalidSearch is an error that represents an invalid search
 	ErrServiceInvalidSearch = errors.New("service: invalid search")
+	// ErrServiceInvalidStats is an error that represents an invalid stats query
+	ErrServiceInvalidStats = errors.New("service: invalid stats")
+	// ErrServiceInvalidCompare is an error that represents an invalid comparison
+	ErrServiceInvalidCompare = errors.New("service: invalid compare")
+	// ErrServiceInvalidSimilar is an error that represents an invalid similar query
+	ErrServiceInvalidSimilar = errors.New("service: invalid similar")
 	// ErrServiceNoVehicles is an error that represents no vehicles
 	ErrServiceNoVehicles = errors.New("service: no vehicles")
+	// ErrServiceInvalidVehicle is an error that represents a vehicle with invalid attributes
+	ErrServiceInvalidVehicle = errors.New("service: invalid vehicle")
+	// ErrServiceVehicleNotFound is an error that represents a vehicle not found
+	ErrServiceVehicleNotFound = errors.New("service: vehicle not found")
+	// ErrServiceVehicleConflict is an error that represents a vehicle that conflicts with an existing one (id or registration)
+	ErrServiceVehicleConflict = errors.New("service: vehicle conflict")
 )
 
 // SearchQuery is a struct that represents a search query
@@ -20,23 +35,62 @@ type SearchQuery struct {
 }
 
 // ServiceVehicle is an interface that represents a vehicle service
+// - ctx is passed to the repository: its errors are returned as they are, not as service errors
+// - text attributes are matched as the text match mode of ctx says (see TextMatchFromContext); a value that no vehicle
+// has may be reported with a NoVehiclesError suggesting the values of the vehicles like it
 type ServiceVehicle interface {
-	// FindByColorAndYear is a method that returns a map of vehicles that match the color and fabrication year
-	FindByColorAndYear(color string, fabricationYear int) (v map[int]Vehicle, err error)
+	// FindByColorAndYear is a method that returns a list of vehicles that match the color and fabrication year, ordered by id
+	FindByColorAndYear(ctx context.Context, color string, fabricationYear int) (v []Vehicle, err error)
 
-	// FindByBrandAndYearRange is a method that returns a map of vehicles that match the brand and a range of fabrication years
-	FindByBrandAndYearRange(brand string, startYear int, endYear int) (v map[int]Vehicle, err error)
+	// FindByBrandAndYearRange is a method that returns a list of vehicles that match the brand and a range of fabrication years, ordered by id
+	FindByBrandAndYearRange(ctx context.Context, brand string, startYear int, endYear int) (v []Vehicle, err error)
 
 	// AverageMaxSpeedByBrand is a method that returns the average speed of the vehicles by brand
-	AverageMaxSpeedByBrand(brand string) (a float64, err error)
+	AverageMaxSpeedByBrand(ctx context.Context, brand string) (a float64, err error)
 
 	// AverageCapacityByBrand is a method that returns the average capacity of the vehicles by brand
-	AverageCapacityByBrand(brand string) (a int, err error)
+	AverageCapacityByBrand(ctx context.Context, brand string) (a float64, err error)
 
 	// SearchByWeightRange
 	// - method: hybrid. usage of static procedure and static optional (not dynamic types such as maps or slices)
 	// - query:
 	// 	 !ok -> will return all vehicles
 	// 	 ok  -> will return filtered vehicles
-	SearchByWeightRange(query SearchQuery, ok bool) (v map[int]Vehicle, err error)
-}
\ No newline at end of file
+	SearchByWeightRange(ctx context.Context, query SearchQuery, ok bool) (v []Vehicle, err error)
+
+	// Search is a method that returns a list of vehicles that match every set field of the filter, ordered by id
+	// - an empty filter will return all vehicles
+	Search(ctx context.Context, filter VehicleFilter) (v []Vehicle, err error)
+
+	// Stream is a method that returns an iteration over the vehicles that match every set field of the filter, ordered by id
+	// - same rules and errors as Search, but the vehicles are read from the repository as the iteration advances
+	// - the caller must close the iteration
+	Stream(ctx context.Context, filter VehicleFilter) (it VehicleIterator, err error)
+
+	// Page is a method that returns the page of the vehicles that match every set field of the filter, sorted and paged as the query says
+	// - same rules and errors as Search (a set filter that matches no vehicle is an error), the page is read from the repository
+	Page(ctx context.Context, filter VehicleFilter, q PageQuery) (v []Vehicle, p Page, err error)
+
+	// Stats is a method that returns the aggregates of a metric per group of the vehicles that match the filter, ordered by group
+	// - an empty filter will aggregate all vehicles
+	Stats(ctx context.Context, filter VehicleFilter, query StatsQuery) (groups []StatsGroup, err error)
+
+	// Compare is a method that returns the vehicles of the ids aligned attribute by attribute, in the order of the ids
+	// - ids that are not found are listed by a *VehiclesNotFoundError
+	Compare(ctx context.Context, ids []int) (c VehicleComparison, err error)
+
+	// Similar is a method that returns the vehicles most like the vehicle of the id, nearest first
+	Similar(ctx context.Context, id int, query SimilarQuery) (matches []SimilarVehicle, err error)
+
+	// Save is a method that saves a new vehicle
+	Save(ctx context.Context, v *Vehicle) (err error)
+
+	// Update is a method that replaces the attributes of an existing vehicle
+	Update(ctx context.Context, v *Vehicle) (err error)
+
+	// Patch is a method that updates only the given attributes of an existing vehicle
+	Patch(ctx context.Context, id int, patch VehicleAttributesPatch) (v Vehicle, err error)
+
+	// Delete is a method that deletes a vehicle
+	Delete(ctx context.Context, id int) (err error)
+}
@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+var (
+	// ErrSimilarInvalid is an error that represents an invalid similar query
+	ErrSimilarInvalid = errors.New("similar: invalid query")
+)
+
+const (
+	// SimilarDefaultK is the number of similar vehicles returned if the query does not set it
+	SimilarDefaultK = 5
+	// SimilarMaxK is the maximum number of similar vehicles of a query
+	SimilarMaxK = 100
+)
+
+// VehicleSimilarSame is the list of categorical attributes a similar vehicle can be required to share, by name
+var VehicleSimilarSame = map[string]func(v Vehicle) string{
+	"fuel_type":    func(v Vehicle) string { return v.FuelType },
+	"transmission": func(v Vehicle) string { return v.Transmission },
+}
+
+// SimilarQuery is a struct that represents a search of the vehicles most like a given one
+type SimilarQuery struct {
+	// K is the number of similar vehicles
+	K int
+	// Weights is the weight of each numeric attribute of VehicleCompareNumbers in the distance (missing: 1, 0: ignored)
+	Weights map[string]float64
+	// Same is the list of attributes of VehicleSimilarSame the similar vehicles must share
+	Same []string
+}
+
+// Validate returns an error wrapping ErrSimilarInvalid if k, an attribute or a weight is invalid
+func (q SimilarQuery) Validate() (err error) {
+	if q.K < 1 || q.K > SimilarMaxK {
+		return fmt.Errorf("%w: k must be between 1 and %d", ErrSimilarInvalid, SimilarMaxK)
+	}
+	for name, w := range q.Weights {
+		if _, ok := VehicleStatsMetrics[name]; !ok {
+			return fmt.Errorf("%w: unknown weight %s", ErrSimilarInvalid, name)
+		}
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("%w: weight %s must be a non-negative number", ErrSimilarInvalid, name)
+		}
+	}
+	total := 0.0
+	for _, name := range VehicleCompareNumbers {
+		w, ok := q.Weights[name]
+		if !ok {
+			w = 1
+		}
+		total += w
+	}
+	if total == 0 {
+		return fmt.Errorf("%w: every weight is 0", ErrSimilarInvalid)
+	}
+	for _, name := range q.Same {
+		if _, ok := VehicleSimilarSame[name]; !ok {
+			return fmt.Errorf("%w: unknown same %s", ErrSimilarInvalid, name)
+		}
+	}
+	return
+}
+
+// SimilarVehicle is a struct that represents a vehicle similar to another one
+type SimilarVehicle struct {
+	// Vehicle is the similar vehicle
+	Vehicle
+	// Distance is the distance to the other vehicle, from 0 (same attributes) to 1
+	Distance float64
+}
+
+// NewVehicleMatrix is a function that returns the matrix of the normalised numeric attributes of the vehicles
+func NewVehicleMatrix(v []Vehicle) *VehicleMatrix {
+	dims := len(VehicleCompareNumbers)
+	m := &VehicleMatrix{vehicles: v, rows: make(map[int]int, len(v)), values: make([]float64, len(v)*dims)}
+	for i, vh := range v {
+		m.rows[vh.Id] = i
+	}
+
+	// min-max normalisation per attribute, an attribute with a single value is 0 for every vehicle
+	for j, name := range VehicleCompareNumbers {
+		metric := VehicleStatsMetrics[name]
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, vh := range v {
+			lo, hi = math.Min(lo, metric(vh)), math.Max(hi, metric(vh))
+		}
+		for i, vh := range v {
+			if hi > lo {
+				m.values[i*dims+j] = (metric(vh) - lo) / (hi - lo)
+			}
+		}
+	}
+	return m
+}
+
+// VehicleMatrix is a struct that represents the vehicles with their numeric attributes normalised to [0, 1]
+// - one row per vehicle, one column per attribute of VehicleCompareNumbers
+// - it is built once per version of the vehicles, a search only computes the distances
+type VehicleMatrix struct {
+	// vehicles is the vehicle of each row
+	vehicles []Vehicle
+	// rows is the row of each vehicle, by id
+	rows map[int]int
+	// values are the rows one after the other
+	values []float64
+}
+
+// Similar is a method that returns the k vehicles nearest to the vehicle of the id, nearest first (ties by id)
+// - the distance is the weighted euclidean distance of the normalised attributes, divided by the total weight
+// - ok is false if there is no vehicle with the id
+func (m *VehicleMatrix) Similar(id int, q SimilarQuery) (matches []SimilarVehicle, ok bool) {
+	row, ok := m.rows[id]
+	if !ok {
+		return
+	}
+
+	dims := len(VehicleCompareNumbers)
+	weights := make([]float64, dims)
+	total := 0.0
+	for j, name := range VehicleCompareNumbers {
+		weights[j] = 1
+		if w, set := q.Weights[name]; set {
+			weights[j] = w
+		}
+		total += weights[j]
+	}
+
+	target, ref := m.values[row*dims:(row+1)*dims], m.vehicles[row]
+	matches = make([]SimilarVehicle, 0, len(m.vehicles)-1)
+	for i, vh := range m.vehicles {
+		if i == row || !sameAttributes(vh, ref, q.Same) {
+			continue
+		}
+		sum := 0.0
+		for j, value := range m.values[i*dims : (i+1)*dims] {
+			d := value - target[j]
+			sum += weights[j] * d * d
+		}
+		matches = append(matches, SimilarVehicle{Vehicle: vh, Distance: math.Sqrt(sum / total)})
+	}
+
+	slices.SortFunc(matches, func(a, b SimilarVehicle) int {
+		if a.Distance != b.Distance {
+			if a.Distance < b.Distance {
+				return -1
+			}
+			return 1
+		}
+		return a.Id - b.Id
+	})
+	if len(matches) > q.K {
+		matches = matches[:q.K]
+	}
+	return
+}
+
+// sameAttributes is a function that returns true if the vehicles share every attribute of VehicleSimilarSame of the list
+func sameAttributes(a, b Vehicle, names []string) bool {
+	for _, name := range names {
+		if VehicleSimilarSame[name](a) != VehicleSimilarSame[name](b) {
+			return false
+		}
+	}
+	return true
+}
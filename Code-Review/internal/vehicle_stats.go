@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrStatsInvalid is an error that represents an invalid stats query
+	ErrStatsInvalid = errors.New("stats: invalid query")
+)
+
+// VehicleStatsMetrics is the list of numeric attributes that can be aggregated, by name
+var VehicleStatsMetrics = map[string]func(v Vehicle) float64{
+	"year":      func(v Vehicle) float64 { return float64(v.FabricationYear) },
+	"capacity":  func(v Vehicle) float64 { return float64(v.Capacity) },
+	"max_speed": func(v Vehicle) float64 { return v.MaxSpeed },
+	"weight":    func(v Vehicle) float64 { return v.Weight },
+	"height":    func(v Vehicle) float64 { return v.Height },
+	"length":    func(v Vehicle) float64 { return v.Length },
+	"width":     func(v Vehicle) float64 { return v.Width },
+}
+
+// VehicleStatsGroups is the list of categorical attributes the vehicles can be grouped by, by name
+var VehicleStatsGroups = map[string]func(v Vehicle) string{
+	"brand":        func(v Vehicle) string { return v.Brand },
+	"model":        func(v Vehicle) string { return v.Model },
+	"color":        func(v Vehicle) string { return v.Color },
+	"year":         func(v Vehicle) string { return strconv.Itoa(v.FabricationYear) },
+	"fuel_type":    func(v Vehicle) string { return v.FuelType },
+	"transmission": func(v Vehicle) string { return v.Transmission },
+}
+
+// StatsQuery is a struct that represents the aggregates of a metric to compute per group of vehicles
+type StatsQuery struct {
+	// GroupBy is the list of attributes of VehicleStatsGroups that make the key of a group (none: a single group)
+	GroupBy []string
+	// Metric is the attribute of VehicleStatsMetrics to aggregate
+	Metric string
+	// Aggregates is the list of aggregates: count, sum, avg, min, max or pN (percentile N, 0 <= N <= 100)
+	Aggregates []string
+}
+
+// Validate returns an error wrapping ErrStatsInvalid if any attribute or aggregate is unknown
+func (q StatsQuery) Validate() (err error) {
+	for _, name := range q.GroupBy {
+		if _, ok := VehicleStatsGroups[name]; !ok {
+			return fmt.Errorf("%w: unknown group_by %s", ErrStatsInvalid, name)
+		}
+	}
+	if _, ok := VehicleStatsMetrics[q.Metric]; !ok {
+		return fmt.Errorf("%w: unknown metric %s", ErrStatsInvalid, q.Metric)
+	}
+	if len(q.Aggregates) == 0 {
+		return fmt.Errorf("%w: no aggregates", ErrStatsInvalid)
+	}
+	for _, name := range q.Aggregates {
+		if _, ok := statsAggregate(name); !ok {
+			return fmt.Errorf("%w: unknown agg %s", ErrStatsInvalid, name)
+		}
+	}
+	return
+}
+
+// StatsGroup is a struct that represents the aggregates of a group of vehicles
+type StatsGroup struct {
+	// Key is the value of each attribute of StatsQuery.GroupBy, in the same order
+	Key []string
+	// Values is the value of each aggregate of StatsQuery.Aggregates, in the same order
+	Values []float64
+}
+
+// ComputeVehicleStats is a function that groups the vehicles and computes the aggregates of the metric per group
+// - groups are ordered by key
+func ComputeVehicleStats(v []Vehicle, q StatsQuery) (groups []StatsGroup, err error) {
+	err = q.Validate()
+	if err != nil {
+		return
+	}
+
+	// group values of the metric
+	metric := VehicleStatsMetrics[q.Metric]
+	keys := make(map[string][]string)
+	values := make(map[string][]float64)
+	for _, vh := range v {
+		key := make([]string, len(q.GroupBy))
+		for i, name := range q.GroupBy {
+			key[i] = VehicleStatsGroups[name](vh)
+		}
+		id := strings.Join(key, "\x00")
+		keys[id] = key
+		values[id] = append(values[id], metric(vh))
+	}
+
+	// aggregate each group
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int { return slices.Compare(keys[a], keys[b]) })
+
+	groups = make([]StatsGroup, 0, len(ids))
+	for _, id := range ids {
+		vs := values[id]
+		slices.Sort(vs)
+		g := StatsGroup{Key: keys[id], Values: make([]float64, len(q.Aggregates))}
+		for i, name := range q.Aggregates {
+			aggregate, _ := statsAggregate(name)
+			g.Values[i] = aggregate(vs)
+		}
+		groups = append(groups, g)
+	}
+	return
+}
+
+// statsAggregate is a function that returns the aggregate function of the given name
+// - the function receives the values sorted in ascending order (never empty)
+func statsAggregate(name string) (fn func(sorted []float64) float64, ok bool) {
+	switch name {
+	case "count":
+		return func(s []float64) float64 { return float64(len(s)) }, true
+	case "sum":
+		return statsSum, true
+	case "avg":
+		return func(s []float64) float64 { return statsSum(s) / float64(len(s)) }, true
+	case "min":
+		return func(s []float64) float64 { return s[0] }, true
+	case "max":
+		return func(s []float64) float64 { return s[len(s)-1] }, true
+	}
+
+	// percentile: pN
+	if !strings.HasPrefix(name, "p") {
+		return
+	}
+	p, err := strconv.ParseFloat(name[1:], 64)
+	if err != nil || p < 0 || p > 100 || math.IsNaN(p) {
+		return
+	}
+	return func(s []float64) float64 { return statsPercentile(s, p) }, true
+}
+
+// statsSum is a function that returns the sum of the values
+func statsSum(s []float64) (sum float64) {
+	for _, value := range s {
+		sum += value
+	}
+	return
+}
+
+// statsPercentile is a function that returns the percentile p of the sorted values
+// - linear interpolation between the closest ranks
+func statsPercentile(s []float64, p float64) float64 {
+	rank := p / 100 * float64(len(s)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return s[lo] + (s[hi]-s[lo])*(rank-float64(lo))
+}
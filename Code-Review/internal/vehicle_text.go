@@ -0,0 +1,253 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// ErrTextMatchInvalid is an error that represents an unknown text match mode
+	ErrTextMatchInvalid = errors.New("text: invalid match")
+)
+
+// TextMatch is the way the text attributes of a query are compared with the ones of the vehicles
+type TextMatch string
+
+const (
+	// TextMatchNormalized compares the normalized forms of the texts (see NormalizeText)
+	TextMatchNormalized TextMatch = "normalized"
+	// TextMatchFuzzy compares the normalized forms of the texts allowing a few typos (see EditDistance)
+	TextMatchFuzzy TextMatch = "fuzzy"
+)
+
+// ParseTextMatch returns the TextMatch of the name (an empty name is TextMatchNormalized)
+func ParseTextMatch(name string) (m TextMatch, err error) {
+	switch m = TextMatch(name); m {
+	case "":
+		m = TextMatchNormalized
+	case TextMatchNormalized, TextMatchFuzzy:
+	default:
+		m, err = "", ErrTextMatchInvalid
+	}
+	return
+}
+
+// textMatchContextKey is the key of the TextMatch in a context
+type textMatchContextKey struct{}
+
+// NewTextMatchContext returns a copy of ctx that carries the text match mode of the finders
+func NewTextMatchContext(ctx context.Context, m TextMatch) context.Context {
+	return context.WithValue(ctx, textMatchContextKey{}, m)
+}
+
+// TextMatchFromContext returns the text match mode carried by ctx (TextMatchNormalized if there is none)
+func TextMatchFromContext(ctx context.Context) TextMatch {
+	m, ok := ctx.Value(textMatchContextKey{}).(TextMatch)
+	if !ok {
+		return TextMatchNormalized
+	}
+	return m
+}
+
+// VehicleTextAttributes is the list of text attributes the finders match, by name
+var VehicleTextAttributes = map[string]func(v Vehicle) string{
+	"brand":        func(v Vehicle) string { return v.Brand },
+	"model":        func(v Vehicle) string { return v.Model },
+	"color":        func(v Vehicle) string { return v.Color },
+	"fuel_type":    func(v Vehicle) string { return v.FuelType },
+	"transmission": func(v Vehicle) string { return v.Transmission },
+}
+
+// textFolds is the ascii form of the letters that have no canonical decomposition (lower case)
+// - the others are decomposed by NormalizeText into a base letter and combining marks
+var textFolds = map[rune]string{
+	'đ': "d", 'ð': "d", 'ħ': "h", 'ı': "i", 'ł': "l", 'ø': "o", 'ŧ': "t",
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'þ': "th",
+}
+
+// NormalizeText returns the form of a text attribute used to compare it
+// - spaces are trimmed and collapsed, letters are lower cased
+// - accents are stripped: the text is decomposed (NFD) and its nonspacing marks (Mn) dropped
+func NormalizeText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range norm.NFD.String(strings.TrimSpace(s)) {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case unicode.Is(unicode.Mn, r):
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		r = unicode.ToLower(r)
+		if f, ok := textFolds[r]; ok {
+			b.WriteString(f)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// EditDistance returns the Levenshtein distance between two texts (insertions, deletions and substitutions of runes)
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// - a single row of the matrix: row[j] is the distance between the read prefix of a and rb[:j]
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			diagonal, row[j] = row[j], min(row[j]+1, row[j-1]+1, diagonal+cost)
+		}
+	}
+	return row[len(rb)]
+}
+
+// fuzzyMaxDistance returns the edit distance a normalized text can be away from a value in TextMatchFuzzy
+// - short texts must match: a typo in 2 letters is another word
+func fuzzyMaxDistance(s string) int {
+	switch n := len([]rune(s)); {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	}
+	return 2
+}
+
+// TextSuggestionMax is the maximum number of values suggested for a text attribute
+const TextSuggestionMax = 3
+
+// VehicleVocabulary is a struct that represents the distinct values of the text attributes of the vehicles
+type VehicleVocabulary struct {
+	// values is the list of distinct values per attribute of VehicleTextAttributes and normalized form, sorted
+	values map[string]map[string][]string
+}
+
+// NewVehicleVocabulary returns the vocabulary of the distinct values of the vehicles, by attribute of VehicleTextAttributes
+// - the values of an attribute are the ones the repository finds (see RepositoryReadVehicle.FindValues)
+func NewVehicleVocabulary(values map[string][]string) *VehicleVocabulary {
+	vc := &VehicleVocabulary{values: make(map[string]map[string][]string, len(values))}
+	for name, v := range values {
+		forms := make(map[string][]string)
+		for _, value := range v {
+			key := NormalizeText(value)
+			if !slices.Contains(forms[key], value) {
+				forms[key] = append(forms[key], value)
+			}
+		}
+		for _, values := range forms {
+			slices.Sort(values)
+		}
+		vc.values[name] = forms
+	}
+	return vc
+}
+
+// Resolve returns the values of the attribute of the vehicles that match the query, sorted
+// - TextMatchNormalized: the values with the same normalized form as the query
+// - TextMatchFuzzy: the values whose normalized form is within fuzzyMaxDistance of the query
+func (vc *VehicleVocabulary) Resolve(attribute string, query string, m TextMatch) (values []string) {
+	key := NormalizeText(query)
+	forms := vc.values[attribute]
+	if m != TextMatchFuzzy {
+		values = slices.Clone(forms[key])
+		return
+	}
+
+	maxDistance := fuzzyMaxDistance(key)
+	for form, v := range forms {
+		if EditDistance(key, form) <= maxDistance {
+			values = append(values, v...)
+		}
+	}
+	slices.Sort(values)
+	return
+}
+
+// Suggest returns up to TextSuggestionMax values of the attribute of the vehicles like the query, nearest first
+// - the values are within one more edit than fuzzyMaxDistance, or start with the query
+// - a value stands for every value with its normalized form
+func (vc *VehicleVocabulary) Suggest(attribute string, query string) (suggestions []string) {
+	key := NormalizeText(query)
+	maxDistance := fuzzyMaxDistance(key) + 1
+
+	type candidate struct {
+		value    string
+		distance int
+	}
+	var candidates []candidate
+	for form, v := range vc.values[attribute] {
+		d := EditDistance(key, form)
+		if d > maxDistance && (key == "" || !strings.HasPrefix(form, key)) {
+			continue
+		}
+		candidates = append(candidates, candidate{value: strings.TrimSpace(v[0]), distance: d})
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.value, b.value)
+	})
+
+	for _, c := range candidates[:min(len(candidates), TextSuggestionMax)] {
+		suggestions = append(suggestions, c.value)
+	}
+	return
+}
+
+// TextSuggestion is a struct that represents the values of the vehicles suggested for the value of a query
+type TextSuggestion struct {
+	// Attribute is the name of the attribute of VehicleTextAttributes
+	Attribute string
+	// Value is the value of the query
+	Value string
+	// Suggestions is the list of values of the vehicles like Value, nearest first
+	Suggestions []string
+}
+
+// NoVehiclesError is an error that represents a query with text values that no vehicle has
+// - it comes with the values of the vehicles the query may have meant
+type NoVehiclesError struct {
+	// Suggestions is the list of suggestions per unknown value of the query, in the order of the attributes of the query
+	Suggestions []TextSuggestion
+}
+
+// Error returns the message of the error
+func (e *NoVehiclesError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrServiceNoVehicles.Error())
+	for i, s := range e.Suggestions {
+		if i == 0 {
+			b.WriteString(", did you mean ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(s.Attribute + " " + strings.Join(s.Suggestions, " or "))
+	}
+	return b.String()
+}
+
+// Unwrap returns ErrServiceNoVehicles so callers can check the error with errors.Is
+func (e *NoVehiclesError) Unwrap() error {
+	return ErrServiceNoVehicles
+}
@@ -0,0 +1,85 @@
+package internal_test
+
+import (
+	"app/internal"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeText(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{"", ""},
+		{"Ford", "ford"},
+		{"  Land   Rover\t", "land rover"},
+		{"Citroën", "citroen"},
+		{"CITROËN", "citroen"},
+		{"Citroe\u0308n", "citroen"},
+		{"Škoda", "skoda"},
+		{"Ñandú", "nandu"},
+		{"Ærø", "aero"},
+		{"Łada", "lada"},
+		{"Straße", "strasse"},
+		{"Mercedes-Benz", "mercedes-benz"},
+	}
+	for _, c := range cases {
+		// act
+		normalized := internal.NormalizeText(c.text)
+		// assert
+		require.Equal(t, c.expected, normalized, c.text)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"ford", "", 4},
+		{"", "ford", 4},
+		{"ford", "ford", 0},
+		{"ford", "fort", 1},
+		{"ford", "frod", 2},
+		{"chevrolet", "chevrolt", 1},
+		{"toyota", "toyotas", 1},
+		{"kitten", "sitting", 3},
+		{"citroën", "citroen", 1},
+	}
+	for _, c := range cases {
+		// act
+		d := internal.EditDistance(c.a, c.b)
+		// assert
+		require.Equal(t, c.expected, d, c.a+" / "+c.b)
+	}
+}
+
+func TestVehicleVocabulary_Suggest(t *testing.T) {
+	vc := internal.NewVehicleVocabulary(map[string][]string{
+		"brand": {"Chevrolet", "CHEVROLET ", "Chrysler", "Citroën", "Ford", "Mercedes-Benz", "Mercury"},
+		"color": {"red", "blue"},
+	})
+
+	cases := []struct {
+		attribute string
+		query     string
+		expected  []string
+	}{
+		{"brand", "chevrolt", []string{"CHEVROLET"}},
+		{"brand", "citroen", []string{"Citroën"}},
+		{"brand", "frd", []string{"Ford"}},
+		{"brand", "merc", []string{"Mercury", "Mercedes-Benz"}},
+		{"brand", "toyota", nil},
+		{"color", "rd", []string{"red"}},
+		{"model", "fiesta", nil},
+	}
+	for _, c := range cases {
+		// act
+		suggestions := vc.Suggest(c.attribute, c.query)
+		// assert
+		require.Equal(t, c.expected, suggestions, c.attribute+" "+c.query)
+	}
+}
@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+var (
+	// ErrUnitsInvalid is an error that represents an unknown unit system
+	ErrUnitsInvalid = errors.New("units: invalid system")
+)
+
+// UnitSystem is a system of units the quantities of the vehicles can be expressed in
+type UnitSystem string
+
+const (
+	// UnitsMetric expresses weights in kg, speeds in km/h and dimensions in cm
+	UnitsMetric UnitSystem = "metric"
+	// UnitsImperial expresses weights in lb, speeds in mph and dimensions in in
+	UnitsImperial UnitSystem = "imperial"
+	// UnitsCanonical is the unit system of the vehicles: stored values, filters and aggregates of the domain are metric
+	UnitsCanonical = UnitsMetric
+)
+
+// ParseUnitSystem returns the UnitSystem of the name, in any case (an empty name is UnitsCanonical)
+func ParseUnitSystem(name string) (u UnitSystem, err error) {
+	switch u = UnitSystem(strings.ToLower(name)); u {
+	case "":
+		u = UnitsCanonical
+	case UnitsMetric, UnitsImperial:
+	default:
+		u, err = "", ErrUnitsInvalid
+	}
+	return
+}
+
+// Quantity is a physical quantity of the vehicles
+type Quantity string
+
+const (
+	// QuantityMass is the quantity of the weight
+	QuantityMass Quantity = "mass"
+	// QuantitySpeed is the quantity of the maximum speed
+	QuantitySpeed Quantity = "speed"
+	// QuantityLength is the quantity of the dimensions
+	QuantityLength Quantity = "length"
+)
+
+// VehicleQuantities is the quantity of each numeric attribute of the vehicles that has a unit, by name
+// - the other numeric attributes (year, capacity) are counts, the same in every unit system
+var VehicleQuantities = map[string]Quantity{
+	"max_speed": QuantitySpeed,
+	"weight":    QuantityMass,
+	"height":    QuantityLength,
+	"length":    QuantityLength,
+	"width":     QuantityLength,
+}
+
+// Unit is a struct that represents the unit of a quantity in a unit system
+type Unit struct {
+	// Symbol is the symbol of the unit (e.g. kg)
+	Symbol string
+	// Canonical is the value of one unit in the canonical unit of the quantity (exact by definition, e.g. 1 lb = 0.45359237 kg)
+	Canonical float64
+}
+
+// units is the unit of each quantity per unit system
+var units = map[UnitSystem]map[Quantity]Unit{
+	UnitsMetric: {
+		QuantityMass:   {Symbol: "kg", Canonical: 1},
+		QuantitySpeed:  {Symbol: "km/h", Canonical: 1},
+		QuantityLength: {Symbol: "cm", Canonical: 1},
+	},
+	UnitsImperial: {
+		QuantityMass:   {Symbol: "lb", Canonical: 0.45359237},
+		QuantitySpeed:  {Symbol: "mph", Canonical: 1.609344},
+		QuantityLength: {Symbol: "in", Canonical: 2.54},
+	},
+}
+
+const (
+	// UnitsDecimals is the number of decimals of a value converted from the canonical units
+	UnitsDecimals = 6
+	// UnitsCanonicalDecimals is the number of decimals of a value converted to the canonical units, the precision of the vehicles
+	// - a canonical value with up to UnitsCanonicalDecimals decimals converted to any units and back is the same value
+	// - so a value read in any units and sent back as a filter bound finds the same vehicles
+	UnitsCanonicalDecimals = 4
+)
+
+// Unit returns the unit of the quantity in the unit system (the canonical one for an empty system)
+func (u UnitSystem) Unit(q Quantity) Unit {
+	if u == "" {
+		u = UnitsCanonical
+	}
+	return units[u][q]
+}
+
+// FromCanonical returns the value of the quantity in canonical units converted to the unit system
+// - rounded to UnitsDecimals, the canonical system (or an empty one) returns the value as it is
+func (u UnitSystem) FromCanonical(q Quantity, value float64) float64 {
+	if u == "" || u == UnitsCanonical {
+		return value
+	}
+	return roundDecimals(value/u.Unit(q).Canonical, UnitsDecimals)
+}
+
+// ToCanonical returns the value of the quantity in the unit system converted to canonical units
+// - rounded to UnitsCanonicalDecimals, the canonical system (or an empty one) returns the value as it is
+func (u UnitSystem) ToCanonical(q Quantity, value float64) float64 {
+	if u == "" || u == UnitsCanonical {
+		return value
+	}
+	return roundDecimals(value*u.Unit(q).Canonical, UnitsCanonicalDecimals)
+}
+
+// Symbols returns the symbol of the unit of each attribute of VehicleQuantities in the unit system
+func (u UnitSystem) Symbols() map[string]string {
+	symbols := make(map[string]string, len(VehicleQuantities))
+	for name, q := range VehicleQuantities {
+		symbols[name] = u.Unit(q).Symbol
+	}
+	return symbols
+}
+
+// Vehicle returns the vehicle with its quantities converted from canonical units to the unit system
+func (u UnitSystem) Vehicle(v Vehicle) Vehicle {
+	v.MaxSpeed = u.FromCanonical(QuantitySpeed, v.MaxSpeed)
+	v.Weight = u.FromCanonical(QuantityMass, v.Weight)
+	v.Height = u.FromCanonical(QuantityLength, v.Height)
+	v.Length = u.FromCanonical(QuantityLength, v.Length)
+	v.Width = u.FromCanonical(QuantityLength, v.Width)
+	return v
+}
+
+// Range returns the range of the quantity with its bounds converted from the unit system to canonical units
+func (u UnitSystem) Range(q Quantity, r Range[float64]) Range[float64] {
+	convert := func(bound *float64) *float64 {
+		if bound == nil {
+			return nil
+		}
+		value := u.ToCanonical(q, *bound)
+		return &value
+	}
+	return Range[float64]{Min: convert(r.Min), Max: convert(r.Max)}
+}
+
+// Filter returns the filter with the ranges of the quantities converted from the unit system to canonical units
+func (u UnitSystem) Filter(f VehicleFilter) VehicleFilter {
+	f.MaxSpeed = u.Range(QuantitySpeed, f.MaxSpeed)
+	f.Weight = u.Range(QuantityMass, f.Weight)
+	f.Height = u.Range(QuantityLength, f.Height)
+	f.Length = u.Range(QuantityLength, f.Length)
+	f.Width = u.Range(QuantityLength, f.Width)
+	return f
+}
+
+// roundDecimals returns the value rounded to the number of decimals
+// - values too large to have those decimals (and infinities) are returned as they are
+func roundDecimals(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	if math.IsInf(value, 0) || math.Abs(value)*scale >= 1<<53 {
+		return value
+	}
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,115 @@
+package internal_test
+
+import (
+	"app/internal"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnitSystem(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected internal.UnitSystem
+		err      error
+	}{
+		{"", internal.UnitsCanonical, nil},
+		{"metric", internal.UnitsMetric, nil},
+		{"imperial", internal.UnitsImperial, nil},
+		{"Imperial", internal.UnitsImperial, nil},
+		{" metric", "", internal.ErrUnitsInvalid},
+		{"si", "", internal.ErrUnitsInvalid},
+	}
+	for _, c := range cases {
+		// act
+		u, err := internal.ParseUnitSystem(c.name)
+		// assert
+		require.ErrorIs(t, err, c.err, c.name)
+		require.Equal(t, c.expected, u, c.name)
+	}
+}
+
+func TestUnitSystem_FromCanonical(t *testing.T) {
+	cases := []struct {
+		units    internal.UnitSystem
+		quantity internal.Quantity
+		value    float64
+		expected float64
+	}{
+		{internal.UnitsImperial, internal.QuantityMass, 1000, 2204.622622},
+		{internal.UnitsImperial, internal.QuantitySpeed, 180, 111.846815},
+		{internal.UnitsImperial, internal.QuantityLength, 241.54, 95.094488},
+		{internal.UnitsImperial, internal.QuantityLength, 0, 0},
+		{internal.UnitsImperial, internal.QuantityMass, math.Inf(1), math.Inf(1)},
+		{internal.UnitsMetric, internal.QuantityMass, 1000.123456789, 1000.123456789},
+		{"", internal.QuantitySpeed, 180.5, 180.5},
+	}
+	for _, c := range cases {
+		// act
+		value := c.units.FromCanonical(c.quantity, c.value)
+		// assert
+		require.Equal(t, c.expected, value, "%s %s %v", c.units, c.quantity, c.value)
+	}
+}
+
+func TestUnitSystem_ToCanonical(t *testing.T) {
+	cases := []struct {
+		units    internal.UnitSystem
+		quantity internal.Quantity
+		value    float64
+		expected float64
+	}{
+		{internal.UnitsImperial, internal.QuantityMass, 2204.622622, 1000},
+		{internal.UnitsImperial, internal.QuantityMass, 2000, 907.1847},
+		{internal.UnitsImperial, internal.QuantitySpeed, 111.846815, 180},
+		{internal.UnitsImperial, internal.QuantityLength, 95.094488, 241.54},
+		{internal.UnitsImperial, internal.QuantityMass, math.Inf(-1), math.Inf(-1)},
+		{internal.UnitsMetric, internal.QuantityLength, 1.23456789, 1.23456789},
+	}
+	for _, c := range cases {
+		// act
+		value := c.units.ToCanonical(c.quantity, c.value)
+		// assert
+		require.Equal(t, c.expected, value, "%s %s %v", c.units, c.quantity, c.value)
+	}
+}
+
+func TestUnitSystem_RoundTrip(t *testing.T) {
+	// canonical values with up to internal.UnitsCanonicalDecimals decimals are the same after a round trip
+	values := []float64{0, 0.0001, 0.3048, 1.5, 1.8, 4, 180, 241.54, 1234.5678, 99999.9999, 123456789.1234, -3.25}
+	for _, units := range []internal.UnitSystem{internal.UnitsMetric, internal.UnitsImperial} {
+		for _, quantity := range []internal.Quantity{internal.QuantityMass, internal.QuantitySpeed, internal.QuantityLength} {
+			for _, value := range values {
+				// act
+				converted := units.FromCanonical(quantity, value)
+				back := units.ToCanonical(quantity, converted)
+				// assert
+				require.Equal(t, value, back, "%s %s %v", units, quantity, value)
+			}
+		}
+	}
+}
+
+func TestUnitSystem_Filter(t *testing.T) {
+	// arrange
+	brand := "Ford"
+	weightMin, speedMax, year := 2000.0, 111.846815, 2010
+	filter := internal.VehicleFilter{
+		Brand:           &brand,
+		FabricationYear: internal.Range[int]{Min: &year},
+		Weight:          internal.Range[float64]{Min: &weightMin},
+		MaxSpeed:        internal.Range[float64]{Max: &speedMax},
+	}
+	// act
+	canonical := internal.UnitsImperial.Filter(filter)
+	// assert
+	require.Equal(t, 907.1847, *canonical.Weight.Min)
+	require.Nil(t, canonical.Weight.Max)
+	require.Equal(t, 180.0, *canonical.MaxSpeed.Max)
+	require.Nil(t, canonical.MaxSpeed.Min)
+	require.False(t, canonical.Height.IsSet())
+	require.Equal(t, &brand, canonical.Brand)
+	require.Equal(t, 2010, *canonical.FabricationYear.Min)
+	require.Equal(t, 2000.0, *filter.Weight.Min, "the filter is not modified")
+}
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrValidationFailed is an error that represents a dataset with invalid records
+	ErrValidationFailed = errors.New("validation: invalid vehicles")
+)
+
+// ValidationSeverity is the severity of a validation problem
+type ValidationSeverity string
+
+const (
+	// ValidationError is a problem that makes the record invalid (it is skipped in lenient mode)
+	ValidationError ValidationSeverity = "error"
+	// ValidationWarning is a problem that is reported but keeps the record
+	ValidationWarning ValidationSeverity = "warning"
+)
+
+// ValidationProblem is a struct that represents a problem found in a record of a dataset
+type ValidationProblem struct {
+	// Index is the position of the record in the dataset (starting at 0)
+	Index int
+	// Id is the id of the vehicle of the record
+	Id int
+	// Field is the name of the invalid field
+	Field string
+	// Message is the description of the problem
+	Message string
+	// Severity is the severity of the problem
+	Severity ValidationSeverity
+}
+
+// String returns the problem as a line of a report
+func (p ValidationProblem) String() string {
+	return fmt.Sprintf("%s: record %d (id %d): %s: %s", p.Severity, p.Index, p.Id, p.Field, p.Message)
+}
+
+// ValidationReport is a struct that represents the result of the validation of a dataset
+type ValidationReport struct {
+	// Records is the number of records validated
+	Records int
+	// Skipped is the number of records with errors
+	Skipped int
+	// Problems is the list of problems found, in record order
+	Problems []ValidationProblem
+}
+
+// Errors returns the number of problems with error severity
+func (r ValidationReport) Errors() (n int) {
+	for _, p := range r.Problems {
+		if p.Severity == ValidationError {
+			n++
+		}
+	}
+	return
+}
+
+// Summary returns a one line summary of the report, counting problems by field
+func (r ValidationReport) Summary() string {
+	counts := make(map[string]int)
+	var fields []string
+	for _, p := range r.Problems {
+		key := string(p.Severity) + " " + p.Field
+		if counts[key] == 0 {
+			fields = append(fields, key)
+		}
+		counts[key]++
+	}
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts = append(parts, fmt.Sprintf("%s: %d", f, counts[f]))
+	}
+	summary := fmt.Sprintf("%d records, %d skipped, %d problems", r.Records, r.Skipped, len(r.Problems))
+	if len(parts) > 0 {
+		summary += " (" + strings.Join(parts, ", ") + ")"
+	}
+	return summary
+}
+
+// ValidationReportError is an error that represents a dataset that failed the validation
+type ValidationReportError struct {
+	// Report is the validation report
+	Report ValidationReport
+}
+
+// Error returns the summary of the report and its first error
+func (e *ValidationReportError) Error() string {
+	msg := fmt.Sprintf("validation: invalid vehicles: %s", e.Report.Summary())
+	for _, p := range e.Report.Problems {
+		if p.Severity == ValidationError {
+			msg += "; first " + p.String()
+			break
+		}
+	}
+	return msg
+}
+
+// Unwrap returns ErrValidationFailed so callers can check the error with errors.Is
+func (e *ValidationReportError) Unwrap() error {
+	return ErrValidationFailed
+}
+
+// NewVehicleValidatorDefault is a function that returns a validator with the default rules
+// - years between the first car (1886) and the current year (read on every validation)
+// - fuel types and transmissions found in the fleet datasets
+func NewVehicleValidatorDefault() *VehicleValidator {
+	return &VehicleValidator{
+		MinYear:       1886,
+		FuelTypes:     []string{"gas", "gasoline", "diesel", "biodiesel", "electric", "hybrid"},
+		Transmissions: []string{"manual", "automatic", "semi-automatic"},
+	}
+}
+
+// VehicleValidator is a struct that validates the records of a dataset of vehicles
+type VehicleValidator struct {
+	// MinYear is the minimum fabrication year
+	MinYear int
+	// MaxYear is the maximum fabrication year (0 means the current year when Validate is called)
+	MaxYear int
+	// FuelTypes is the list of known fuel types
+	FuelTypes []string
+	// Transmissions is the list of known transmissions
+	Transmissions []string
+}
+
+// Validate is a method that validates every record and returns the valid ones with a report of the problems
+// - errors: duplicated id (the first record wins), year out of range, non-positive weight and dimensions,
+// unknown fuel type or transmission, empty registration
+// - warnings: a zero (missing) length, the one dimension the shipped datasets do not have
+func (vd *VehicleValidator) Validate(records []Vehicle) (valid []Vehicle, report ValidationReport) {
+	maxYear := vd.MaxYear
+	if maxYear == 0 {
+		maxYear = time.Now().Year()
+	}
+	report.Records = len(records)
+	valid = make([]Vehicle, 0, len(records))
+	seen := make(map[int]int, len(records))
+
+	for i, v := range records {
+		var problems []ValidationProblem
+		add := func(field string, severity ValidationSeverity, format string, args ...any) {
+			problems = append(problems, ValidationProblem{
+				Index: i, Id: v.Id, Field: field, Message: fmt.Sprintf(format, args...), Severity: severity,
+			})
+		}
+
+		// identity
+		if first, ok := seen[v.Id]; ok {
+			add("id", ValidationError, "duplicated, first seen in record %d", first)
+		} else {
+			seen[v.Id] = i
+		}
+		if strings.TrimSpace(v.Registration) == "" {
+			add("registration", ValidationError, "is empty")
+		}
+
+		// ranges
+		if v.FabricationYear < vd.MinYear || v.FabricationYear > maxYear {
+			add("year", ValidationError, "%d is out of range [%d, %d]", v.FabricationYear, vd.MinYear, maxYear)
+		}
+		if v.Weight <= 0 {
+			add("weight", ValidationError, "%g is not positive", v.Weight)
+		}
+		for _, d := range []struct {
+			field    string
+			value    float64
+			optional bool
+		}{{"height", v.Height, false}, {"length", v.Length, true}, {"width", v.Width, false}} {
+			switch {
+			case d.value == 0 && d.optional:
+				add(d.field, ValidationWarning, "is missing or zero")
+			case d.value <= 0:
+				add(d.field, ValidationError, "%g is not positive", d.value)
+			}
+		}
+
+		// enumerations
+		if !containsFold(vd.FuelTypes, v.FuelType) {
+			add("fuel_type", ValidationError, "%q is unknown", v.FuelType)
+		}
+		if !containsFold(vd.Transmissions, v.Transmission) {
+			add("transmission", ValidationError, "%q is unknown", v.Transmission)
+		}
+
+		report.Problems = append(report.Problems, problems...)
+		if hasValidationError(problems) {
+			report.Skipped++
+			continue
+		}
+		valid = append(valid, v)
+	}
+
+	return
+}
+
+// containsFold returns true if the list contains the value, ignoring case
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasValidationError returns true if any of the problems has error severity
+func hasValidationError(problems []ValidationProblem) bool {
+	for _, p := range problems {
+		if p.Severity == ValidationError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// HeaderAPIKey is the header of the API key of a request
+const HeaderAPIKey = "X-API-Key"
+
+// NewAPIKeys is a function that returns a new instance of APIKeys
+// - keys is the role of each key
+func NewAPIKeys(keys map[string]Role) *APIKeys {
+	a := &APIKeys{keys: make([]apiKey, 0, len(keys))}
+	for key, role := range keys {
+		sum := sha256.Sum256([]byte(key))
+		a.keys = append(a.keys, apiKey{
+			sum:       sum,
+			principal: Principal{Subject: "key:" + hex.EncodeToString(sum[:4]), Role: role},
+		})
+	}
+	return a
+}
+
+// APIKeys is a struct that implements Authenticator with static API keys
+// - the key is taken from the X-API-Key header, or from a bearer token that is one of the keys
+// - a bearer token that is not a key is left to the next authenticator (e.g. a JWT)
+// - keys are compared by their SHA-256 in constant time, the principal subject is a fingerprint of the key
+type APIKeys struct {
+	// keys is the list of keys
+	keys []apiKey
+}
+
+// apiKey is a struct that represents a static API key
+type apiKey struct {
+	// sum is the SHA-256 of the key
+	sum [sha256.Size]byte
+	// principal is the principal of the requests with the key
+	principal Principal
+}
+
+// Authenticate is a method that returns the principal of the API key of the request
+func (a *APIKeys) Authenticate(r *http.Request) (p Principal, err error) {
+	// header
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		p, ok := a.lookup(key)
+		if !ok {
+			return p, ErrInvalidCredentials
+		}
+		return p, nil
+	}
+
+	// bearer token
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if p, ok := a.lookup(token); ok {
+			return p, nil
+		}
+	}
+	err = ErrNoCredentials
+	return
+}
+
+// lookup is a method that returns the principal of a key
+func (a *APIKeys) lookup(key string) (p Principal, ok bool) {
+	sum := sha256.Sum256([]byte(key))
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(sum[:], k.sum[:]) == 1 {
+			p, ok = k.principal, true
+		}
+	}
+	return
+}
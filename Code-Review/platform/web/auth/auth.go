@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"app/platform/web/response"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNoCredentials is an error that represents a request without the credentials of an authenticator
+	ErrNoCredentials = errors.New("auth: no credentials")
+	// ErrInvalidCredentials is an error that represents a request with credentials that are not valid
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+	// ErrInvalidRole is an error that represents an unknown role
+	ErrInvalidRole = errors.New("auth: invalid role")
+)
+
+// Role is the role of a principal, each role has the permissions of the ones below it
+type Role string
+
+const (
+	// RoleReader can query the vehicles
+	RoleReader Role = "reader"
+	// RoleEditor can also create, update and delete vehicles
+	RoleEditor Role = "editor"
+	// RoleAdmin can also use the admin endpoints
+	RoleAdmin Role = "admin"
+)
+
+// roleRanks is the rank of each role, from the lowest
+var roleRanks = map[Role]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// ParseRole is a function that returns the role with the given name
+func ParseRole(name string) (r Role, err error) {
+	if _, ok := roleRanks[Role(name)]; !ok {
+		err = fmt.Errorf("%w: %q, expected reader, editor or admin", ErrInvalidRole, name)
+		return
+	}
+	r = Role(name)
+	return
+}
+
+// Allows returns true if the role has the permissions of the required one
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required] && roleRanks[r] > 0
+}
+
+// Principal is a struct that represents who made a request
+type Principal struct {
+	// Subject identifies the client (the sub claim of a token or a fingerprint of an API key)
+	Subject string
+	// Role is the role of the client
+	Role Role
+}
+
+// Authenticator is an interface that represents a kind of credentials of a request
+type Authenticator interface {
+	// Authenticate returns the principal of the credentials of the request
+	// - ErrNoCredentials if the request does not have this kind of credentials, so the next authenticator is tried
+	// - an error wrapping ErrInvalidCredentials if it has them, but they are not valid
+	Authenticate(r *http.Request) (p Principal, err error)
+}
+
+// contextKey is the type of the key of the principal in a context
+type contextKey struct{}
+
+// NewContext returns a copy of ctx with the principal of the request
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal of the request, ok is false if the request is anonymous
+func FromContext(ctx context.Context) (p Principal, ok bool) {
+	p, ok = ctx.Value(contextKey{}).(Principal)
+	return
+}
+
+// Challenge is the value of the WWW-Authenticate header of the 401 responses
+const Challenge = `Bearer realm="vehicles"`
+
+// Middleware returns a middleware that sets the principal of a request from its credentials
+// - the authenticators are tried in order, the first one that finds its credentials decides
+// - a request without credentials goes on anonymous (see Require), one with invalid credentials gets 401
+func Middleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, a := range authenticators {
+				p, err := a.Authenticate(r)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					writeUnauthorized(w, r, "invalid credentials")
+					return
+				}
+				r = r.WithContext(NewContext(r.Context(), p))
+				break
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Require returns a middleware that only lets through the requests of a principal with the given role (or a higher one)
+// - anonymous requests get 401, requests with a lower role get 403
+func Require(role Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := FromContext(r.Context())
+			if !ok {
+				writeUnauthorized(w, r, "authentication required")
+				return
+			}
+			if !p.Role.Allows(role) {
+				response.WriteProblem(w, r, response.Problem{
+					Status: http.StatusForbidden,
+					Detail: fmt.Sprintf("forbidden, requires the %s role", role),
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeUnauthorized is a function that writes a 401 with the challenge of the credentials
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", Challenge)
+	response.WriteProblem(w, r, response.Problem{Status: http.StatusUnauthorized, Detail: detail})
+}
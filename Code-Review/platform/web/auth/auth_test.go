@@ -0,0 +1,187 @@
+package auth_test
+
+import (
+	"app/platform/web/auth"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newKey returns a random key generated for the test
+func newKey(t *testing.T) string {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return hex.EncodeToString(b)
+}
+
+// newToken returns a token with the claims signed with the secret
+func newToken(t *testing.T, secret string, c auth.Claims) string {
+	token, err := auth.SignJWT([]byte(secret), c)
+	require.NoError(t, err)
+	return token
+}
+
+// Tests for APIKeys
+func TestAPIKeys_Authenticate(t *testing.T) {
+	// arrange
+	key, other := newKey(t), newKey(t)
+	a := auth.NewAPIKeys(map[string]auth.Role{key: auth.RoleEditor})
+
+	cases := []struct {
+		name       string
+		header     string
+		value      string
+		expectRole auth.Role
+		expectErr  error
+	}{
+		{"header", auth.HeaderAPIKey, key, auth.RoleEditor, nil},
+		{"bearer token", "Authorization", "Bearer " + key, auth.RoleEditor, nil},
+		{"unknown key in the header", auth.HeaderAPIKey, other, "", auth.ErrInvalidCredentials},
+		{"unknown bearer token is left to the next authenticator", "Authorization", "Bearer " + other, "", auth.ErrNoCredentials},
+		{"no credentials", "", "", "", auth.ErrNoCredentials},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/vehicles", nil)
+			if c.header != "" {
+				r.Header.Set(c.header, c.value)
+			}
+			// act
+			p, err := a.Authenticate(r)
+			// assert
+			require.ErrorIs(t, err, c.expectErr)
+			require.Equal(t, c.expectRole, p.Role)
+			if c.expectErr == nil {
+				require.True(t, strings.HasPrefix(p.Subject, "key:"))
+				require.NotContains(t, p.Subject, key[:8])
+			}
+		})
+	}
+}
+
+// Tests for JWT
+func TestJWT_Verify(t *testing.T) {
+	// arrange
+	secret := newKey(t)
+	j := auth.NewJWT([]byte(secret), "vehicles-auth", "vehicles")
+	now := time.Now()
+	valid := auth.Claims{
+		Subject:   "analyst",
+		Role:      auth.RoleReader,
+		Issuer:    "vehicles-auth",
+		Audience:  auth.Audience{"vehicles", "reports"},
+		ExpiresAt: now.Add(time.Hour).Unix(),
+		IssuedAt:  now.Unix(),
+	}
+	with := func(edit func(c *auth.Claims)) auth.Claims {
+		c := valid
+		edit(&c)
+		return c
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	unsigned := header + "." + strings.Split(newToken(t, secret, valid), ".")[1] + "."
+
+	cases := []struct {
+		name          string
+		token         string
+		expectReason  string
+		expectSubject string
+	}{
+		{"valid", newToken(t, secret, valid), "", "analyst"},
+		{"single audience", newToken(t, secret, with(func(c *auth.Claims) { c.Audience = auth.Audience{"vehicles"} })), "", "analyst"},
+		{"other secret", newToken(t, newKey(t), valid), "bad signature", ""},
+		{"algorithm none", unsigned, "unsupported algorithm none", ""},
+		{"malformed", "not-a-token", "malformed token", ""},
+		{"expired", newToken(t, secret, with(func(c *auth.Claims) { c.ExpiresAt = now.Add(-time.Minute).Unix() })), "token expired", ""},
+		{"missing exp", newToken(t, secret, with(func(c *auth.Claims) { c.ExpiresAt = 0 })), "missing exp", ""},
+		{"not valid yet", newToken(t, secret, with(func(c *auth.Claims) { c.NotBefore = now.Add(time.Minute).Unix() })), "token not valid yet", ""},
+		{"unexpected issuer", newToken(t, secret, with(func(c *auth.Claims) { c.Issuer = "someone" })), "unexpected iss", ""},
+		{"unexpected audience", newToken(t, secret, with(func(c *auth.Claims) { c.Audience = auth.Audience{"reports"} })), "unexpected aud", ""},
+		{"unknown role", newToken(t, secret, with(func(c *auth.Claims) { c.Role = "root" })), "invalid role", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			claims, err := j.Verify(c.token)
+			// assert
+			if c.expectReason != "" {
+				require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+				require.ErrorContains(t, err, c.expectReason)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expectSubject, claims.Subject)
+		})
+	}
+}
+
+// Tests for Middleware and Require
+func TestRequire(t *testing.T) {
+	// arrange
+	key, secret := newKey(t), newKey(t)
+	h := auth.Middleware(
+		auth.NewAPIKeys(map[string]auth.Role{key: auth.RoleReader}),
+		auth.NewJWT([]byte(secret), "", ""),
+	)(auth.Require(auth.RoleEditor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := auth.FromContext(r.Context())
+		w.Write([]byte(p.Subject))
+	})))
+	admin := newToken(t, secret, auth.Claims{Subject: "ops", Role: auth.RoleAdmin, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+
+	cases := []struct {
+		name         string
+		header       string
+		value        string
+		expectCode   int
+		expectDetail string
+	}{
+		{"higher role", "Authorization", "Bearer " + admin, http.StatusOK, ""},
+		{"lower role", auth.HeaderAPIKey, key, http.StatusForbidden, "forbidden, requires the editor role"},
+		{"anonymous", "", "", http.StatusUnauthorized, "authentication required"},
+		{"invalid token", "Authorization", "Bearer " + admin + "x", http.StatusUnauthorized, "invalid credentials"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/vehicles/", nil)
+			if c.header != "" {
+				r.Header.Set(c.header, c.value)
+			}
+			w := httptest.NewRecorder()
+			// act
+			h.ServeHTTP(w, r)
+			// assert
+			require.Equal(t, c.expectCode, w.Code)
+			if c.expectCode == http.StatusOK {
+				require.Equal(t, "ops", w.Body.String())
+				return
+			}
+			require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+			require.Contains(t, w.Body.String(), `"detail":"`+c.expectDetail+`"`)
+			if c.expectCode == http.StatusUnauthorized {
+				require.Equal(t, auth.Challenge, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	// act
+	r, err := auth.ParseRole("editor")
+	_, errUnknown := auth.ParseRole("root")
+	// assert
+	require.NoError(t, err)
+	require.True(t, r.Allows(auth.RoleReader))
+	require.False(t, r.Allows(auth.RoleAdmin))
+	require.ErrorIs(t, errUnknown, auth.ErrInvalidRole)
+}
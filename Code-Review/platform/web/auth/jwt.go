@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Claims is a struct that represents the claims of a JWT
+type Claims struct {
+	// Subject identifies the client
+	Subject string `json:"sub"`
+	// Role is the role of the client: reader, editor or admin
+	Role Role `json:"role"`
+	// Issuer identifies who signed the token
+	Issuer string `json:"iss,omitempty"`
+	// Audience identifies who the token is for (a string or a list of strings in the token)
+	Audience Audience `json:"aud,omitempty"`
+	// ExpiresAt is the unix time after which the token is not valid
+	ExpiresAt int64 `json:"exp"`
+	// NotBefore is the unix time before which the token is not valid (optional)
+	NotBefore int64 `json:"nbf,omitempty"`
+	// IssuedAt is the unix time the token was signed (optional)
+	IssuedAt int64 `json:"iat,omitempty"`
+}
+
+// Audience is the list of audiences of a JWT, encoded as a string if it has one
+type Audience []string
+
+// MarshalJSON encodes a single audience as a string
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON decodes a string or a list of strings
+func (a *Audience) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*a = Audience{s}
+		return
+	}
+	var list []string
+	err = json.Unmarshal(b, &list)
+	*a = list
+	return
+}
+
+// jwtHeader is a struct that represents the header of a JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// jwtLeeway is the clock skew allowed when checking the times of a JWT
+const jwtLeeway = 30 * time.Second
+
+// NewJWT is a function that returns a new instance of JWT
+// - issuer and audience are checked if they are not empty
+func NewJWT(secret []byte, issuer string, audience string) *JWT {
+	return &JWT{secret: secret, issuer: issuer, audience: audience}
+}
+
+// JWT is a struct that implements Authenticator with bearer tokens signed with HMAC-SHA256 (HS256)
+// - the token must have a subject, a valid role and an expiration time
+// - any other algorithm (including none) is rejected
+type JWT struct {
+	// secret is the key of the signatures
+	secret []byte
+	// issuer is the required iss claim (empty: not checked)
+	issuer string
+	// audience is the audience the aud claim must contain (empty: not checked)
+	audience string
+}
+
+// Authenticate is a method that returns the principal of the bearer token of the request
+func (j *JWT) Authenticate(r *http.Request) (p Principal, err error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		err = ErrNoCredentials
+		return
+	}
+
+	c, err := j.Verify(token)
+	if err != nil {
+		return
+	}
+	p = Principal{Subject: c.Subject, Role: c.Role}
+	return
+}
+
+// Verify is a method that returns the claims of a token, if its signature and claims are valid
+func (j *JWT) Verify(token string) (c Claims, err error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidCredentials, reason)
+	}
+
+	// signature
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		err = invalid("malformed token")
+		return
+	}
+	var h jwtHeader
+	if decodeSegment(parts[0], &h) != nil {
+		err = invalid("malformed header")
+		return
+	}
+	if h.Alg != "HS256" {
+		err = invalid("unsupported algorithm " + h.Alg)
+		return
+	}
+	signature, errDecode := base64.RawURLEncoding.DecodeString(parts[2])
+	if errDecode != nil || !hmac.Equal(signature, sign(j.secret, parts[0]+"."+parts[1])) {
+		err = invalid("bad signature")
+		return
+	}
+
+	// claims
+	if decodeSegment(parts[1], &c) != nil {
+		err = invalid("malformed claims")
+		return
+	}
+	now := time.Now()
+	switch {
+	case c.ExpiresAt == 0:
+		err = invalid("missing exp")
+	case now.After(time.Unix(c.ExpiresAt, 0).Add(jwtLeeway)):
+		err = invalid("token expired")
+	case c.NotBefore != 0 && now.Before(time.Unix(c.NotBefore, 0).Add(-jwtLeeway)):
+		err = invalid("token not valid yet")
+	case c.Subject == "":
+		err = invalid("missing sub")
+	case j.issuer != "" && c.Issuer != j.issuer:
+		err = invalid("unexpected iss")
+	case j.audience != "" && !slices.Contains(c.Audience, j.audience):
+		err = invalid("unexpected aud")
+	default:
+		if _, errRole := ParseRole(string(c.Role)); errRole != nil {
+			err = invalid("invalid role")
+		}
+	}
+	return
+}
+
+// SignJWT is a function that returns a token with the claims signed with HS256
+// - it is meant for tests and tools: the service only verifies tokens
+func SignJWT(secret []byte, c Claims) (token string, err error) {
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return
+	}
+	claims, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	token = unsigned + "." + base64.RawURLEncoding.EncodeToString(sign(secret, unsigned))
+	return
+}
+
+// sign is a function that returns the HMAC-SHA256 of a message
+func sign(secret []byte, message string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// decodeSegment is a function that decodes a base64url segment of a JWT as JSON
+func decodeSegment(segment string, ptr any) (err error) {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, ptr)
+	return
+}
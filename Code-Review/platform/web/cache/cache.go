@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VersionFunc is a function that returns the current version of the data served and the time it was made
+// - a request whose version can not be read is served as is: without validators, not cached
+type VersionFunc func(ctx context.Context) (version uint64, modified time.Time, err error)
+
+// NewValidator is a function that returns a new instance of Validator
+// - maxAge is how long clients can reuse a response without asking again (0: every time)
+// - vary are the request headers, besides the URL, that change the response (e.g. Accept)
+func NewValidator(version VersionFunc, maxAge time.Duration, vary ...string) *Validator {
+	cacheControl := "private, no-cache"
+	if maxAge > 0 {
+		cacheControl = "private, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+	}
+	return &Validator{version: version, cacheControl: cacheControl, vary: vary}
+}
+
+// Validator is a struct that represents the validators of the responses of a versioned dataset
+// - the ETag is strong, derived from the version, the URL (query sorted) and the vary headers
+// - Last-Modified is the time of the version
+type Validator struct {
+	// version returns the current version
+	version VersionFunc
+	// cacheControl is the value of the Cache-Control header
+	cacheControl string
+	// vary are the request headers that change the response
+	vary []string
+}
+
+// key is a method that returns the key of the response of a request, for a version
+func (v *Validator) key(r *http.Request, version uint64) string {
+	var b strings.Builder
+	b.WriteString(strconv.FormatUint(version, 10))
+	b.WriteString("\n" + r.Method + " " + r.URL.Path + "?" + r.URL.Query().Encode())
+	for _, h := range v.vary {
+		b.WriteString("\n" + h + ": " + strings.Join(r.Header.Values(h), ","))
+	}
+	return b.String()
+}
+
+// etag is a method that returns the ETag of the response of a request, for a version
+func (v *Validator) etag(r *http.Request, version uint64) string {
+	sum := sha256.Sum256([]byte(v.key(r, version)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Middleware is a method that returns a middleware that validates the GET / HEAD requests
+// - responses 200 get the ETag, Last-Modified and Cache-Control headers, unless the version changed while they were made
+// - If-None-Match with the ETag (or If-Modified-Since, without If-None-Match) gets 304 without a body
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		version, modified, err := v.version(r.Context())
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		etag, lastModified := v.etag(r, version), modified.UTC().Format(http.TimeFormat)
+		if len(v.vary) > 0 {
+			w.Header().Set("Vary", strings.Join(v.vary, ", "))
+		}
+		if notModified(r, etag, modified) {
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", lastModified)
+			w.Header().Set("Cache-Control", v.cacheControl)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		next.ServeHTTP(&validatorWriter{ResponseWriter: w, set: func() {
+			if current, _, err := v.version(r.Context()); err != nil || current != version {
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", lastModified)
+			w.Header().Set("Cache-Control", v.cacheControl)
+		}}, r)
+	})
+}
+
+// notModified is a function that returns true if the conditional headers of a request match the response
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// validatorWriter is a struct that represents a response writer that sets the validators of a response 200
+type validatorWriter struct {
+	http.ResponseWriter
+	// set sets the validators
+	set func()
+	// wroteHeader is true once the status code is written
+	wroteHeader bool
+}
+
+// WriteHeader is a method that sets the validators if the status code is 200, then writes it
+func (w *validatorWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if code == http.StatusOK {
+			w.set()
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write is a method that writes the status code 200 if it was not written, then the body
+func (w *validatorWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap is a method that returns the wrapped response writer (see http.ResponseController)
+func (w *validatorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// NewCache is a function that returns a new instance of Cache
+// - maxEntries is the maximum number of responses kept, the cache is emptied when it is full
+func NewCache(v *Validator, maxEntries int) *Cache {
+	return &Cache{validator: v, maxEntries: maxEntries, entries: make(map[string]entry)}
+}
+
+// Cache is a struct that represents an in-process cache of the responses 200 of the GET requests
+// - responses are kept by the key of their validator, and dropped when the version changes
+type Cache struct {
+	// validator is the validator of the responses, whose version and key are used
+	validator *Validator
+	// maxEntries is the maximum number of responses kept
+	maxEntries int
+	// mu is the mutex that protects the entries
+	mu sync.Mutex
+	// version is the version of the entries
+	version uint64
+	// entries are the responses by key
+	entries map[string]entry
+}
+
+// entry is a struct that represents a cached response
+type entry struct {
+	// header is the header set by the handler
+	header http.Header
+	// body is the body of the response
+	body []byte
+}
+
+// get is a method that returns the response of a key for a version, dropping the entries of other versions
+func (c *Cache) get(key string, version uint64) (e entry, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		c.version, c.entries = version, make(map[string]entry)
+	}
+	e, ok = c.entries[key]
+	return
+}
+
+// put is a method that keeps the response of a key for a version, unless the version changed
+func (c *Cache) put(key string, version uint64, e entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.entries = make(map[string]entry)
+	}
+	c.entries[key] = e
+}
+
+// Middleware is a method that returns a middleware that serves the GET requests from the cache
+// - a miss is served by next and kept if its status code is 200 and the version did not change meanwhile
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		version, _, err := c.validator.version(r.Context())
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := c.validator.key(r, version)
+		e, ok := c.get(key, version)
+		if !ok {
+			rec := &recorder{header: make(http.Header), code: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			e = entry{header: rec.header, body: rec.body.Bytes()}
+			if rec.code != http.StatusOK {
+				writeEntry(w, rec.code, e)
+				return
+			}
+			if current, _, err := c.validator.version(r.Context()); err == nil && current == version {
+				c.put(key, version, e)
+			}
+		}
+		writeEntry(w, http.StatusOK, e)
+	})
+}
+
+// writeEntry is a function that writes a response
+func writeEntry(w http.ResponseWriter, code int, e entry) {
+	for name, values := range e.header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.WriteHeader(code)
+	w.Write(e.body)
+}
+
+// recorder is a struct that represents a response writer that keeps the response in memory
+type recorder struct {
+	// header is the header of the response
+	header http.Header
+	// code is the status code of the response
+	code int
+	// wroteHeader is true once the status code is written
+	wroteHeader bool
+	// body is the body of the response
+	body bytes.Buffer
+}
+
+// Header is a method that returns the header of the response
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+// WriteHeader is a method that keeps the first status code
+func (r *recorder) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader, r.code = true, code
+}
+
+// Write is a method that keeps the body
+func (r *recorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
@@ -0,0 +1,202 @@
+package cache_test
+
+import (
+	"app/platform/web/cache"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// dataset is a struct that represents a versioned dataset for the tests
+type dataset struct {
+	version  uint64
+	modified time.Time
+	// err is the error of Version, if set
+	err error
+}
+
+// Version is a method that returns the version of the dataset
+func (d *dataset) Version(ctx context.Context) (uint64, time.Time, error) {
+	return d.version, d.modified, d.err
+}
+
+// Tests for Validator
+func TestValidator_Middleware(t *testing.T) {
+	// arrange
+	ds := &dataset{version: 1, modified: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	v := cache.NewValidator(ds.Version, 0, "Accept")
+	hd := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("vehicles"))
+	}))
+	// serve is a function that makes a GET request with the given headers
+	serve := func(target string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, value := range header {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		hd.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("case - validators of a response 200", func(t *testing.T) {
+		// act
+		w := serve("/vehicles?b=2&a=1", nil)
+		same := serve("/vehicles?a=1&b=2", nil)
+		csv := serve("/vehicles?a=1&b=2", map[string]string{"Accept": "text/csv"})
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Regexp(t, `^"[0-9a-f]{32}"$`, w.Header().Get("ETag"))
+		require.Equal(t, "Tue, 02 Jan 2024 03:04:05 GMT", w.Header().Get("Last-Modified"))
+		require.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+		require.Equal(t, "Accept", w.Header().Get("Vary"))
+		require.Equal(t, w.Header().Get("ETag"), same.Header().Get("ETag"))
+		require.NotEqual(t, w.Header().Get("ETag"), csv.Header().Get("ETag"))
+	})
+
+	t.Run("case - not modified", func(t *testing.T) {
+		// arrange
+		etag := serve("/vehicles", nil).Header().Get("ETag")
+		cases := []struct {
+			name       string
+			header     map[string]string
+			expectCode int
+		}{
+			{"if-none-match", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+			{"if-none-match in a list, weak", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+			{"if-none-match any", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+			{"if-none-match other", map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+			{"if-modified-since", map[string]string{"If-Modified-Since": "Tue, 02 Jan 2024 03:04:05 GMT"}, http.StatusNotModified},
+			{"if-modified-since before", map[string]string{"If-Modified-Since": "Tue, 02 Jan 2024 03:04:04 GMT"}, http.StatusOK},
+			{"if-none-match wins", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": "Tue, 02 Jan 2024 03:04:05 GMT"}, http.StatusOK},
+		}
+		for _, c := range cases {
+			// act
+			w := serve("/vehicles", c.header)
+			// assert
+			require.Equal(t, c.expectCode, w.Code, c.name)
+			if c.expectCode == http.StatusNotModified {
+				require.Empty(t, w.Body.String(), c.name)
+				require.Equal(t, etag, w.Header().Get("ETag"), c.name)
+			}
+		}
+	})
+
+	t.Run("case - a new version changes the etag", func(t *testing.T) {
+		// arrange
+		etag := serve("/vehicles", nil).Header().Get("ETag")
+		ds.version++
+		// act
+		w := serve("/vehicles", map[string]string{"If-None-Match": etag})
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NotEqual(t, etag, w.Header().Get("ETag"))
+	})
+
+	t.Run("case - errors have no validators", func(t *testing.T) {
+		// act
+		w := serve("/missing", nil)
+		// assert
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Empty(t, w.Header().Get("ETag"))
+	})
+
+	t.Run("case - without a version there are no validators", func(t *testing.T) {
+		// arrange
+		ds.err = errors.New("dataset: unavailable")
+		defer func() { ds.err = nil }()
+		// act
+		w := serve("/vehicles", map[string]string{"If-None-Match": "*"})
+		// assert
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "vehicles", w.Body.String())
+		require.Empty(t, w.Header().Get("ETag"))
+	})
+
+	t.Run("case - max age", func(t *testing.T) {
+		// arrange
+		hd := cache.NewValidator(ds.Version, time.Minute).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("vehicles")) }))
+		w := httptest.NewRecorder()
+		// act
+		hd.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vehicles", nil))
+		// assert
+		require.Equal(t, "private, max-age=60", w.Header().Get("Cache-Control"))
+	})
+}
+
+// Tests for Cache
+func TestCache_Middleware(t *testing.T) {
+	// arrange
+	ds := &dataset{version: 1}
+	calls := 0
+	c := cache.NewCache(cache.NewValidator(ds.Version, 0, "Accept"), 2)
+	hd := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Has("fail") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": 180}`))
+	}))
+	// serve is a function that makes a GET request
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		hd.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	t.Run("case - hit", func(t *testing.T) {
+		// act
+		miss, hit := serve("/vehicles/average_speed/brand/Ford"), serve("/vehicles/average_speed/brand/Ford")
+		// assert
+		require.Equal(t, 1, calls)
+		require.Equal(t, http.StatusOK, hit.Code)
+		require.Equal(t, miss.Body.String(), hit.Body.String())
+		require.Equal(t, "application/json", hit.Header().Get("Content-Type"))
+	})
+
+	t.Run("case - a new version drops the responses", func(t *testing.T) {
+		// arrange
+		calls = 0
+		ds.version++
+		// act
+		serve("/vehicles/average_speed/brand/Ford")
+		serve("/vehicles/average_speed/brand/Ford")
+		// assert
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("case - errors are not kept", func(t *testing.T) {
+		// arrange
+		calls = 0
+		// act
+		first, second := serve("/vehicles/stats?fail"), serve("/vehicles/stats?fail")
+		// assert
+		require.Equal(t, 2, calls)
+		require.Equal(t, http.StatusBadRequest, first.Code)
+		require.Equal(t, http.StatusBadRequest, second.Code)
+	})
+
+	t.Run("case - without a version the responses are not kept", func(t *testing.T) {
+		// arrange
+		calls = 0
+		ds.err = errors.New("dataset: unavailable")
+		defer func() { ds.err = nil }()
+		// act
+		first, second := serve("/vehicles/average_speed/brand/Ford"), serve("/vehicles/average_speed/brand/Ford")
+		// assert
+		require.Equal(t, 2, calls)
+		require.Equal(t, http.StatusOK, first.Code)
+		require.Equal(t, http.StatusOK, second.Code)
+	})
+}
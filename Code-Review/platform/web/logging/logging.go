@@ -0,0 +1,142 @@
+package logging
+
+import (
+	"app/platform/web/requestid"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// NewLogger is a function that returns a logger that writes to w
+// - format: json or text (default: json)
+// - the records logged with a context have the request id of the context (see requestid)
+func NewLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch format {
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(NewHandlerRequestID(h))
+}
+
+// NewHandlerRequestID is a function that returns a handler that adds the request id of the context of a record
+func NewHandlerRequestID(h slog.Handler) *HandlerRequestID {
+	return &HandlerRequestID{Handler: h}
+}
+
+// HandlerRequestID is a struct that adds the request id of the context to the records of the wrapped handler
+type HandlerRequestID struct {
+	// Handler is the handler that writes the records
+	slog.Handler
+}
+
+// Handle is a method that adds the request id of ctx, if any, to the record
+func (h *HandlerRequestID) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs is a method that returns a handler with the attributes, that still adds the request id
+func (h *HandlerRequestID) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &HandlerRequestID{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup is a method that returns a handler with the group, that still adds the request id
+func (h *HandlerRequestID) WithGroup(name string) slog.Handler {
+	return &HandlerRequestID{Handler: h.Handler.WithGroup(name)}
+}
+
+// contextKey is the type of the key of the logger in a context
+type contextKey struct{}
+
+// discard is the logger of the contexts without one, that writes nothing
+var discard = slog.New(discardHandler{})
+
+// discardHandler is a struct that represents a handler that is enabled for no level
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// NewContext is a function that returns a copy of ctx with the logger
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext is a function that returns the logger of ctx
+// - it returns a logger that writes nothing if the request is not served through Middleware
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return discard
+}
+
+// Middleware returns a middleware that logs every request once it has been served
+// - the logger is in the context of the request, for the handlers (see FromContext)
+// - attributes: method, path, route pattern, route params, status, bytes written, latency and the request id (if set before)
+// - level: error for 5xx, warn for 4xx, info otherwise
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					// - nothing written: net/http responds 200
+					status = http.StatusOK
+				}
+				attrs := []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Duration("latency", time.Since(start)),
+				}
+				if rctx := chi.RouteContext(r.Context()); rctx != nil {
+					attrs = append(attrs, slog.String("route", rctx.RoutePattern()))
+					if params := routeParams(rctx); len(params) > 0 {
+						attrs = append(attrs, slog.Any("params", params))
+					}
+				}
+
+				level := slog.LevelInfo
+				switch {
+				case status >= 500:
+					level = slog.LevelError
+				case status >= 400:
+					level = slog.LevelWarn
+				}
+				logger.LogAttrs(r.Context(), level, "request", attrs...)
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(NewContext(r.Context(), logger)))
+		})
+	}
+}
+
+// routeParams is a function that returns the url params of the route (not the wildcards of the subrouters)
+func routeParams(rctx *chi.Context) map[string]string {
+	params := make(map[string]string, len(rctx.URLParams.Keys))
+	for i, key := range rctx.URLParams.Keys {
+		if key == "*" || strings.TrimSpace(key) == "" {
+			continue
+		}
+		params[key] = rctx.URLParams.Values[i]
+	}
+	return params
+}
@@ -0,0 +1,93 @@
+package logging_test
+
+import (
+	"app/platform/web/logging"
+	"app/platform/web/requestid"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for Middleware function
+func TestMiddleware(t *testing.T) {
+	// arrange
+	var b bytes.Buffer
+	logger := logging.NewLogger(&b, "json", slog.LevelInfo)
+	rt := chi.NewRouter()
+	rt.Use(requestid.Middleware)
+	rt.Use(logging.Middleware(logger))
+	rt.Route("/vehicles", func(r chi.Router) {
+		r.Get("/color/{color}/year/{year}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		})
+	})
+	r := httptest.NewRequest(http.MethodGet, "/vehicles/color/red/year/2010", nil)
+	r.Header.Set(requestid.Header, "abc-123")
+	// act
+	rt.ServeHTTP(httptest.NewRecorder(), r)
+	// assert
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(b.Bytes(), &record))
+	require.Equal(t, "WARN", record["level"])
+	require.Equal(t, "request", record["msg"])
+	require.Equal(t, "GET", record["method"])
+	require.Equal(t, "/vehicles/color/red/year/2010", record["path"])
+	require.Equal(t, "/vehicles/color/{color}/year/{year}", record["route"])
+	require.Equal(t, map[string]any{"color": "red", "year": "2010"}, record["params"])
+	require.Equal(t, float64(http.StatusNotFound), record["status"])
+	require.Equal(t, float64(len("not found")), record["bytes"])
+	require.Contains(t, record, "latency")
+	require.Equal(t, "abc-123", record["request_id"])
+}
+
+// Tests for FromContext function
+func TestFromContext(t *testing.T) {
+	t.Run("logger of the middleware", func(t *testing.T) {
+		// arrange
+		var b bytes.Buffer
+		logger := logging.NewLogger(&b, "json", slog.LevelInfo)
+		h := logging.Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logging.FromContext(r.Context()).InfoContext(r.Context(), "handler")
+		}))
+		// act
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		// assert
+		require.Contains(t, b.String(), `"msg":"handler"`)
+	})
+
+	t.Run("without the middleware nothing is written", func(t *testing.T) {
+		// act
+		logger := logging.FromContext(context.Background())
+		// assert
+		require.NotNil(t, logger)
+		require.False(t, logger.Enabled(context.Background(), slog.LevelError))
+	})
+}
+
+// Tests for NewLogger function
+func TestNewLogger(t *testing.T) {
+	t.Run("request id of the context, also with attributes", func(t *testing.T) {
+		// arrange
+		var b bytes.Buffer
+		logger := logging.NewLogger(&b, "text", slog.LevelInfo).With("component", "test")
+		ctx := requestid.NewContext(context.Background(), "abc-123")
+		// act
+		logger.InfoContext(ctx, "hello")
+		logger.Info("no context")
+		logger.DebugContext(ctx, "below the level")
+		// assert
+		lines := bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+		require.Contains(t, string(lines[0]), "component=test")
+		require.Contains(t, string(lines[0]), "request_id=abc-123")
+		require.NotContains(t, string(lines[1]), "request_id")
+	})
+}
@@ -0,0 +1,261 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the default upper bounds of the buckets of a histogram, in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// NewRegistry is a function that returns a new instance of Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Registry is a struct that holds metric families and writes them in the Prometheus text exposition format
+// - families are written in the order they are registered, series ordered by label values
+type Registry struct {
+	// mu is the mutex that guards the families and their series
+	mu sync.Mutex
+	// families are the registered families
+	families []*family
+}
+
+// family is a struct that represents a metric with its series, one per combination of label values
+type family struct {
+	// name is the name of the metric (e.g. http_requests_total)
+	name string
+	// help is the description of the metric
+	help string
+	// kind is the type of the metric: counter, gauge or histogram
+	kind string
+	// labels are the names of the labels of the series
+	labels []string
+	// buckets are the upper bounds of the buckets (histogram)
+	buckets []float64
+	// series are the series by joined label values
+	series map[string]*series
+}
+
+// series is a struct that represents the value of a metric for some label values
+type series struct {
+	// values are the label values
+	values []string
+	// value is the value (counter and gauge)
+	value float64
+	// counts are the cumulative counts per bucket (histogram)
+	counts []uint64
+	// sum is the sum of the observations (histogram)
+	sum float64
+	// count is the number of observations (histogram)
+	count uint64
+}
+
+// register is a method that adds a family, panics if the name is taken (a programming error)
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, g := range r.families {
+		if g.name == f.name {
+			panic("metrics: duplicated metric " + f.name)
+		}
+	}
+	f.series = make(map[string]*series)
+	r.families = append(r.families, f)
+	return f
+}
+
+// get is a method that returns the series of the label values, creating it
+// - the caller must hold the lock of the registry
+func (f *family) get(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s: %d label values, expected %d", f.name, len(values), len(f.labels)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: slices.Clone(values)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter is a struct that represents a counter, a value that only goes up
+type Counter struct {
+	r *Registry
+	f *family
+}
+
+// Counter is a method that registers a counter with the given labels
+func (r *Registry) Counter(name string, help string, labels ...string) *Counter {
+	return &Counter{r: r, f: r.register(&family{name: name, help: help, kind: "counter", labels: labels})}
+}
+
+// Inc is a method that adds 1 to the series of the label values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add is a method that adds v (>= 0) to the series of the label values
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		return
+	}
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+
+	c.f.get(values).value += v
+}
+
+// Gauge is a struct that represents a gauge, a value that goes up and down
+type Gauge struct {
+	r *Registry
+	f *family
+}
+
+// Gauge is a method that registers a gauge with the given labels
+func (r *Registry) Gauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{r: r, f: r.register(&family{name: name, help: help, kind: "gauge", labels: labels})}
+}
+
+// Set is a method that sets the series of the label values to v
+func (g *Gauge) Set(v float64, values ...string) {
+	g.r.mu.Lock()
+	defer g.r.mu.Unlock()
+
+	g.f.get(values).value = v
+}
+
+// Add is a method that adds v (it can be negative) to the series of the label values
+func (g *Gauge) Add(v float64, values ...string) {
+	g.r.mu.Lock()
+	defer g.r.mu.Unlock()
+
+	g.f.get(values).value += v
+}
+
+// Histogram is a struct that represents a histogram, observations counted in buckets
+type Histogram struct {
+	r *Registry
+	f *family
+}
+
+// Histogram is a method that registers a histogram with the given buckets (sorted upper bounds, +Inf is implicit) and labels
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Histogram{r: r, f: r.register(&family{name: name, help: help, kind: "histogram", labels: labels, buckets: buckets})}
+}
+
+// Observe is a method that adds an observation to the series of the label values
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.r.mu.Lock()
+	defer h.r.mu.Unlock()
+
+	s := h.f.get(values)
+	for i, upper := range h.f.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// WriteTo is a method that writes every family in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (n int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range r.families {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.name, escape(f.help, false))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			switch f.kind {
+			case "histogram":
+				for i, upper := range f.buckets {
+					fmt.Fprintf(bw, "%s_bucket%s %d\n", f.name, labels(f.labels, s.values, "le", formatFloat(upper)), s.counts[i])
+				}
+				fmt.Fprintf(bw, "%s_bucket%s %d\n", f.name, labels(f.labels, s.values, "le", "+Inf"), s.count)
+				fmt.Fprintf(bw, "%s_sum%s %s\n", f.name, labels(f.labels, s.values), formatFloat(s.sum))
+				fmt.Fprintf(bw, "%s_count%s %d\n", f.name, labels(f.labels, s.values), s.count)
+			default:
+				fmt.Fprintf(bw, "%s%s %s\n", f.name, labels(f.labels, s.values), formatFloat(s.value))
+			}
+		}
+	}
+
+	n = int64(bw.Buffered())
+	err = bw.Flush()
+	return
+}
+
+// Handler is a method that returns a handler that serves the metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		r.WriteTo(w)
+	})
+}
+
+// labels is a function that returns the label set of a series, with extra name / value pairs (e.g. le)
+func labels(names []string, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+escape(values[i], true)+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+extra[i+1]+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escape is a function that escapes a help text or a label value (which also escapes double quotes)
+func escape(s string, quote bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quote {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+// formatFloat is a function that formats a value as Prometheus does (e.g. 0.005, 1e+06, +Inf, NaN)
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,94 @@
+package metrics_test
+
+import (
+	"app/platform/web/metrics"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for Registry
+func TestRegistry_WriteTo(t *testing.T) {
+	// arrange
+	reg := metrics.NewRegistry()
+	c := reg.Counter("jobs_total", "Jobs done.", "queue")
+	g := reg.Gauge("temperature", "Current\ntemperature.")
+	h := reg.Histogram("latency_seconds", "Latency.", []float64{1, 0.1}, "path")
+	c.Inc("b")
+	c.Add(2, "a")
+	c.Add(-1, "a")
+	c.Inc(`x"y\z`)
+	g.Set(21.5)
+	g.Add(-0.5)
+	h.Observe(0.05, "/")
+	h.Observe(0.5, "/")
+	h.Observe(5, "/")
+	// act
+	var b bytes.Buffer
+	_, err := reg.WriteTo(&b)
+	// assert
+	require.NoError(t, err)
+	expected := `# HELP jobs_total Jobs done.
+# TYPE jobs_total counter
+jobs_total{queue="a"} 2
+jobs_total{queue="b"} 1
+jobs_total{queue="x\"y\\z"} 1
+# HELP temperature Current\ntemperature.
+# TYPE temperature gauge
+temperature 21
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{path="/",le="0.1"} 1
+latency_seconds_bucket{path="/",le="1"} 2
+latency_seconds_bucket{path="/",le="+Inf"} 3
+latency_seconds_sum{path="/"} 5.55
+latency_seconds_count{path="/"} 3
+`
+	require.Equal(t, expected, b.String())
+}
+
+// Tests for Middleware
+func TestMiddleware(t *testing.T) {
+	// arrange
+	reg := metrics.NewRegistry()
+	rt := chi.NewRouter()
+	rt.Use(metrics.Middleware(reg, rt))
+	rt.Method(http.MethodGet, "/metrics", reg.Handler())
+	rt.Route("/vehicles", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			if chi.URLParam(r, "id") == "0" {
+				metrics.RecordError(r.Context(), "internal")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte("ok"))
+		})
+	})
+	for _, target := range []string{"/vehicles/1", "/vehicles/2", "/vehicles/0", "/unknown/path"} {
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	// act
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	// assert
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, metrics.ContentType, w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/vehicles/{id}",status="200"} 2`,
+		`http_requests_total{method="GET",route="/vehicles/{id}",status="500"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/vehicles/{id}"} 3`,
+		`http_requests_in_flight{method="GET",route="/vehicles/{id}"} 0`,
+		`http_requests_in_flight{method="GET",route="/metrics"} 1`,
+		`http_handler_errors_total{route="/vehicles/{id}",kind="internal"} 1`,
+	} {
+		require.Contains(t, body, line+"\n")
+	}
+	require.False(t, strings.Contains(body, "/unknown/path"))
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RouteUnmatched is the route label of the requests that match no route (e.g. 404), so paths do not become labels
+const RouteUnmatched = "unmatched"
+
+// Middleware returns a middleware that records the metrics of every request in reg, by method and route pattern
+// - http_requests_total: counter by method, route and status
+// - http_request_duration_seconds: histogram by method and route
+// - http_requests_in_flight: gauge by method and route
+// - http_handler_errors_total: counter by route and kind, of the errors recorded by the handlers (see RecordError)
+// - the route is matched on routes, so the middleware can be used before routing (e.g. on the root router)
+func Middleware(reg *Registry, routes chi.Routes) func(http.Handler) http.Handler {
+	requests := reg.Counter("http_requests_total", "Number of requests served, by method, route and status.", "method", "route", "status")
+	duration := reg.Histogram("http_request_duration_seconds", "Latency of the requests, by method and route.", DefaultBuckets, "method", "route")
+	inFlight := reg.Gauge("http_requests_in_flight", "Number of requests being served, by method and route.", "method", "route")
+	handlerErrors := reg.Counter("http_handler_errors_total", "Number of errors recorded by the handlers, by route and kind.", "route", "kind")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := RouteUnmatched
+			rctx := chi.NewRouteContext()
+			if routes.Match(rctx, r.Method, r.URL.Path) {
+				route = rctx.RoutePattern()
+			}
+
+			inFlight.Add(1, r.Method, route)
+			rec := &recorder{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				inFlight.Add(-1, r.Method, route)
+				duration.Observe(time.Since(start).Seconds(), r.Method, route)
+				requests.Inc(r.Method, route, strconv.Itoa(status))
+				for _, kind := range rec.kinds() {
+					handlerErrors.Inc(route, kind)
+				}
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), recorderKey{}, rec)))
+		})
+	}
+}
+
+// RecordError is a function that records an error of the given kind (e.g. internal) for the request of ctx
+// - it does nothing if the request is not served through Middleware
+func RecordError(ctx context.Context, kind string) {
+	if rec, ok := ctx.Value(recorderKey{}).(*recorder); ok {
+		rec.add(kind)
+	}
+}
+
+// recorderKey is the type of the key of the recorder of a request in its context
+type recorderKey struct{}
+
+// recorder is a struct that collects the errors recorded for a request
+type recorder struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+// add is a method that records an error kind
+func (r *recorder) add(kind string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errors = append(r.errors, kind)
+}
+
+// kinds is a method that returns the recorded error kinds
+func (r *recorder) kinds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.errors
+}
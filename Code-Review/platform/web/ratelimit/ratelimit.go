@@ -0,0 +1,218 @@
+package ratelimit
+
+import (
+	"app/platform/web/response"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLimitInvalid is an error that represents an invalid limit spec
+	ErrLimitInvalid = errors.New("ratelimit: invalid limit")
+)
+
+// Limit is a struct that represents the limit of a token bucket
+type Limit struct {
+	// Rate is the number of tokens added per second
+	Rate float64
+	// Burst is the size of the bucket, the number of requests allowed at once
+	Burst int
+}
+
+// ParseLimit is a function that returns the limit of a spec: rate/unit, optionally followed by :burst
+// - unit is s, m or h (e.g. 10/s, 100/m:20)
+// - the burst defaults to the rate per second, at least 1
+func ParseLimit(spec string) (l Limit, err error) {
+	invalid := fmt.Errorf("%w: %q, expected rate/unit[:burst] (e.g. 10/s:20)", ErrLimitInvalid, spec)
+
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(spec), ":")
+	n, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		err = invalid
+		return
+	}
+	count, errCount := strconv.ParseFloat(n, 64)
+	if errCount != nil || count <= 0 || math.IsInf(count, 0) {
+		err = invalid
+		return
+	}
+	switch unit {
+	case "s":
+		l.Rate = count
+	case "m":
+		l.Rate = count / 60
+	case "h":
+		l.Rate = count / 3600
+	default:
+		err = invalid
+		return
+	}
+
+	l.Burst = max(1, int(math.Round(l.Rate)))
+	if hasBurst {
+		l.Burst, err = strconv.Atoi(burst)
+		if err != nil || l.Burst < 1 {
+			l, err = Limit{}, invalid
+			return
+		}
+	}
+	return
+}
+
+// sweepEvery is the number of calls to Allow between removals of the full buckets
+const sweepEvery = 1024
+
+// NewLimiter is a function that returns a new instance of Limiter
+func NewLimiter(l Limit) *Limiter {
+	return &Limiter{limit: l, buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Limiter is a struct that represents a token bucket per key
+// - a bucket starts full and gets Rate tokens per second up to Burst, each request takes a token
+// - full buckets are removed from time to time: they are the same as a new one
+type Limiter struct {
+	// limit is the limit of every bucket
+	limit Limit
+	// mu is the mutex that protects the buckets
+	mu sync.Mutex
+	// buckets is the bucket of each key
+	buckets map[string]*bucket
+	// calls is the number of calls to Allow since the last sweep
+	calls int
+	// now returns the current time
+	now func() time.Time
+}
+
+// bucket is a struct that represents the tokens of a key
+type bucket struct {
+	// tokens is the number of tokens at last
+	tokens float64
+	// last is the time of the last update
+	last time.Time
+}
+
+// Result is a struct that represents the decision of a Limiter on a request
+type Result struct {
+	// Allowed is true if the request took a token
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token, if the request was not allowed
+	RetryAfter time.Duration
+}
+
+// Allow is a method that takes a token of the bucket of the key, if there is one
+func (l *Limiter) Allow(key string) (res Result) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	res.Limit = l.limit.Burst
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / l.limit.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((float64(l.limit.Burst) - b.tokens) / l.limit.Rate)
+	return
+}
+
+// refill is a method that returns the tokens of a bucket at the given time
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(float64(l.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate)
+}
+
+// sweep is a method that removes the full buckets every sweepEvery calls
+// - the caller must hold the lock
+func (l *Limiter) sweep(now time.Time) {
+	l.calls++
+	if l.calls < sweepEvery {
+		return
+	}
+	l.calls = 0
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// seconds is a function that converts a number of seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Middleware returns a middleware that limits the rate of the requests of each key
+// - every response has the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers (in seconds)
+// - requests over the limit get 429 with Retry-After (in seconds)
+func Middleware(l *Limiter, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := l.Allow(key(r))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+			if !res.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+				response.WriteProblem(w, r, response.Problem{Status: http.StatusTooManyRequests, Detail: "rate limit exceeded"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MaxInFlight returns a middleware that limits the number of requests served at once
+// - requests over the limit are not queued, they get 429 with Retry-After
+func MaxInFlight(n int) func(http.Handler) http.Handler {
+	sem := make(chan struct{}, n)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				next.ServeHTTP(w, r)
+			default:
+				w.Header().Set("Retry-After", "1")
+				response.WriteProblem(w, r, response.Problem{Status: http.StatusTooManyRequests, Detail: "too many requests in flight"})
+			}
+		})
+	}
+}
+
+// ClientIP is a function that returns the IP of the client of a request (the peer address, proxies are not trusted)
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds is a function that formats a duration as a whole number of seconds, rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,159 @@
+package ratelimit_test
+
+import (
+	"app/platform/web/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for ParseLimit function
+func TestParseLimit(t *testing.T) {
+	t.Run("case - success", func(t *testing.T) {
+		cases := []struct {
+			spec   string
+			expect ratelimit.Limit
+		}{
+			{spec: "10/s:20", expect: ratelimit.Limit{Rate: 10, Burst: 20}},
+			{spec: "10/s", expect: ratelimit.Limit{Rate: 10, Burst: 10}},
+			{spec: "120/m", expect: ratelimit.Limit{Rate: 2, Burst: 2}},
+			{spec: "60/h:5", expect: ratelimit.Limit{Rate: 1.0 / 60, Burst: 5}},
+			{spec: "0.5/s", expect: ratelimit.Limit{Rate: 0.5, Burst: 1}},
+		}
+		for _, c := range cases {
+			// act
+			l, err := ratelimit.ParseLimit(c.spec)
+			// assert
+			require.NoError(t, err, c.spec)
+			require.Equal(t, c.expect, l, c.spec)
+		}
+	})
+
+	t.Run("case error, invalid spec", func(t *testing.T) {
+		for _, spec := range []string{"", "10", "10/d", "0/s", "-1/s", "x/s", "10/s:0", "10/s:x"} {
+			// act
+			_, err := ratelimit.ParseLimit(spec)
+			// assert
+			require.ErrorIs(t, err, ratelimit.ErrLimitInvalid, spec)
+		}
+	})
+}
+
+// Tests for Limiter
+func TestLimiter_Allow(t *testing.T) {
+	t.Run("case - burst, then throttled by key", func(t *testing.T) {
+		// arrange
+		l := ratelimit.NewLimiter(ratelimit.Limit{Rate: 1.0 / 3600, Burst: 2})
+		// act
+		first, second, third := l.Allow("a"), l.Allow("a"), l.Allow("a")
+		other := l.Allow("b")
+		// assert
+		require.True(t, first.Allowed)
+		require.Equal(t, 1, first.Remaining)
+		require.True(t, second.Allowed)
+		require.Equal(t, 0, second.Remaining)
+		require.False(t, third.Allowed)
+		require.Equal(t, 2, third.Limit)
+		require.InDelta(t, time.Hour.Seconds(), third.RetryAfter.Seconds(), 1)
+		require.InDelta(t, 2*time.Hour.Seconds(), third.Reset.Seconds(), 1)
+		require.True(t, other.Allowed)
+	})
+
+	t.Run("case - refill", func(t *testing.T) {
+		// arrange
+		l := ratelimit.NewLimiter(ratelimit.Limit{Rate: 100, Burst: 1})
+		require.True(t, l.Allow("a").Allowed)
+		require.False(t, l.Allow("a").Allowed)
+		// act
+		time.Sleep(20 * time.Millisecond)
+		res := l.Allow("a")
+		// assert
+		require.True(t, res.Allowed)
+	})
+}
+
+// Tests for Middleware function
+func TestMiddleware(t *testing.T) {
+	// arrange
+	l := ratelimit.NewLimiter(ratelimit.Limit{Rate: 1.0 / 60, Burst: 1})
+	key := func(r *http.Request) string { return r.Header.Get("X-Client") }
+	hd := ratelimit.Middleware(l, key)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(client string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/average_speed/brand/Ford", nil)
+		r.Header.Set("X-Client", client)
+		w := httptest.NewRecorder()
+		hd.ServeHTTP(w, r)
+		return w
+	}
+	// act
+	allowed, throttled, other := serve("a"), serve("a"), serve("b")
+	// assert
+	require.Equal(t, http.StatusOK, allowed.Code)
+	require.Equal(t, "1", allowed.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "0", allowed.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "60", allowed.Header().Get("RateLimit-Reset"))
+	require.Empty(t, allowed.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusTooManyRequests, throttled.Code)
+	require.Equal(t, "0", throttled.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "60", throttled.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"type": "about:blank", "title": "Too Many Requests", "status": 429, "detail": "rate limit exceeded",
+		"instance": "/vehicles/average_speed/brand/Ford"}`, throttled.Body.String())
+
+	require.Equal(t, http.StatusOK, other.Code)
+}
+
+// Tests for MaxInFlight function
+func TestMaxInFlight(t *testing.T) {
+	// arrange
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	hd := ratelimit.MaxInFlight(1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case entered <- struct{}{}:
+		default:
+		}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		hd.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vehicles/stats", nil))
+		return w
+	}
+	var wg sync.WaitGroup
+	var first *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = serve()
+	}()
+	<-entered
+	// act
+	rejected := serve()
+	close(release)
+	wg.Wait()
+	// assert
+	require.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	require.Equal(t, "1", rejected.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusOK, first.Code)
+	// - the slot is released
+	require.Equal(t, http.StatusOK, serve().Code)
+}
+
+// Tests for ClientIP function
+func TestClientIP(t *testing.T) {
+	// arrange
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	// act
+	ip := ratelimit.ClientIP(r)
+	// assert
+	require.Equal(t, "192.0.2.1", ip)
+}
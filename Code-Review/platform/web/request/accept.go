@@ -0,0 +1,67 @@
+package request
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NegotiateMediaType returns the offered media type the Accept header prefers
+// - each offer gets the weight (q) of the most specific media range that matches it: type/subtype, type/*, */*
+// - the offer with the highest weight wins, ties go to the first offer (server preference)
+// - an empty header accepts the first offer, ok is false if every offer has weight 0
+func NegotiateMediaType(accept string, offers ...string) (mediaType string, ok bool) {
+	if len(offers) == 0 {
+		return
+	}
+	if strings.TrimSpace(accept) == "" {
+		mediaType, ok = offers[0], true
+		return
+	}
+
+	// media ranges
+	type mediaRange struct {
+		typ, subtype string
+		q            float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, _ := strings.Cut(part, ";")
+		typ, subtype, _ := strings.Cut(strings.ToLower(strings.TrimSpace(mt)), "/")
+		rg := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range strings.Split(params, ";") {
+			if value, found := strings.CutPrefix(strings.TrimSpace(param), "q="); found {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					rg.q = q
+				}
+			}
+		}
+		ranges = append(ranges, rg)
+	}
+
+	// weight of each offer
+	best := 0.0
+	for _, offer := range offers {
+		typ, subtype, _ := strings.Cut(strings.ToLower(offer), "/")
+		q, specificity := 0.0, -1
+		for _, rg := range ranges {
+			var s int
+			switch {
+			case rg.typ == typ && rg.subtype == subtype:
+				s = 2
+			case rg.typ == typ && rg.subtype == "*":
+				s = 1
+			case rg.typ == "*" && rg.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				q, specificity = rg.q, s
+			}
+		}
+		if q > best {
+			mediaType, ok, best = offer, true, q
+		}
+	}
+	return
+}
@@ -0,0 +1,40 @@
+package request_test
+
+import (
+	"app/platform/web/request"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for NegotiateMediaType function
+func TestNegotiateMediaType(t *testing.T) {
+	offers := []string{"application/json", "text/csv", "application/xml"}
+	cases := []struct {
+		name            string
+		accept          string
+		expectMediaType string
+		expectOk        bool
+	}{
+		{"empty header", "", "application/json", true},
+		{"any", "*/*", "application/json", true},
+		{"exact", "text/csv", "text/csv", true},
+		{"case and spaces", " Text/CSV ; charset=utf-8", "text/csv", true},
+		{"type wildcard", "text/*", "text/csv", true},
+		{"highest weight", "application/json;q=0.5, application/xml;q=0.8", "application/xml", true},
+		{"tie goes to the server preference", "application/xml, text/csv", "text/csv", true},
+		{"specific range over wildcard", "*/*;q=0.1, application/json;q=0", "text/csv", true},
+		{"refused", "application/json;q=0", "", false},
+		{"not offered", "image/png", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			mediaType, ok := request.NegotiateMediaType(c.accept, offers...)
+			// assert
+			require.Equal(t, c.expectOk, ok)
+			require.Equal(t, c.expectMediaType, mediaType)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header is the header of the request id, read from the request and echoed in the response
+const Header = "X-Request-ID"
+
+// MaxLength is the maximum length of a request id given by the client, longer ones are replaced
+const MaxLength = 128
+
+// contextKey is the type of the key of the request id in a context
+type contextKey struct{}
+
+// NewContext is a function that returns a copy of ctx with the request id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext is a function that returns the request id of ctx, or an empty string
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Middleware is a middleware that sets the id of each request
+// - the id is the X-Request-ID header of the request if it is valid, otherwise a new random one
+// - the id is set in the context of the request and in the X-Request-ID header of the response, before the handler writes it
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = New()
+		}
+
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// New is a function that returns a new random request id (32 hex characters)
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// valid is a function that returns true if the id can be logged and echoed as is
+// - 1 to MaxLength characters: letters, digits, '-', '_', '.' and ':'
+func valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package requestid_test
+
+import (
+	"app/platform/web/requestid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for Middleware function
+func TestMiddleware(t *testing.T) {
+	// serve is a function that returns the response and the request id seen by the handler
+	serve := func(header string) (w *httptest.ResponseRecorder, id string) {
+		h := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id = requestid.FromContext(r.Context())
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set(requestid.Header, header)
+		}
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	t.Run("given by the client", func(t *testing.T) {
+		// act
+		w, id := serve("req-1:a_b.c")
+		// assert
+		require.Equal(t, "req-1:a_b.c", id)
+		require.Equal(t, "req-1:a_b.c", w.Header().Get(requestid.Header))
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		for _, header := range []string{"", "with space", "<script>", strings.Repeat("a", requestid.MaxLength+1)} {
+			// act
+			w, id := serve(header)
+			// assert
+			require.Len(t, id, 32, header)
+			require.Equal(t, id, w.Header().Get(requestid.Header), header)
+		}
+	})
+
+	t.Run("unique", func(t *testing.T) {
+		// act
+		_, a := serve("")
+		_, b := serve("")
+		// assert
+		require.NotEqual(t, a, b)
+	})
+}
@@ -0,0 +1,47 @@
+package response_test
+
+import (
+	"app/platform/web/response"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for Error function
+func TestError(t *testing.T) {
+	t.Run("404 - not found", func(t *testing.T) {
+		// act
+		rr := httptest.NewRecorder()
+		response.Error(rr, http.StatusNotFound, "vehicles not found")
+
+		// assert
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"vehicles not found"}`, rr.Body.String())
+	})
+
+	t.Run("500 - with the request id of the response", func(t *testing.T) {
+		// arrange
+		rr := httptest.NewRecorder()
+		rr.Header().Set("X-Request-ID", "abc-123")
+
+		// act
+		response.Error(rr, http.StatusInternalServerError, "internal error")
+
+		// assert
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal error","request_id":"abc-123"}`, rr.Body.String())
+	})
+
+	t.Run("500 - invalid status code", func(t *testing.T) {
+		// act
+		rr := httptest.NewRecorder()
+		response.Error(rr, http.StatusOK, "ok")
+
+		// assert
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"ok"}`, rr.Body.String())
+	})
+}
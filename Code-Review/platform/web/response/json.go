@@ -7,6 +7,11 @@ import (
 
 // JSON writes json response
 func JSON(w http.ResponseWriter, code int, body any) {
+	JSONMediaType(w, code, "application/json", body)
+}
+
+// JSONMediaType writes json response with a specific json media type (e.g. a versioned vendor media type)
+func JSONMediaType(w http.ResponseWriter, code int, mediaType string, body any) {
 	// check body
 	if body == nil {
 		w.WriteHeader(code)
@@ -22,7 +27,7 @@ func JSON(w http.ResponseWriter, code int, body any) {
 	}
 
 	// set header
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
 
 	// set status code
 	w.WriteHeader(code)
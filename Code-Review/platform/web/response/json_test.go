@@ -86,4 +86,25 @@ func TestJSON(t *testing.T) {
 		require.Equal(t, expectedCode, rr.Code)
 		require.Equal(t, expectedBody, rr.Body.String())
 	})
-}
\ No newline at end of file
+}
+// Tests for JSONMediaType function
+func TestJSONMediaType(t *testing.T) {
+	t.Run("200 - vendor media type", func(t *testing.T) {
+		// arrange
+		// ...
+
+		// act
+		rr := httptest.NewRecorder()
+		code := http.StatusOK
+		body := struct{Message string}{Message: "ok"}
+		response.JSONMediaType(rr, code, "application/vnd.test.v1+json", body)
+
+		// assert
+		expectedHeader := http.Header{"Content-Type": []string{"application/vnd.test.v1+json; charset=utf-8"}}
+		expectedCode := http.StatusOK
+		expectedBody := `{"Message":"ok"}`
+		require.Equal(t, expectedHeader, rr.Header())
+		require.Equal(t, expectedCode, rr.Code)
+		require.JSONEq(t, expectedBody, rr.Body.String())
+	})
+}
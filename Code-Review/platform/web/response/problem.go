@@ -0,0 +1,148 @@
+package response
+
+import (
+	"app/platform/web/requestid"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ContentTypeProblem is the media type of the problem details of an error (RFC 7807)
+const ContentTypeProblem = "application/problem+json"
+
+// Problem is a struct that represents the problem details of an error (RFC 7807)
+// - it is also an error, so a handler can return a problem that is not registered
+type Problem struct {
+	// Type is a URI that identifies the kind of problem (about:blank if empty: the title is the status text)
+	Type string `json:"type"`
+	// Title is a short summary of the kind of problem (the status text if empty)
+	Title string `json:"title"`
+	// Status is the HTTP status code
+	Status int `json:"status"`
+	// Detail is the explanation of this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// Instance is the URI of this occurrence of the problem (the path of the request if empty)
+	Instance string `json:"instance,omitempty"`
+	// Errors is the list of invalid fields of the request
+	Errors []ProblemField `json:"errors,omitempty"`
+	// Suggestions is the list of values the client may have meant for the fields of the request
+	Suggestions []ProblemSuggestion `json:"suggestions,omitempty"`
+	// RequestID is the id of the request, taken from the X-Request-ID header of the response (see requestid.Middleware)
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ProblemField is a struct that represents an invalid field of a request
+type ProblemField struct {
+	// Field is the name of the field, as the client sent it
+	Field string `json:"field"`
+	// Message is the reason why the field is invalid
+	Message string `json:"message"`
+}
+
+// ProblemSuggestion is a struct that represents the values the client may have meant for a field of a request
+type ProblemSuggestion struct {
+	// Field is the name of the field, as the client sent it
+	Field string `json:"field"`
+	// Value is the value of the field, as the client sent it
+	Value string `json:"value"`
+	// DidYouMean is the list of known values like Value, nearest first
+	DidYouMean []string `json:"did_you_mean"`
+}
+
+// Error returns the detail of the problem (or its title)
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	if p.Title != "" {
+		return p.Title
+	}
+	return http.StatusText(p.Status)
+}
+
+// WriteProblem writes the problem details of an error
+// - r may be nil, then the problem has no instance unless it is set
+// - statuses outside 300-599 are written as 500
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	// defaults
+	if p.Status < 300 || p.Status > 599 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" && r != nil {
+		p.Instance = r.URL.Path
+	}
+	p.RequestID = w.Header().Get(requestid.Header)
+
+	bytes, err := json.Marshal(p)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// the header must be set before the status code, or it is not sent
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(p.Status)
+	w.Write(bytes)
+}
+
+// NewProblemRegistry is a function that returns a new instance of ProblemRegistry
+func NewProblemRegistry() *ProblemRegistry {
+	return &ProblemRegistry{}
+}
+
+// ProblemRegistry is a struct that maps errors to the problems written for them
+// - errors are matched in registration order, so specific errors go before the ones they wrap
+// - a *Problem in the chain of an error is used as is, before any match
+// - it is filled on start up: matching is not synchronized with registering
+type ProblemRegistry struct {
+	// matchers is the list of functions that return the problem of an error, if they match it
+	matchers []func(err error) (p Problem, ok bool)
+}
+
+// Register is a method that maps an error (and every error that wraps it) to a status and a detail
+func (rg *ProblemRegistry) Register(target error, status int, detail string) {
+	rg.RegisterFunc(func(err error) (p Problem, ok bool) {
+		if !errors.Is(err, target) {
+			return
+		}
+		p, ok = Problem{Status: status, Detail: detail}, true
+		return
+	})
+}
+
+// RegisterFunc is a method that adds a function that maps errors to problems
+// - e.g. errors.As on an error type, to build the detail or the field errors from it
+func (rg *ProblemRegistry) RegisterFunc(match func(err error) (p Problem, ok bool)) {
+	rg.matchers = append(rg.matchers, match)
+}
+
+// Problem is a method that returns the problem of an error
+// - ok is false if the error is not mapped: the problem is a 500 without the message of the error
+func (rg *ProblemRegistry) Problem(err error) (p Problem, ok bool) {
+	var pr *Problem
+	if errors.As(err, &pr) {
+		p, ok = *pr, true
+		return
+	}
+	for _, match := range rg.matchers {
+		if p, ok = match(err); ok {
+			return
+		}
+	}
+	p = Problem{Status: http.StatusInternalServerError, Detail: "internal error"}
+	return
+}
+
+// Write is a method that writes the problem of an error
+// - it returns false if the error is not mapped, so the caller can log it
+func (rg *ProblemRegistry) Write(w http.ResponseWriter, r *http.Request, err error) (ok bool) {
+	p, ok := rg.Problem(err)
+	WriteProblem(w, r, p)
+	return
+}
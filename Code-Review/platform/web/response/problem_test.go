@@ -0,0 +1,80 @@
+package response_test
+
+import (
+	"app/platform/web/response"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for WriteProblem function
+func TestWriteProblem(t *testing.T) {
+	t.Run("instance and field errors", func(t *testing.T) {
+		// arrange
+		r := httptest.NewRequest(http.MethodGet, "/vehicles/?year_gte=2020&year_lte=2010", nil)
+		rr := httptest.NewRecorder()
+		p := response.Problem{
+			Type:   "https://example.com/problems/invalid-filter",
+			Title:  "Invalid filter",
+			Status: http.StatusBadRequest,
+			Detail: "invalid year: min is greater than max",
+			Errors: []response.ProblemField{{Field: "year", Message: "min is greater than max"}},
+		}
+
+		// act
+		response.WriteProblem(rr, r, p)
+
+		// assert
+		expectedHeader := http.Header{"Content-Type": []string{"application/problem+json"}}
+		expectedBody := `{
+			"type": "https://example.com/problems/invalid-filter",
+			"title": "Invalid filter",
+			"status": 400,
+			"detail": "invalid year: min is greater than max",
+			"instance": "/vehicles/",
+			"errors": [{"field": "year", "message": "min is greater than max"}]
+		}`
+		require.Equal(t, expectedHeader, rr.Header())
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.JSONEq(t, expectedBody, rr.Body.String())
+	})
+}
+
+// Tests for ProblemRegistry
+func TestProblemRegistry_Problem(t *testing.T) {
+	// arrange
+	errNotFound := errors.New("test: not found")
+	errDuplicated := errors.New("test: duplicated")
+	errConflict := errors.New("test: conflict")
+	rg := response.NewProblemRegistry()
+	rg.Register(errNotFound, http.StatusNotFound, "not found")
+	rg.Register(errDuplicated, http.StatusConflict, "already exists")
+	rg.Register(errConflict, http.StatusConflict, "conflict")
+
+	cases := []struct {
+		name          string
+		err           error
+		expectProblem response.Problem
+		expectOk      bool
+	}{
+		{"sentinel", errNotFound, response.Problem{Status: http.StatusNotFound, Detail: "not found"}, true},
+		{"wrapped sentinel", fmt.Errorf("%w: id 3", errNotFound), response.Problem{Status: http.StatusNotFound, Detail: "not found"}, true},
+		{"first registered match", fmt.Errorf("%w: %w", errConflict, errDuplicated), response.Problem{Status: http.StatusConflict, Detail: "already exists"}, true},
+		{"problem in the chain", fmt.Errorf("test: %w", &response.Problem{Status: http.StatusTeapot, Detail: "teapot"}), response.Problem{Status: http.StatusTeapot, Detail: "teapot"}, true},
+		{"not registered", errors.New("test: disk full"), response.Problem{Status: http.StatusInternalServerError, Detail: "internal error"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			p, ok := rg.Problem(c.err)
+			// assert
+			require.Equal(t, c.expectOk, ok)
+			require.Equal(t, c.expectProblem, p)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+)
+
+const (
+	// MediaTypeCSV is the media type of CSV responses (RFC 4180)
+	MediaTypeCSV = "text/csv"
+	// MediaTypeXML is the media type of XML responses
+	MediaTypeXML = "application/xml"
+	// MediaTypeNDJSON is the media type of newline delimited JSON responses (one document per line)
+	MediaTypeNDJSON = "application/x-ndjson"
+)
+
+// flushEvery is the number of records written between flushes of a streamed response
+const flushEvery = 100
+
+// CSV writes csv response, streaming the records returned by next until it returns false
+// - the header is the first row
+// - errors while writing the body are ignored: the status code has already been sent
+func CSV(w http.ResponseWriter, code int, header []string, next func() (record []string, ok bool)) {
+	// set header
+	w.Header().Set("Content-Type", MediaTypeCSV+"; charset=utf-8")
+
+	// set status code
+	w.WriteHeader(code)
+
+	// write body
+	cw := csv.NewWriter(w)
+	if cw.Write(header) != nil {
+		return
+	}
+	for n := 1; ; n++ {
+		record, ok := next()
+		if !ok {
+			break
+		}
+		if cw.Write(record) != nil {
+			return
+		}
+		if n%flushEvery == 0 {
+			cw.Flush()
+			flush(w)
+		}
+	}
+	cw.Flush()
+}
+
+// NDJSON writes newline delimited json response, streaming the documents returned by next until it returns false
+// - errors while writing the body are ignored: the status code has already been sent
+func NDJSON(w http.ResponseWriter, code int, next func() (doc any, ok bool)) {
+	// set header
+	w.Header().Set("Content-Type", MediaTypeNDJSON)
+
+	// set status code
+	w.WriteHeader(code)
+
+	// write body (Encode ends each document with a newline)
+	enc := json.NewEncoder(w)
+	for n := 1; ; n++ {
+		doc, ok := next()
+		if !ok {
+			break
+		}
+		if enc.Encode(doc) != nil {
+			return
+		}
+		if n%flushEvery == 0 {
+			flush(w)
+		}
+	}
+}
+
+// XML writes xml response, streaming the elements returned by next until it returns false inside a root element
+// - the elements are encoded with encoding/xml, so they can implement xml.Marshaler
+// - errors while writing the body are ignored: the status code has already been sent
+func XML(w http.ResponseWriter, code int, root string, next func() (element any, ok bool)) {
+	// set header
+	w.Header().Set("Content-Type", MediaTypeXML+"; charset=utf-8")
+
+	// set status code
+	w.WriteHeader(code)
+
+	// write body
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	start := xml.StartElement{Name: xml.Name{Local: root}}
+	if enc.EncodeToken(start) != nil {
+		return
+	}
+	for n := 1; ; n++ {
+		element, ok := next()
+		if !ok {
+			break
+		}
+		if enc.Encode(element) != nil {
+			return
+		}
+		if n%flushEvery == 0 {
+			flush(w)
+		}
+	}
+	if enc.EncodeToken(start.End()) != nil {
+		return
+	}
+	enc.Flush()
+}
+
+// flush sends the buffered body to the client, if the writer supports it
+func flush(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).Flush()
+}
@@ -0,0 +1,81 @@
+package response_test
+
+import (
+	"app/platform/web/response"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// items returns a function that returns each item in turn, as the streamed responses read them
+func items[T any](v ...T) func() (T, bool) {
+	return func() (item T, ok bool) {
+		if len(v) == 0 {
+			return
+		}
+		item, v, ok = v[0], v[1:], true
+		return
+	}
+}
+
+// Tests for CSV function
+func TestCSV(t *testing.T) {
+	t.Run("200 - header and quoted records", func(t *testing.T) {
+		// act
+		rr := httptest.NewRecorder()
+		response.CSV(rr, http.StatusOK, []string{"id", "brand"}, items([]string{"1", "Ford"}, []string{"2", `Land "Rover", Ltd`}))
+
+		// assert
+		expectedHeader := http.Header{"Content-Type": []string{"text/csv; charset=utf-8"}}
+		expectedBody := "id,brand\n1,Ford\n2,\"Land \"\"Rover\"\", Ltd\"\n"
+		require.Equal(t, expectedHeader, rr.Header())
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, expectedBody, rr.Body.String())
+	})
+
+	t.Run("200 - only the header", func(t *testing.T) {
+		// act
+		rr := httptest.NewRecorder()
+		response.CSV(rr, http.StatusOK, []string{"id", "brand"}, items[[]string]())
+
+		// assert
+		require.Equal(t, "id,brand\n", rr.Body.String())
+	})
+}
+
+// Tests for NDJSON function
+func TestNDJSON(t *testing.T) {
+	// act
+	rr := httptest.NewRecorder()
+	response.NDJSON(rr, http.StatusOK, items[any](map[string]any{"id": 1}, map[string]any{"id": 2}))
+
+	// assert
+	expectedHeader := http.Header{"Content-Type": []string{"application/x-ndjson"}}
+	expectedBody := "{\"id\":1}\n{\"id\":2}\n"
+	require.Equal(t, expectedHeader, rr.Header())
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, expectedBody, rr.Body.String())
+}
+
+// Tests for XML function
+func TestXML(t *testing.T) {
+	// arrange
+	type item struct {
+		Id    int    `xml:"id"`
+		Brand string `xml:"brand"`
+	}
+
+	// act
+	rr := httptest.NewRecorder()
+	response.XML(rr, http.StatusOK, "items", items[any](item{Id: 1, Brand: "Ford"}, item{Id: 2, Brand: "A&B"}))
+
+	// assert
+	expectedHeader := http.Header{"Content-Type": []string{"application/xml; charset=utf-8"}}
+	expectedBody := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<items><item><id>1</id><brand>Ford</brand></item><item><id>2</id><brand>A&amp;B</brand></item></items>`
+	require.Equal(t, expectedHeader, rr.Header())
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, expectedBody, rr.Body.String())
+}
@@ -0,0 +1,48 @@
+package timeout
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Middleware returns a middleware that sets the deadline of the context of a request
+// - the duration of each request is given by lookup, a duration <= 0 leaves the request without deadline
+// - the handlers must stop when the context is done and respond to context.DeadlineExceeded (e.g. 504)
+func Middleware(lookup func(r *http.Request) time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := lookup(r)
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ByRoute returns a lookup of the duration of the route that matches a request, or def if it has none
+// - timeouts are keyed by method and route pattern (e.g. "GET /vehicles/stats")
+// - the route is matched on routes, so the middleware can be used before routing (e.g. on the root router)
+func ByRoute(routes chi.Routes, def time.Duration, timeouts map[string]time.Duration) func(r *http.Request) time.Duration {
+	return func(r *http.Request) time.Duration {
+		if len(timeouts) == 0 {
+			return def
+		}
+
+		rctx := chi.NewRouteContext()
+		if !routes.Match(rctx, r.Method, r.URL.Path) {
+			return def
+		}
+		if d, ok := timeouts[r.Method+" "+rctx.RoutePattern()]; ok {
+			return d
+		}
+		return def
+	}
+}
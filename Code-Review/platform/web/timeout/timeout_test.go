@@ -0,0 +1,72 @@
+package timeout_test
+
+import (
+	"app/platform/web/timeout"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for Middleware and ByRoute functions
+func TestMiddleware(t *testing.T) {
+	// newRouter is a function that returns a router that writes the remaining time of the request, by route
+	newRouter := func(def time.Duration, timeouts map[string]time.Duration) *chi.Mux {
+		rt := chi.NewRouter()
+		rt.Use(timeout.Middleware(timeout.ByRoute(rt, def, timeouts)))
+		remaining := func(w http.ResponseWriter, r *http.Request) {
+			deadline, ok := r.Context().Deadline()
+			if !ok {
+				w.Write([]byte("none"))
+				return
+			}
+			w.Write([]byte(time.Until(deadline).Round(time.Minute).String()))
+		}
+		rt.Route("/vehicles", func(r chi.Router) {
+			r.Get("/stats", remaining)
+			r.Get("/{id}", remaining)
+			r.Post("/{id}", remaining)
+		})
+		return rt
+	}
+
+	t.Run("case - default and per route", func(t *testing.T) {
+		// arrange
+		rt := newRouter(time.Minute, map[string]time.Duration{
+			"GET /vehicles/stats": 10 * time.Minute,
+			"GET /vehicles/{id}":  5 * time.Minute,
+		})
+		cases := []struct {
+			method, target, expected string
+		}{
+			{http.MethodGet, "/vehicles/stats", "10m0s"},
+			{http.MethodGet, "/vehicles/1", "5m0s"},
+			{http.MethodPost, "/vehicles/1", "1m0s"},
+			{http.MethodGet, "/unknown", ""},
+		}
+		for _, cs := range cases {
+			// act
+			w := httptest.NewRecorder()
+			rt.ServeHTTP(w, httptest.NewRequest(cs.method, cs.target, nil))
+			// assert
+			if cs.expected == "" {
+				require.Equal(t, http.StatusNotFound, w.Code)
+				continue
+			}
+			require.Equal(t, cs.expected, w.Body.String(), cs.method+" "+cs.target)
+		}
+	})
+
+	t.Run("case - no deadline", func(t *testing.T) {
+		// arrange
+		rt := newRouter(0, map[string]time.Duration{"GET /vehicles/stats": 10 * time.Minute})
+		// act
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vehicles/1", nil))
+		// assert
+		require.Equal(t, "none", w.Body.String())
+	})
+}
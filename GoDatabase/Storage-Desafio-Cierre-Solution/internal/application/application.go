@@ -1,5 +1,7 @@
 package application
 
+import "context"
+
 // Application is an interface that represents an application.
 type Application interface {
 	// TearDown tears down the application.
@@ -8,4 +10,6 @@ type Application interface {
 	SetUp() (err error)
 	// Run runs the application.
 	Run() (err error)
+	// Shutdown stops the application gracefully, waiting for the in-flight work until ctx is done.
+	Shutdown(ctx context.Context) (err error)
 }
\ No newline at end of file
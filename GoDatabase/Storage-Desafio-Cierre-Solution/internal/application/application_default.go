@@ -4,8 +4,15 @@ import (
 	"app/internal/handler"
 	"app/internal/repository"
 	"app/internal/service"
+	"context"
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -18,6 +25,16 @@ type ConfigApplicationDefault struct {
 	Db *mysql.Config
 	// Addr is the server address.
 	Addr string
+	// ReadHeaderTimeout is the maximum duration to read the headers of a request.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout is the maximum duration to read a request, including the body.
+	ReadTimeout time.Duration
+	// WriteTimeout is the maximum duration to write a response.
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum duration to wait for the next request on a keep-alive connection.
+	IdleTimeout time.Duration
+	// ShutdownTimeout is the maximum duration to drain the in-flight requests on SIGINT / SIGTERM.
+	ShutdownTimeout time.Duration
 }
 
 // NewApplicationDefault creates a new ApplicationDefault.
@@ -26,6 +43,11 @@ func NewApplicationDefault(config *ConfigApplicationDefault) *ApplicationDefault
 	defaultCfg := &ConfigApplicationDefault{
 		Db:      nil,
 		Addr: ":8080",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout: 10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout: 2 * time.Minute,
+		ShutdownTimeout: 15 * time.Second,
 	}
 	if config != nil {
 		if config.Db != nil {
@@ -34,11 +56,34 @@ func NewApplicationDefault(config *ConfigApplicationDefault) *ApplicationDefault
 		if config.Addr != "" {
 			defaultCfg.Addr = config.Addr
 		}
+		if config.ReadHeaderTimeout != 0 {
+			defaultCfg.ReadHeaderTimeout = config.ReadHeaderTimeout
+		}
+		if config.ReadTimeout != 0 {
+			defaultCfg.ReadTimeout = config.ReadTimeout
+		}
+		if config.WriteTimeout != 0 {
+			defaultCfg.WriteTimeout = config.WriteTimeout
+		}
+		if config.IdleTimeout != 0 {
+			defaultCfg.IdleTimeout = config.IdleTimeout
+		}
+		if config.ShutdownTimeout != 0 {
+			defaultCfg.ShutdownTimeout = config.ShutdownTimeout
+		}
 	}
 
 	return &ApplicationDefault{
 		cfgDb:      defaultCfg.Db,
 		cfgAddr: defaultCfg.Addr,
+		server: &http.Server{
+			Addr: defaultCfg.Addr,
+			ReadHeaderTimeout: defaultCfg.ReadHeaderTimeout,
+			ReadTimeout: defaultCfg.ReadTimeout,
+			WriteTimeout: defaultCfg.WriteTimeout,
+			IdleTimeout: defaultCfg.IdleTimeout,
+		},
+		cfgShutdownTimeout: defaultCfg.ShutdownTimeout,
 	}
 }
 
@@ -48,6 +93,10 @@ type ApplicationDefault struct {
 	cfgDb *mysql.Config
 	// cfgAddr is the server address.
 	cfgAddr string
+	// cfgShutdownTimeout is the maximum duration to drain the in-flight requests on SIGINT / SIGTERM.
+	cfgShutdownTimeout time.Duration
+	// server is the http server, it serves the router.
+	server *http.Server
 	// db is the database connection.
 	db *sql.DB
 	// router is the chi router.
@@ -130,12 +179,51 @@ func (a *ApplicationDefault) SetUp() (err error) {
 		// - POST /sales
 		r.Post("/", hdSale.Create())
 	})
+	// - server
+	a.server.Handler = a.router
 
 	return
 }
 
-// Run runs the application.
+// Run runs the application until SIGINT / SIGTERM or Shutdown.
+// On a signal, the in-flight requests are drained within the shutdown timeout and the database is closed.
 func (a *ApplicationDefault) Run() (err error) {
-	err = http.ListenAndServe(a.cfgAddr, a.router)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	chErr := make(chan error, 1)
+	go func() {
+		chErr <- a.server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-chErr:
+		// - closed by Shutdown, not an error
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		return
+	case <-ctx.Done():
+		stop()
+	}
+
+	log.Printf("server: shutting down, draining requests for up to %s", a.cfgShutdownTimeout)
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), a.cfgShutdownTimeout)
+	defer cancel()
+	err = a.Shutdown(ctxShutdown)
+	if errServe := <-chErr; !errors.Is(errServe, http.ErrServerClosed) {
+		err = errors.Join(errServe, err)
+	}
+	return
+}
+
+// Shutdown stops the application gracefully.
+// The server stops accepting requests and waits for the in-flight ones until ctx is done, then the database is closed.
+func (a *ApplicationDefault) Shutdown(ctx context.Context) (err error) {
+	err = a.server.Shutdown(ctx)
+	// close db (the connections of the drained requests are back in the pool)
+	if a.db != nil {
+		err = errors.Join(err, a.db.Close())
+	}
 	return
 }
\ No newline at end of file